@@ -3,11 +3,14 @@ module github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/client
 go 1.24.9
 
 require (
-	github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common v0.0.0-20250923124507-702f9ea13f21 // indirect
+	github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common v0.0.0-20250923124507-702f9ea13f21
+	google.golang.org/grpc v1.75.1
+)
+
+require (
 	golang.org/x/net v0.41.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
-	google.golang.org/grpc v1.75.1 // indirect
 	google.golang.org/protobuf v1.36.9 // indirect
 )
@@ -1,7 +1,12 @@
 package bo
 
 type UserBO struct {
-	ID       int    `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	ID                int      `json:"id"`
+	Email             string   `json:"email"`
+	Password          string   `json:"password"`
+	Role              string   `json:"role"`
+	Permissions       []string `json:"permissions"`
+	CredentialVersion int      `json:"credential_version"`
+	SessionID         int64    `json:"session_id"`
+	ImpersonatorID    int      `json:"impersonator_id"`
 }
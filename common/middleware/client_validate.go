@@ -1,15 +1,24 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/gin-gonic/gin"
+)
 
+// ClientKey is the context key holding the client validated by ValidateClient.
+const ClientKey = "client"
+
+// ValidateClient checks the :client path parameter and stores it in the
+// context, where AuthMiddleware matches it against the token audience.
 func ValidateClient() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		client := c.Param("client")
-		if client != "merchant" && client != "customer" {
+		if client != utils.AudienceMerchant && client != utils.AudienceCustomer && client != utils.AudienceAdmin {
 			c.JSON(400, gin.H{"error": "Invalid client type"})
 			c.Abort()
 			return
 		}
+		c.Set(ClientKey, client)
 		c.Next()
 	}
 }
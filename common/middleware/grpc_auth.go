@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthMetadataKey is the gRPC metadata key carrying the same token as the
+// auth-token cookie.
+const AuthMetadataKey = "auth-token"
+
+// AuthorizationMetadataKey carries an API key as "ApiKey <key>", like the
+// Authorization header.
+const AuthorizationMetadataKey = "authorization"
+
+type userIDKey struct{}
+
+type claimsKey struct{}
+
+// UserIDFromContext returns the user ID set by AuthUnaryInterceptor.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(int)
+	return userID, ok
+}
+
+// ClaimsFromContext returns the token claims set by AuthUnaryInterceptor.
+func ClaimsFromContext(ctx context.Context) (*utils.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*utils.Claims)
+	return claims, ok
+}
+
+// AuthUnaryInterceptor validates the auth token or API key of every unary
+// call except the given public methods, and stores the user ID in the handler
+// context.
+func AuthUnaryInterceptor(publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := make(map[string]struct{}, len(publicMethods))
+	for _, method := range publicMethods {
+		public[method] = struct{}{}
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := public[info.FullMethod]; ok {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		var claims *utils.Claims
+		if values := md.Get(AuthorizationMetadataKey); len(values) > 0 {
+			key, ok := apiKeyFromHeader(values[0])
+			if !ok {
+				return nil, status.Error(codes.Unauthenticated, "unsupported authorization scheme")
+			}
+			var err error
+			claims, err = utils.ValidateApiKey(ctx, key)
+			if errors.Is(err, utils.ErrAccountUnavailable) {
+				return nil, status.Error(codes.PermissionDenied, err.Error())
+			}
+			if err != nil || claims.ID <= 0 {
+				return nil, status.Error(codes.Unauthenticated, "invalid or expired API key")
+			}
+		} else {
+			values := md.Get(AuthMetadataKey)
+			if len(values) == 0 || values[0] == "" {
+				return nil, status.Error(codes.Unauthenticated, "auth token metadata is required")
+			}
+			var err error
+			claims, err = utils.ValidateJWTClaims(values[0])
+			if errors.Is(err, utils.ErrAccountUnavailable) {
+				return nil, status.Error(codes.PermissionDenied, err.Error())
+			}
+			if err != nil || claims.ID <= 0 {
+				return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+			}
+		}
+		ctx = context.WithValue(ctx, userIDKey{}, claims.ID)
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+// PermissionUnaryInterceptor is the gRPC counterpart of RequirePermission:
+// calls to a method listed in methodPermissions must carry a token granting
+// all of its permissions. It must be chained after AuthUnaryInterceptor.
+func PermissionUnaryInterceptor(methodPermissions map[string][]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		permissions, ok := methodPermissions[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		claims, ok := ClaimsFromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "auth token metadata is required")
+		}
+		if !claims.HasPermissions(permissions...) {
+			return nil, status.Error(codes.PermissionDenied, "insufficient permissions")
+		}
+		return handler(ctx, req)
+	}
+}
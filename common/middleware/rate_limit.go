@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKeyFunc derives the bucket a request counts against. Returning ""
+// exempts the request from the limit, e.g. when the keyed field is missing.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimit is a token bucket holding up to Burst requests that refills at
+// Requests per Period.
+type RateLimit struct {
+	Name     string
+	Key      RateLimitKeyFunc
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func (l RateLimit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// refillRate is in tokens per second.
+func (l RateLimit) refillRate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Take spends one token of a bucket that held tokens at last. It returns the
+// tokens left and, when the bucket is empty, how long until the next token.
+// A zero last means a new, full bucket.
+func (l RateLimit) Take(tokens float64, last, now time.Time) (float64, time.Duration) {
+	if last.IsZero() {
+		tokens = l.capacity()
+	} else if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(l.capacity(), tokens+elapsed*l.refillRate())
+	}
+	if tokens >= 1 {
+		return tokens - 1, 0
+	}
+	wait := time.Duration((1 - tokens) / l.refillRate() * float64(time.Second))
+	return tokens, wait
+}
+
+// FullAfter returns how long a bucket with the given tokens takes to refill
+// completely, after which it can be forgotten.
+func (l RateLimit) FullAfter(tokens float64) time.Duration {
+	return time.Duration((l.capacity() - tokens) / l.refillRate() * float64(time.Second))
+}
+
+// RateLimitStore keeps the buckets. Take returns zero when the request is
+// allowed, otherwise how long the client has to wait.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (time.Duration, error)
+}
+
+// RateLimitMiddleware rejects requests exceeding any of the limits with 429
+// and a Retry-After header. If the store fails the request is let through, so
+// an outage of a shared store does not take the endpoints down with it.
+func RateLimitMiddleware(store RateLimitStore, limits ...RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		for _, limit := range limits {
+			key := limit.Key(c)
+			if c.IsAborted() {
+				return
+			}
+			if key == "" {
+				continue
+			}
+			wait, err := store.Take(c.Request.Context(), limit.Name+":"+key, limit, now)
+			if err != nil || wait <= 0 {
+				continue
+			}
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please retry later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// KeyByIP gives every client IP its own bucket. The IP is only taken from
+// X-Forwarded-For if the engine trusts the proxy it came from, see
+// gin.Engine.SetTrustedProxies.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByRoute shares one bucket among all callers of the route.
+func KeyByRoute(c *gin.Context) string {
+	return "route:" + c.Request.Method + " " + c.FullPath()
+}
+
+// maxKeyedBodySize bounds the bodies read by KeyByJSONField, plenty for the
+// small JSON requests of throttled endpoints.
+const maxKeyedBodySize = 8 << 10
+
+// KeyByJSONField keys on a field of the JSON request body, e.g. the email of
+// a login. The body is restored for the handler. Bodies over
+// maxKeyedBodySize are refused with 413 before they are buffered.
+func KeyByJSONField(field string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil {
+			return ""
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxKeyedBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return ""
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+		var fields map[string]interface{}
+		if json.Unmarshal(body, &fields) != nil {
+			return ""
+		}
+		value, _ := fields[field].(string)
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			return ""
+		}
+		return field + ":" + value
+	}
+}
+
+// MemoryRateLimitStore keeps buckets in process memory, so every instance
+// enforces its own limits.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{}
+		s.buckets[key] = bucket
+	}
+	tokens, wait := limit.Take(bucket.tokens, bucket.updatedAt, now)
+	bucket.tokens = tokens
+	bucket.updatedAt = now
+	bucket.fullAt = now.Add(limit.FullAfter(tokens))
+	return wait, nil
+}
+
+// PruneExpired forgets buckets that have refilled completely.
+func (s *MemoryRateLimitStore) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for key, bucket := range s.buckets {
+		if bucket.fullAt.Before(now) {
+			delete(s.buckets, key)
+			count++
+		}
+	}
+	return count, nil
+}
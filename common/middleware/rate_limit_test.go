@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStore allows every request and remembers the keys it was asked for.
+type countingStore struct {
+	keys []string
+}
+
+func (s *countingStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (time.Duration, error) {
+	s.keys = append(s.keys, key)
+	return 0, nil
+}
+
+func TestKeyByJSONField(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newEngine := func(store RateLimitStore, handled *string) *gin.Engine {
+		r := gin.New()
+		limit := RateLimit{Name: "login", Key: KeyByJSONField("email"), Requests: 5, Period: time.Minute}
+		r.POST("/login", RateLimitMiddleware(store, limit), func(c *gin.Context) {
+			body, _ := io.ReadAll(c.Request.Body)
+			*handled = string(body)
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+
+	t.Run("Keys on the field and restores the body", func(t *testing.T) {
+		store := &countingStore{}
+		var handled string
+		body := `{"email": " Buyer@Example.com "}`
+		w := httptest.NewRecorder()
+		newEngine(store, &handled).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"login:email:buyer@example.com"}, store.keys)
+		assert.Equal(t, body, handled)
+	})
+
+	t.Run("Oversized bodies are refused unread", func(t *testing.T) {
+		store := &countingStore{}
+		var handled string
+		body := `{"email": "buyer@example.com", "padding": "` + strings.Repeat("x", maxKeyedBodySize) + `"}`
+		w := httptest.NewRecorder()
+		newEngine(store, &handled).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Empty(t, store.keys)
+		assert.Empty(t, handled)
+	})
+}
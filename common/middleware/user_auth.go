@@ -1,38 +1,161 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// ClaimsKey is the context key holding the *utils.Claims of the request.
+const ClaimsKey = "claims"
+
+// ApiKeyScheme is the Authorization scheme of API keys.
+const ApiKeyScheme = "ApiKey"
+
+// AuthMiddleware validates the auth-token cookie, or an API key sent as
+// "Authorization: ApiKey <key>". The token must have been issued for one of
+// the given audiences or, when none are given, for the client stored by
+// ValidateClient. Routes with neither accept any audience.
+func AuthMiddleware(audiences ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Extract the token from the Authorization header
-		authCookie, err := c.Cookie("auth-token")
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Auth token cookie is required"})
+		var claims *utils.Claims
+		if key, ok := apiKeyFromHeader(c.GetHeader("Authorization")); ok {
+			var err error
+			claims, err = utils.ValidateApiKey(c.Request.Context(), key)
+			if errors.Is(err, utils.ErrAccountUnavailable) {
+				rejectUnavailableAccount(c, err)
+				return
+			}
+			if err != nil || claims.ID <= 0 {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
+				c.Abort()
+				return
+			}
+		} else {
+			authCookie, err := c.Cookie("auth-token")
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Auth token cookie is required"})
+				c.Abort()
+				return
+			}
+
+			if authCookie == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+				c.Abort()
+				return
+			}
+			claims, err = utils.ValidateJWTClaims(authCookie)
+			if errors.Is(err, utils.ErrAccountUnavailable) {
+				rejectUnavailableAccount(c, err)
+				return
+			}
+			if err != nil || claims.ID <= 0 {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+				c.Abort()
+				return
+			}
+		}
+		expected := audiences
+		if len(expected) == 0 {
+			if client := c.GetString(ClientKey); client != "" {
+				expected = []string{client}
+			}
+		}
+		if len(expected) > 0 && !slices.ContainsFunc(expected, claims.HasAudience) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token was not issued for this client"})
 			c.Abort()
 			return
 		}
+		audience := ""
+		if len(claims.Audience) > 0 {
+			audience = claims.Audience[0]
+		}
+		// Set user ID, role, audience and claims into the context
+		c.Set("userID", claims.ID)
+		c.Set("role", claims.Role)
+		c.Set("audience", audience)
+		c.Set(ClaimsKey, claims)
 
-		if authCookie == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		// Token is valid, proceed to the next handler
+		c.Next()
+	}
+}
+
+// rejectUnavailableAccount tells the caller why its otherwise valid
+// credentials are refused, e.g. that the account is suspended.
+func rejectUnavailableAccount(c *gin.Context, err error) {
+	c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	c.Abort()
+}
+
+// apiKeyFromHeader extracts the key from an "ApiKey <key>" Authorization
+// header value.
+func apiKeyFromHeader(value string) (string, bool) {
+	scheme, key, found := strings.Cut(strings.TrimSpace(value), " ")
+	if !found || !strings.EqualFold(scheme, ApiKeyScheme) {
+		return "", false
+	}
+	key = strings.TrimSpace(key)
+	return key, key != ""
+}
+
+// DenyApiKeys rejects requests authenticated with an API key, for account
+// management only the user may do. It must run after AuthMiddleware.
+func DenyApiKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(ClaimsKey)
+		if claims, ok := value.(*utils.Claims); ok && claims.IsApiKey() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed with an API key"})
 			c.Abort()
 			return
 		}
-		ret, err := utils.ValidateJWTToken(authCookie)
+		c.Next()
+	}
+}
 
-		if err != nil || ret <= 0 {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+// DenyImpersonation rejects requests made with an impersonation token, for
+// actions that must stay with the user such as changing the password, the
+// email or deleting the account. It must run after AuthMiddleware.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(ClaimsKey)
+		if claims, ok := value.(*utils.Claims); ok && claims.IsImpersonated() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating"})
 			c.Abort()
 			return
 		}
-		// Set user ID into the context
-		c.Set("userID", ret)
+		c.Next()
+	}
+}
 
-		// Token is valid, proceed to the next handler
+// RequireRole only lets through users whose token carries one of the given
+// roles. It must run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, c.GetString("role")) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission only lets through tokens that grant all of the given
+// permissions. It must run after AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(ClaimsKey)
+		claims, ok := value.(*utils.Claims)
+		if !ok || !claims.HasPermissions(permissions...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
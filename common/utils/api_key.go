@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"context"
+	"errors"
+)
+
+// ApiKeyValidator resolves an API key to the claims of its owner, limited to
+// the scopes of the key. It returns an error for unknown, expired or revoked
+// keys.
+type ApiKeyValidator func(ctx context.Context, key string) (*Claims, error)
+
+var apiKeyValidator ApiKeyValidator
+
+var ErrApiKeysNotSupported = errors.New("API keys are not accepted by this service")
+
+// RegisterApiKeyValidator enables API key authentication in AuthMiddleware
+// and the gRPC interceptor. It is meant to be called during startup, before
+// any request is served.
+func RegisterApiKeyValidator(validator ApiKeyValidator) {
+	apiKeyValidator = validator
+}
+
+// ValidateApiKey returns the claims for an API key using the registered
+// validator.
+func ValidateApiKey(ctx context.Context, key string) (*Claims, error) {
+	if apiKeyValidator == nil {
+		return nil, ErrApiKeysNotSupported
+	}
+	return apiKeyValidator(ctx, key)
+}
+
+// IsApiKey reports whether the claims were resolved from an API key rather
+// than a token issued at login.
+func (c *Claims) IsApiKey() bool {
+	return c.ApiKeyID != 0
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksServer publishes the public keys of *keys and counts the fetches.
+func jwksServer(t *testing.T, keys *atomic.Pointer[KeySet], fetches *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(keys.Load().JWKS())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestJWKSVerifierKey(t *testing.T) {
+	first := newEd25519Key(t)
+	second := newEd25519Key(t)
+
+	t.Run("Keys are cached", func(t *testing.T) {
+		var keys atomic.Pointer[KeySet]
+		var fetches atomic.Int32
+		keys.Store(NewKeySet(first))
+		verifier := NewJWKSVerifier(jwksServer(t, &keys, &fetches).URL, time.Hour)
+
+		for range 3 {
+			key, err := verifier.Key(first.Kid)
+			require.NoError(t, err)
+			assert.Equal(t, first.PublicKey, key)
+		}
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("Unknown kid refreshes the cache", func(t *testing.T) {
+		var keys atomic.Pointer[KeySet]
+		var fetches atomic.Int32
+		keys.Store(NewKeySet(first))
+		verifier := NewJWKSVerifier(jwksServer(t, &keys, &fetches).URL, time.Hour)
+		_, err := verifier.Key(first.Kid)
+		require.NoError(t, err)
+
+		// The issuer rotates to a new key
+		keys.Store(NewKeySet(second, first))
+		_, err = verifier.Key(second.Kid)
+		assert.ErrorContains(t, err, "unknown JWT key id")
+		assert.Equal(t, int32(1), fetches.Load(), "unknown kids refetch at most once a minute")
+
+		verifier.fetchedAt = time.Now().Add(-jwksMinRefreshInterval)
+		key, err := verifier.Key(second.Kid)
+		require.NoError(t, err)
+		assert.Equal(t, second.PublicKey, key)
+		assert.Equal(t, int32(2), fetches.Load())
+	})
+
+	t.Run("Stale keys are served while the endpoint is down", func(t *testing.T) {
+		var keys atomic.Pointer[KeySet]
+		var fetches atomic.Int32
+		keys.Store(NewKeySet(first))
+		server := jwksServer(t, &keys, &fetches)
+		verifier := NewJWKSVerifier(server.URL, time.Hour)
+		_, err := verifier.Key(first.Kid)
+		require.NoError(t, err)
+
+		server.Close()
+		verifier.fetchedAt = time.Now().Add(-2 * time.Hour)
+		key, err := verifier.Key(first.Kid)
+		require.NoError(t, err)
+		assert.Equal(t, first.PublicKey, key)
+	})
+
+	t.Run("Tokens validate against the JWKS", func(t *testing.T) {
+		var keys atomic.Pointer[KeySet]
+		var fetches atomic.Int32
+		keys.Store(NewKeySet(first))
+		useJwtKeys(t, nil, "", time.Time{})
+		jwksVerifier = NewJWKSVerifier(jwksServer(t, &keys, &fetches).URL, time.Hour)
+
+		claims, err := ParseJWTToken(signClaims(t, jwt.SigningMethodEdDSA, first.Kid, first.PrivateKey))
+		require.NoError(t, err)
+		assert.Equal(t, 1, claims.ID)
+		// Signed by a key that was never published
+		_, err = ParseJWTToken(signClaims(t, jwt.SigningMethodEdDSA, first.Kid, second.PrivateKey))
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+	})
+}
@@ -0,0 +1,264 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is an asymmetric JWT key. PrivateKey is nil for keys that are
+// only kept to verify tokens signed before a rotation.
+type SigningKey struct {
+	Kid        string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// KeySet holds the active signing key and any previous keys whose tokens may
+// still be in circulation.
+type KeySet struct {
+	Active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// JWK is the public part of a signing key as published in the JWKS document.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var signingKeys *KeySet
+
+// InitJwtKeys loads the active private key and the previous keys from PEM
+// files and switches token signing to the active key. Rotating means adding a
+// new active key, moving the old one to the previous list, and dropping it once
+// every token it signed has expired.
+func InitJwtKeys(activeKeyFile string, previousKeyFiles ...string) error {
+	active, err := LoadSigningKey(activeKeyFile)
+	if err != nil {
+		return err
+	}
+	if active.PrivateKey == nil {
+		return fmt.Errorf("active JWT key %s must be a private key", activeKeyFile)
+	}
+	previous := make([]*SigningKey, 0, len(previousKeyFiles))
+	for _, file := range previousKeyFiles {
+		key, err := LoadSigningKey(file)
+		if err != nil {
+			return err
+		}
+		previous = append(previous, key)
+	}
+	signingKeys = NewKeySet(active, previous...)
+	return nil
+}
+
+func NewKeySet(active *SigningKey, previous ...*SigningKey) *KeySet {
+	ks := &KeySet{Active: active, keys: map[string]*SigningKey{active.Kid: active}}
+	for _, key := range previous {
+		ks.keys[key.Kid] = key
+	}
+	return ks
+}
+
+// Key returns the key with the given kid, or nil if it is unknown.
+func (ks *KeySet) Key(kid string) *SigningKey {
+	return ks.keys[kid]
+}
+
+// JWKS returns the public keys of the set, active key first.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{ks.Active.JWK()}}
+	for kid, key := range ks.keys {
+		if kid != ks.Active.Kid {
+			jwks.Keys = append(jwks.Keys, key.JWK())
+		}
+	}
+	return jwks
+}
+
+// PublicJWKS returns the JWKS of the configured signing keys. It is empty when
+// tokens are still signed with the legacy shared secret.
+func PublicJWKS() JWKS {
+	if signingKeys == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return signingKeys.JWKS()
+}
+
+// LoadSigningKey reads a PEM file holding an RSA or Ed25519 private key, or a
+// public key for verification only.
+func LoadSigningKey(file string) (*SigningKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key %s: %w", file, err)
+	}
+	return ParseSigningKeyPEM(data)
+}
+
+func ParseSigningKeyPEM(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in JWT key")
+	}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return NewSigningKey(key, &key.PublicKey)
+	case ed25519.PrivateKey:
+		return NewSigningKey(key, key.Public())
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return NewSigningKey(nil, key)
+	default:
+		return nil, fmt.Errorf("unsupported JWT key type %T", parsed)
+	}
+}
+
+// NewSigningKey builds a key whose kid is the RFC 7638 thumbprint of the
+// public key, so the same key always gets the same kid.
+func NewSigningKey(privateKey crypto.Signer, publicKey crypto.PublicKey) (*SigningKey, error) {
+	key := &SigningKey{PrivateKey: privateKey, PublicKey: publicKey}
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported JWT key type %T", publicKey)
+	}
+	jwk := key.JWK()
+	// Thumbprint members must be in lexicographic order without whitespace
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(canonical)
+	key.Kid = base64.RawURLEncoding.EncodeToString(sum[:])
+	return key, nil
+}
+
+// JWK returns the public JWK representation of the key.
+func (k *SigningKey) JWK() JWK {
+	jwk := JWK{Kid: k.Kid, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// PublicKey converts a published JWK back into a verification key. Besides
+// our own RSA and Ed25519 keys it accepts the EC keys some identity providers
+// publish.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid EC public key")
+		}
+		return key, nil
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type %q", j.Kty)
+	}
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/bo"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useJwtKeys swaps the package key configuration for the duration of a test.
+func useJwtKeys(t *testing.T, keys *KeySet, secret string, legacyUntil time.Time) {
+	savedKeys, savedSecret, savedUntil, savedVerifier := signingKeys, jwtSecret, legacySecretUntil, jwksVerifier
+	signingKeys, jwtSecret, legacySecretUntil, jwksVerifier = keys, secret, legacyUntil, nil
+	t.Cleanup(func() {
+		signingKeys, jwtSecret, legacySecretUntil, jwksVerifier = savedKeys, savedSecret, savedUntil, savedVerifier
+	})
+}
+
+func newEd25519Key(t *testing.T) *SigningKey {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := NewSigningKey(priv, pub)
+	require.NoError(t, err)
+	return key
+}
+
+func signClaims(t *testing.T, method jwt.SigningMethod, kid string, signer interface{}) string {
+	claims := Claims{ID: 1, RegisteredClaims: jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{AudienceCustomer},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(signer)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestSigningKeyThumbprint(t *testing.T) {
+	// Examples from RFC 7638 section 3.1 and RFC 8037 appendix A.3
+	rsaKey, err := JWK{
+		Kty: "RSA",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+	}.PublicKey()
+	require.NoError(t, err)
+	key, err := NewSigningKey(nil, rsaKey)
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", key.Kid)
+	assert.Equal(t, jwt.SigningMethodRS256, key.Method)
+
+	edKey, err := JWK{Kty: "OKP", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}.PublicKey()
+	require.NoError(t, err)
+	key, err = NewSigningKey(nil, edKey)
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", key.Kid)
+	assert.Equal(t, jwt.SigningMethodEdDSA, key.Method)
+}
+
+func TestValidateWithKeySet(t *testing.T) {
+	previous := newEd25519Key(t)
+	active := newEd25519Key(t)
+
+	t.Run("Tokens of the active and previous keys validate", func(t *testing.T) {
+		useJwtKeys(t, NewKeySet(active, previous), "", time.Time{})
+
+		token, err := GenerateJWTToken(&bo.UserBO{ID: 1}, AudienceCustomer)
+		require.NoError(t, err)
+		claims, err := ParseJWTToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, 1, claims.ID)
+
+		claims, err = ParseJWTToken(signClaims(t, jwt.SigningMethodEdDSA, previous.Kid, previous.PrivateKey))
+		require.NoError(t, err)
+		assert.Equal(t, 1, claims.ID)
+	})
+
+	t.Run("Dropped keys no longer validate", func(t *testing.T) {
+		useJwtKeys(t, NewKeySet(active), "", time.Time{})
+
+		_, err := ParseJWTToken(signClaims(t, jwt.SigningMethodEdDSA, previous.Kid, previous.PrivateKey))
+		assert.ErrorContains(t, err, "unknown JWT key id")
+	})
+
+	t.Run("Algorithm must match the key", func(t *testing.T) {
+		rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		rsaKey, err := NewSigningKey(rsaPrivate, &rsaPrivate.PublicKey)
+		require.NoError(t, err)
+		useJwtKeys(t, NewKeySet(active, rsaKey), "", time.Time{})
+
+		// An EdDSA token claiming the kid of the RSA key
+		_, err = ParseJWTToken(signClaims(t, jwt.SigningMethodEdDSA, rsaKey.Kid, active.PrivateKey))
+		assert.ErrorContains(t, err, "does not sign EdDSA tokens")
+		// An RS512 token is not on the accepted list at all
+		_, err = ParseJWTToken(signClaims(t, jwt.SigningMethodRS512, rsaKey.Kid, rsaPrivate))
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+		_, err = ParseJWTToken(signClaims(t, jwt.SigningMethodNone, active.Kid, jwt.UnsafeAllowNoneSignatureType))
+		assert.Error(t, err)
+	})
+}
+
+func TestLegacySecretCutoff(t *testing.T) {
+	active := newEd25519Key(t)
+	legacyToken := func(t *testing.T) string {
+		return signClaims(t, jwt.SigningMethodHS256, "", []byte("legacy-secret"))
+	}
+
+	t.Run("Accepted while only the secret is set", func(t *testing.T) {
+		useJwtKeys(t, nil, "legacy-secret", time.Time{})
+
+		_, err := ParseJWTToken(legacyToken(t))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Refused once signing keys are set", func(t *testing.T) {
+		useJwtKeys(t, NewKeySet(active), "legacy-secret", time.Time{})
+
+		_, err := ParseJWTToken(legacyToken(t))
+		assert.ErrorContains(t, err, "HS256 tokens are no longer accepted")
+	})
+
+	t.Run("Accepted until the cutoff", func(t *testing.T) {
+		useJwtKeys(t, NewKeySet(active), "legacy-secret", time.Now().Add(time.Minute))
+		_, err := ParseJWTToken(legacyToken(t))
+		assert.NoError(t, err)
+
+		legacySecretUntil = time.Now().Add(-time.Second)
+		_, err = ParseJWTToken(legacyToken(t))
+		assert.ErrorContains(t, err, "HS256 tokens are no longer accepted")
+	})
+
+	t.Run("Refused by JWKS verifiers", func(t *testing.T) {
+		useJwtKeys(t, nil, "legacy-secret", time.Time{})
+		jwksVerifier = NewJWKSVerifier("http://127.0.0.1:0/jwks.json", time.Minute)
+
+		_, err := ParseJWTToken(legacyToken(t))
+		assert.ErrorContains(t, err, "HS256 tokens are no longer accepted")
+	})
+}
@@ -1,17 +1,27 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/bo"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// jwtSecret is the legacy HS256 shared secret. Once signing keys are set up
+// with InitJwtKeys it is only used to verify tokens issued before the switch,
+// and only until legacySecretUntil.
 var jwtSecret string
 
+// legacySecretUntil is when HS256 tokens stop being accepted once signing keys
+// or a JWKS are in use. While zero they are refused straight away.
+var legacySecretUntil time.Time
+
 func InitJwtSecret() {
 	jwtSecret = os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
@@ -19,28 +29,108 @@ func InitJwtSecret() {
 	}
 }
 
+// AcceptLegacyTokensUntil keeps HS256 tokens signed with JWT_SECRET valid
+// until the deadline after switching to signing keys, so tokens issued before
+// the switch are not cut off. Whoever knows the secret can forge tokens until
+// then, so the deadline should be one access token lifetime after the rollout.
+func AcceptLegacyTokensUntil(deadline time.Time) {
+	legacySecretUntil = deadline
+}
+
+// Audiences an access token can be issued for. A token only grants access to
+// the client it was issued to.
+const (
+	AudienceCustomer = "customer"
+	AudienceMerchant = "merchant"
+	AudienceAdmin    = "admin"
+)
+
 // Claims structure
 type Claims struct {
-	ID int `json:"id"`
+	ID   int    `json:"id"`
+	Role string `json:"role"`
+	// Permissions are resolved from the role when the token is issued, so
+	// services can authorize requests without calling the user service.
+	Permissions []string `json:"perms,omitempty"`
+	// CredentialVersion is bumped whenever the user's password changes, so
+	// tokens issued before the change can be told apart from fresh ones.
+	CredentialVersion int `json:"cv"`
+	// SessionID names the login the token belongs to, so revoking the
+	// session rejects its tokens before they expire.
+	SessionID int64 `json:"sid,omitempty"`
+	// ImpersonatorID is the admin a token issued by impersonation was
+	// handed to. The token acts as user ID, but services should keep the
+	// impersonator out of sensitive actions and may audit its requests.
+	ImpersonatorID int `json:"imp,omitempty"`
+	// ApiKeyID is set on claims resolved from an API key and is never part of
+	// a token.
+	ApiKeyID int64 `json:"-"`
 	jwt.RegisteredClaims
 }
 
-const oneDay = 24 * time.Hour
+// ClaimsChecker performs additional, service-specific checks on a token whose
+// signature and expiry are already valid. A non-nil error rejects the token.
+type ClaimsChecker func(claims *Claims) error
 
-func GenerateJWTToken(user *bo.UserBO) (string, error) {
-	if jwtSecret == "" {
-		return "", fmt.Errorf("JWT secret is not set")
+var claimsCheckers []ClaimsChecker
+
+// ErrAccountUnavailable is matched by ClaimsChecker and ApiKeyValidator errors
+// that refuse valid credentials because of the state of the account, such as
+// a suspension. AuthMiddleware answers them with 403 and the error message
+// instead of a plain 401.
+var ErrAccountUnavailable = errors.New("account is not available")
+
+// RegisterClaimsChecker adds a checker run by ValidateJWTToken. It is meant to
+// be called during startup, before any token is validated.
+func RegisterClaimsChecker(checker ClaimsChecker) {
+	claimsCheckers = append(claimsCheckers, checker)
+}
+
+// accessTokenTTL is how long an issued token stays valid. Keep it short and
+// let clients renew it with a refresh token.
+var accessTokenTTL = 15 * time.Minute
+
+// SetAccessTokenTTL overrides the default token lifetime. It is meant to be
+// called during startup, before any token is issued.
+func SetAccessTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		accessTokenTTL = ttl
+	}
+}
+
+func GenerateJWTToken(user *bo.UserBO, audience string) (string, error) {
+	if signingKeys == nil && jwtSecret == "" {
+		return "", fmt.Errorf("no JWT signing key or secret is set")
+	}
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
 	}
 	// Create a new token object, specifying signing method and the claims
 	claims := Claims{
-		ID: user.ID,
+		ID:                user.ID,
+		Role:              user.Role,
+		Permissions:       user.Permissions,
+		CredentialVersion: user.CredentialVersion,
+		SessionID:         user.SessionID,
+		ImpersonatorID:    user.ImpersonatorID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oneDay)), // Token expiration time
-			IssuedAt:  jwt.NewNumericDate(time.Now()),             // Token issued time
-			NotBefore: jwt.NewNumericDate(time.Now()),             // Token valid from
+			Audience:  jwt.ClaimStrings{audience},                         // Client the token is valid for
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)), // Token expiration time
+			IssuedAt:  jwt.NewNumericDate(time.Now()),                     // Token issued time
+			NotBefore: jwt.NewNumericDate(time.Now()),                     // Token valid from
+			ID:        jti,                                                // Token id, used for revocation
 		},
 	}
 
+	if signingKeys != nil {
+		// Sign with the active key and tell verifiers which key that was
+		active := signingKeys.Active
+		token := jwt.NewWithClaims(active.Method, claims)
+		token.Header["kid"] = active.Kid
+		return token.SignedString(active.PrivateKey)
+	}
+
 	// Create a new token with claims
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
@@ -53,24 +143,97 @@ func GenerateJWTToken(user *bo.UserBO) (string, error) {
 	return tokenString, nil
 }
 
+// newTokenID returns a random identifier for the jti claim
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func ValidateJWTToken(token string) (int, error) {
-	if jwtSecret == "" {
-		return -1, fmt.Errorf("JWT secret is not set")
+	claims, err := ValidateJWTClaims(token)
+	if err != nil {
+		return -1, err
+	}
+	return claims.ID, nil
+}
+
+// ValidateJWTClaims is ValidateJWTToken returning the full claims.
+func ValidateJWTClaims(token string) (*Claims, error) {
+	claims, err := ParseJWTToken(token)
+	if err != nil {
+		return nil, err
 	}
-	parsedToken, err := jwt.ParseWithClaims(token, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	for _, checker := range claimsCheckers {
+		if err := checker(claims); err != nil {
+			return nil, err
 		}
-		return []byte(jwtSecret), nil
-	})
+	}
+	return claims, nil
+}
+
+// IsImpersonated reports whether the token was issued to an admin
+// impersonating the user.
+func (c *Claims) IsImpersonated() bool {
+	return c.ImpersonatorID != 0
+}
+
+// HasAudience reports whether the token was issued for the given client.
+func (c *Claims) HasAudience(audience string) bool {
+	return slices.Contains(c.Audience, audience)
+}
+
+// ParseJWTToken verifies the token signature and standard claims and returns
+// the parsed claims without running the registered ClaimsCheckers.
+func ParseJWTToken(token string) (*Claims, error) {
+	parsedToken, err := jwt.ParseWithClaims(token, &Claims{}, verificationKey,
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodEdDSA.Alg(),
+		}))
 
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
 
 	if claims, ok := parsedToken.Claims.(*Claims); ok && parsedToken.Valid {
-		return claims.ID, nil
+		return claims, nil
 	}
 
-	return -1, errors.New("invalid token")
+	return nil, errors.New("invalid token")
+}
+
+// verificationKey picks the key for a token: the shared secret for legacy
+// HS256 tokens, otherwise the public key named by the kid header, looked up in
+// the local key set first and then in the remote JWKS.
+func verificationKey(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		if jwtSecret == "" {
+			return nil, fmt.Errorf("JWT secret is not set")
+		}
+		if (signingKeys != nil || jwksVerifier != nil) && !time.Now().Before(legacySecretUntil) {
+			return nil, errors.New("HS256 tokens are no longer accepted")
+		}
+		return []byte(jwtSecret), nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+	if signingKeys != nil {
+		if key := signingKeys.Key(kid); key != nil {
+			// The key decides the algorithm, not the token
+			if t.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("JWT key %q does not sign %s tokens", kid, t.Method.Alg())
+			}
+			return key.PublicKey, nil
+		}
+	}
+	if jwksVerifier != nil {
+		return jwksVerifier.Key(kid)
+	}
+	return nil, fmt.Errorf("unknown JWT key id %q", kid)
 }
@@ -0,0 +1,28 @@
+package utils
+
+import "slices"
+
+// Permissions granted to roles through the role_permissions table of the user
+// service and embedded in access tokens.
+const (
+	PermProfileRead  = "profile:read"
+	PermProfileWrite = "profile:write"
+	PermAddressRead  = "address:read"
+	PermAddressWrite = "address:write"
+	PermUsersAdmin   = "users:admin"
+)
+
+// HasPermission reports whether the token grants the permission.
+func (c *Claims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// HasPermissions reports whether the token grants all of the permissions.
+func (c *Claims) HasPermissions(permissions ...string) bool {
+	for _, permission := range permissions {
+		if !c.HasPermission(permission) {
+			return false
+		}
+	}
+	return true
+}
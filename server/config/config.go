@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -11,12 +12,171 @@ var (
 )
 
 type Conf struct {
-	GrpcConfig  *GrpcConfig  `mapstructure:"grpc"`
-	LogConfig   *LogConfig   `mapstructure:"log"`
-	HttpConfig  *HttpConfig  `mapstructure:"http"`
-	MySQLConfig *MySQL       `mapstructure:"mysql"`
-	EmailConfig *EmailConfig `mapstructure:"email"`
-	KafkaConfig *KafkaConfig `mapstructure:"kafka"`
+	GrpcConfig            *GrpcConfig            `mapstructure:"grpc"`
+	LogConfig             *LogConfig             `mapstructure:"log"`
+	HttpConfig            *HttpConfig            `mapstructure:"http"`
+	MySQLConfig           *MySQL                 `mapstructure:"mysql"`
+	EmailConfig           *EmailConfig           `mapstructure:"email"`
+	KafkaConfig           *KafkaConfig           `mapstructure:"kafka"`
+	AuthConfig            *AuthConfig            `mapstructure:"auth"`
+	OidcConfig            *OidcConfig            `mapstructure:"oidc"`
+	LockoutConfig         *LockoutConfig         `mapstructure:"lockout"`
+	RateLimitConfig       *RateLimitConfig       `mapstructure:"rate_limit"`
+	ActivationConfig      *ActivationConfig      `mapstructure:"activation"`
+	PasswordlessConfig    *PasswordlessConfig    `mapstructure:"passwordless"`
+	PasswordPolicy        *PasswordPolicy        `mapstructure:"password_policy"`
+	PasswordHash          *PasswordHash          `mapstructure:"password_hash"`
+	EmailChangeConfig     *EmailChangeConfig     `mapstructure:"email_change"`
+	AccountDeletionConfig *AccountDeletionConfig `mapstructure:"account_deletion"`
+	DataExportConfig      *DataExportConfig      `mapstructure:"data_export"`
+}
+
+// DataExportConfig controls personal data exports. An archive can be
+// downloaded for DownloadTTLHours after it is ready.
+type DataExportConfig struct {
+	DownloadTTLHours int `mapstructure:"download_ttl_hours"`
+	// LinkBaseURL is the public URL of the service prefix the download links
+	// in the ready email point to; the email goes without links while it is
+	// empty
+	LinkBaseURL string `mapstructure:"link_base_url"`
+}
+
+// AccountDeletionConfig controls self-service account deletion. An account is
+// anonymized GraceDays after the request unless the owner cancels it with
+// the link emailed to them.
+type AccountDeletionConfig struct {
+	GraceDays int `mapstructure:"grace_days"`
+	// LinkBaseURL is the public URL of the service prefix the cancel links
+	// point to; the email goes without a link while it is empty
+	LinkBaseURL string `mapstructure:"link_base_url"`
+	FrontendURL string `mapstructure:"frontend_url"`
+}
+
+type EmailChangeConfig struct {
+	// LinkBaseURL is the public URL of the service prefix the revert links
+	// sent to the old address point to; they are left out while it is empty
+	LinkBaseURL string `mapstructure:"link_base_url"`
+	// FrontendURL is where the browser is sent after following a link
+	FrontendURL string `mapstructure:"frontend_url"`
+}
+
+// PasswordHash selects how new passwords are hashed. Hashes made with another
+// algorithm or other parameters keep working and are upgraded on login.
+type PasswordHash struct {
+	// Algorithm is argon2id or bcrypt
+	Algorithm  string        `mapstructure:"algorithm"`
+	BcryptCost int           `mapstructure:"bcrypt_cost"`
+	Argon2id   *Argon2Params `mapstructure:"argon2id"`
+}
+
+type Argon2Params struct {
+	MemoryKiB   uint32 `mapstructure:"memory_kib"`
+	Iterations  uint32 `mapstructure:"iterations"`
+	Parallelism uint8  `mapstructure:"parallelism"`
+	SaltLength  uint32 `mapstructure:"salt_length"`
+	KeyLength   uint32 `mapstructure:"key_length"`
+}
+
+// PasswordPolicy configures the rules new passwords are checked against in
+// register, change password and password reset.
+type PasswordPolicy struct {
+	MinLength int `mapstructure:"min_length"`
+	// RequiredClasses must all be present: lower, upper, letter, digit or
+	// symbol
+	RequiredClasses []string `mapstructure:"required_classes"`
+	// MinClasses is how many of lower, upper, digit and symbol must be present
+	MinClasses int `mapstructure:"min_classes"`
+	// BlockPersonalInfo refuses passwords containing the email or name
+	BlockPersonalInfo bool `mapstructure:"block_personal_info"`
+	// HistorySize is how many previous passwords cannot be reused
+	HistorySize int `mapstructure:"history_size"`
+	// BreachedHashDir holds Pwned Passwords range files, one per 5 character
+	// SHA-1 prefix named <PREFIX>.txt; the check is off while it is empty
+	BreachedHashDir string `mapstructure:"breached_hash_dir"`
+}
+
+type PasswordlessConfig struct {
+	// LinkBaseURL is the public URL of the service prefix the login links in
+	// emails point to; emails only carry the code while it is empty
+	LinkBaseURL string `mapstructure:"link_base_url"`
+	// FrontendURL is where the browser is sent after following a link
+	FrontendURL string `mapstructure:"frontend_url"`
+}
+
+type ActivationConfig struct {
+	// LinkBaseURL is the public URL of the service prefix the activation
+	// links in emails point to, e.g. https://example.com/user-ms/v1
+	LinkBaseURL string `mapstructure:"link_base_url"`
+	// FrontendURL is where the browser is sent after following a link
+	FrontendURL string `mapstructure:"frontend_url"`
+	// LinkSecret signs the links; read from ACTIVATION_LINK_SECRET. Emails
+	// only carry the code while it is empty.
+	LinkSecret string `mapstructure:"-"`
+}
+
+type RateLimitConfig struct {
+	// Backend is "memory" for per-instance buckets or "mysql" to share them
+	// between instances
+	Backend string `mapstructure:"backend"`
+	// Routes maps a route name such as "login" to the limits applied to it
+	Routes map[string][]RateLimitRule `mapstructure:"routes"`
+}
+
+// RateLimitRule is a token bucket allowing Requests per PeriodSeconds, with
+// bursts of up to Burst requests (defaults to Requests).
+type RateLimitRule struct {
+	// Key is what the bucket is kept per: ip, email or route
+	Key           string `mapstructure:"key"`
+	Requests      int    `mapstructure:"requests"`
+	PeriodSeconds int    `mapstructure:"period_seconds"`
+	Burst         int    `mapstructure:"burst"`
+}
+
+// LockoutConfig throttles password guessing. Failures are counted per account
+// and per source IP within FailureWindowMinutes. After DelayAfterFailures
+// failures every further attempt is delayed, doubling from BaseDelaySeconds up
+// to MaxDelaySeconds, and at the lockout threshold the account or IP is
+// blocked for the lockout duration.
+type LockoutConfig struct {
+	FailureWindowMinutes    int `mapstructure:"failure_window_minutes"`
+	DelayAfterFailures      int `mapstructure:"delay_after_failures"`
+	BaseDelaySeconds        int `mapstructure:"base_delay_seconds"`
+	MaxDelaySeconds         int `mapstructure:"max_delay_seconds"`
+	AccountLockoutThreshold int `mapstructure:"account_lockout_threshold"`
+	AccountLockoutMinutes   int `mapstructure:"account_lockout_minutes"`
+	IPLockoutThreshold      int `mapstructure:"ip_lockout_threshold"`
+	IPLockoutMinutes        int `mapstructure:"ip_lockout_minutes"`
+}
+
+type OidcConfig struct {
+	// RedirectBaseURL is the public URL of the service prefix, e.g.
+	// https://example.com/user-ms/v1, used to build the callback URLs
+	RedirectBaseURL string `mapstructure:"redirect_base_url"`
+	// FrontendURL is where the browser is sent after a social login
+	FrontendURL string                         `mapstructure:"frontend_url"`
+	Providers   map[string]*OidcProviderConfig `mapstructure:"providers"`
+}
+
+type OidcProviderConfig struct {
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Scopes       []string `mapstructure:"scopes"`
+	// ResponseMode is sent as response_mode, e.g. form_post for Apple
+	ResponseMode string `mapstructure:"response_mode"`
+}
+
+type AuthConfig struct {
+	AccessTokenTTLMinutes int `mapstructure:"access_token_ttl_minutes"`
+	RefreshTokenTTLHours  int `mapstructure:"refresh_token_ttl_hours"`
+	// PEM files of the asymmetric JWT keys; tokens fall back to the HS256
+	// JWT_SECRET when no signing key is set
+	SigningKeyFile          string   `mapstructure:"signing_key_file"`
+	PreviousSigningKeyFiles []string `mapstructure:"previous_signing_key_files"`
+	// LegacySecretUntil is the RFC 3339 time until which HS256 tokens signed
+	// with JWT_SECRET are still accepted once a signing key is set. While
+	// empty they are refused.
+	LegacySecretUntil string `mapstructure:"legacy_secret_until"`
 }
 
 type EmailConfig struct {
@@ -28,6 +188,10 @@ type EmailConfig struct {
 type HttpConfig struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
+	// TrustedProxies are the addresses or CIDRs X-Forwarded-For is accepted
+	// from. While empty the client IP is the address of the connection, so
+	// clients cannot pick their own IP to get around per-IP limits.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type LogConfig struct {
@@ -53,11 +217,19 @@ type MySQL struct {
 type KafkaConfig struct {
 	Brokers            []string `mapstructure:"brokers"`
 	UserActivatedTopic string   `mapstructure:"user_activated_topic"`
-	MaxBytes           int      `mapstructure:"max_bytes"`
-	Acks               int      `mapstructure:"acks"`
-	Retries            int      `mapstructure:"retries"`
-	BatchSize          int      `mapstructure:"batch_size"`
-	BatchTimeoutMillis int      `mapstructure:"batch_timeout_millis"`
+	PasswordResetTopic string   `mapstructure:"password_reset_topic"`
+	// UserEmailChangedTopic receives a UserEmailChangedEvent per change
+	UserEmailChangedTopic string `mapstructure:"user_email_changed_topic"`
+	// UserDeletedTopic receives a UserDeletedEvent per anonymized account
+	UserDeletedTopic string `mapstructure:"user_deleted_topic"`
+	// UserStatusChangedTopic receives a UserStatusChangedEvent per lifecycle
+	// transition
+	UserStatusChangedTopic string `mapstructure:"user_status_changed_topic"`
+	MaxBytes               int    `mapstructure:"max_bytes"`
+	Acks                   int    `mapstructure:"acks"`
+	Retries                int    `mapstructure:"retries"`
+	BatchSize              int    `mapstructure:"batch_size"`
+	BatchTimeoutMillis     int    `mapstructure:"batch_timeout_millis"`
 }
 
 func Init() {
@@ -83,4 +255,16 @@ func Init() {
 	}
 	Config.EmailConfig.SmtpPass = os.Getenv("SMTP_PASSWORD")
 	Config.EmailConfig.SmtpEmailFrom = os.Getenv("SMTP_EMAIL_FROM")
+	if Config.ActivationConfig != nil {
+		Config.ActivationConfig.LinkSecret = os.Getenv("ACTIVATION_LINK_SECRET")
+	}
+	if Config.OidcConfig != nil {
+		for name, provider := range Config.OidcConfig.Providers {
+			// e.g. OIDC_GOOGLE_CLIENT_SECRET
+			secret := os.Getenv("OIDC_" + strings.ToUpper(name) + "_CLIENT_SECRET")
+			if secret != "" {
+				provider.ClientSecret = secret
+			}
+		}
+	}
 }
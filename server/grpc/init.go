@@ -1,17 +1,26 @@
 package grpc
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
 	"time"
 
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/middleware"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/userpb"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
+// methodPermissions lists the permissions required by protected gRPC methods,
+// keyed by full method name.
+var methodPermissions = map[string][]string{}
+
 func Init(exitSig chan os.Signal) {
 	ipPort := fmt.Sprintf("%s:%d", config.Config.GrpcConfig.Host, config.Config.GrpcConfig.Port)
 	listener, err := net.Listen("tcp", ipPort)
@@ -26,6 +35,11 @@ func Init(exitSig chan os.Signal) {
 		grpc.MaxConcurrentStreams(uint32(config.Config.GrpcConfig.MaxPoolSize)),                      // Set maximum concurrent streams
 		grpc.MaxRecvMsgSize(1024 * 1024), // Set maximum receive message size (1MB here)
 		grpc.MaxSendMsgSize(1024 * 1024), // Set maximum send message size (1MB here)
+		grpc.ChainUnaryInterceptor(
+			middleware.AuthUnaryInterceptor(userpb.UserService_SayHello_FullMethodName),
+			impersonationAuditInterceptor(),
+			middleware.PermissionUnaryInterceptor(methodPermissions),
+		),
 	}
 	grpcServer := grpc.NewServer(opts...)
 	userpb.RegisterUserServiceServer(grpcServer, &UserService{})
@@ -36,3 +50,36 @@ func Init(exitSig chan os.Signal) {
 		exitSig <- os.Interrupt
 	}
 }
+
+// impersonationAuditInterceptor records every call made with an impersonation
+// token, like the HTTP impersonationAudit middleware. It runs right after
+// authentication so calls refused for missing permissions are recorded too.
+// The method is recorded as GRPC and the status as the gRPC status code.
+func impersonationAuditInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		claims, ok := middleware.ClaimsFromContext(ctx)
+		if !ok || !claims.IsImpersonated() {
+			return handler(ctx, req)
+		}
+		resp, err := handler(ctx, req)
+		ctx = service.WithClientInfo(ctx, service.ClientInfo{IP: peerIP(ctx)})
+		auditErr := service.GetImpersonationService().RecordRequest(ctx, claims, "GRPC", info.FullMethod, int(status.Code(err)))
+		if auditErr != nil {
+			log.Logger.Errorf("Failed to audit impersonated call of user %d by admin %d: %v", claims.ID, claims.ImpersonatorID, auditErr)
+		}
+		return resp, err
+	}
+}
+
+// peerIP returns the address the call came from, without the port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteAccount schedules the deletion of the current user.
+// @Summary Delete Account
+// @Description Schedules the deletion of the current account after checking the password and, with 2FA enabled, a TOTP or recovery code. The user is signed out everywhere and emailed a link to cancel; when the grace period ends the account is anonymized and its addresses are deleted.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param req body data.AccountDeletionReq true "Password and, with 2FA enabled, a code"
+// @Success 200 {object} data.BaseResponse{data=data.AccountDeletionVO}
+// @Failure 400 {object} data.BaseResponse "Wrong password or code"
+// @Failure 403 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "Deletion is already scheduled"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/customer/users/self [delete]
+func DeleteAccount(c *gin.Context) {
+	req := &data.AccountDeletionReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	deleteTime, err := service.GetAccountDeletionService().RequestDeletion(c.Request.Context(), userId.(int), req.Password, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIncorrectPassword), errors.Is(err, service.ErrInvalidMfaCode):
+			c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		case errors.Is(err, service.ErrAccountPendingDeletion):
+			c.JSON(http.StatusConflict, data.BaseResponse{Code: http.StatusConflict, ErrMsg: err.Error()})
+		case errors.Is(err, utils.ErrAccountUnavailable):
+			c.JSON(http.StatusForbidden, data.BaseResponse{Code: http.StatusForbidden, ErrMsg: err.Error()})
+		case errors.Is(err, service.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		}
+		return
+	}
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: data.AccountDeletionVO{DeleteTime: deleteTime}})
+}
+
+// CancelAccountDeletion keeps an account scheduled for deletion.
+// @Summary Cancel Account Deletion
+// @Description Follows the link emailed when the deletion was requested. It keeps the account and redirects to the frontend with account_deletion=cancelled, invalid or error; the user signs in again afterwards.
+// @Tags User
+// @Param token query string true "Token of the cancel link"
+// @Success 302
+// @Router /user-ms/v1/customer/users/deletion/cancel [get]
+func CancelAccountDeletion(c *gin.Context) {
+	target := "/"
+	if cfg := config.Config.AccountDeletionConfig; cfg != nil && cfg.FrontendURL != "" {
+		target = cfg.FrontendURL
+	}
+	err := service.GetAccountDeletionService().CancelDeletion(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		reason := "error"
+		if errors.Is(err, service.ErrInvalidDeletionCancelLink) {
+			reason = "invalid"
+		} else {
+			log.Logger.Errorf("Account deletion cancel error: %v", err)
+		}
+		redirectWithParam(c, target, "account_deletion", reason)
+		return
+	}
+	redirectWithParam(c, target, "account_deletion", "cancelled")
+}
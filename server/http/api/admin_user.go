@@ -0,0 +1,317 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchUsers lists users for staff.
+// @Summary Search Users
+// @Description Lists the users matching the filters, newest first. Requires an admin token with the users:admin permission.
+// @Tags Admin
+// @Produce json
+// @Param email query string false "Email prefix"
+// @Param status query string false "Account status" Enums(pending, active, locked, suspended, pending_deletion, deleted)
+// @Param created_from query string false "Registered at or after, RFC 3339"
+// @Param created_to query string false "Registered before, RFC 3339"
+// @Param page query int false "Page, starting at 1" default(1)
+// @Param page_size query int false "Users per page, at most 100" default(20)
+// @Success 200 {object} data.BaseResponse{data=data.AdminUserPageVO}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/admin/accounts [get]
+func SearchUsers(c *gin.Context) {
+	req := &data.AdminUserSearchReq{}
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	filter := dao.UserSearchFilter{EmailPrefix: req.Email, CreatedFrom: req.CreatedFrom, CreatedTo: req.CreatedTo}
+	if req.Status != "" {
+		status, _ := model.UserStatusByName(req.Status)
+		filter.Status = &status
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = service.AdminUserDefaultPageSize
+	}
+	users, total, err := service.GetAdminUserService().SearchUsers(c.Request.Context(), filter, req.Page, req.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	vos := make([]data.AdminUserVO, 0, len(users))
+	for _, user := range users {
+		vos = append(vos, toAdminUserVO(user))
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: data.AdminUserPageVO{
+		Users: vos, Total: total, Page: req.Page, PageSize: req.PageSize,
+	}})
+}
+
+// GetUser shows a user to staff.
+// @Summary Get User
+// @Description Returns a user with their addresses and the history of their account status. Requires an admin token with the users:admin permission.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} data.BaseResponse{data=data.AdminUserDetailVO}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/admin/accounts/{user_id} [get]
+func GetUser(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+	detail, err := service.GetAdminUserService().GetUserDetail(c.Request.Context(), userID)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	history := make([]data.StatusChangeVO, 0, len(detail.StatusHistory))
+	for _, change := range detail.StatusHistory {
+		history = append(history, data.StatusChangeVO{
+			FromStatus: model.UserStatusNames[change.FromStatus],
+			ToStatus:   model.UserStatusNames[change.ToStatus],
+			Reason:     change.Reason,
+			ActorID:    change.ActorID,
+			CreatedAt:  change.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: data.AdminUserDetailVO{
+		AdminUserVO:   toAdminUserVO(detail.User),
+		Addresses:     detail.Addresses,
+		StatusHistory: history,
+	}})
+}
+
+// SuspendUser blocks an account.
+// @Summary Suspend User
+// @Description Suspends an account until it is unsuspended. The user is signed out everywhere and cannot sign in meanwhile. The reason is kept in the status history. Admins cannot suspend themselves.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Param req body data.AdminSuspendReq true "Reason of the suspension"
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "The account cannot be suspended from its current status"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/admin/accounts/{user_id}/suspension [put]
+func SuspendUser(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+	req := &data.AdminSuspendReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	err := service.GetAdminUserService().Suspend(c.Request.Context(), c.GetInt("userID"), userID, req.Reason)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "User suspended"})
+}
+
+// UnsuspendUser lifts a suspension.
+// @Summary Unsuspend User
+// @Description Returns a suspended account to the status it was suspended from. A pending account still has to be activated and a locked one still needs a password reset.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "The account is not suspended"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/admin/accounts/{user_id}/suspension [delete]
+func UnsuspendUser(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+	err := service.GetAdminUserService().Unsuspend(c.Request.Context(), c.GetInt("userID"), userID)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "User unsuspended"})
+}
+
+// ForcePasswordReset makes a user choose a new password.
+// @Summary Force Password Reset
+// @Description Locks an active account, signing the user out everywhere, and emails a password reset code. Resetting the password unlocks the account. For an account that is already locked the code is sent again.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "The account is neither active nor locked"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/admin/accounts/{user_id}/password-reset [post]
+func ForcePasswordReset(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+	err := service.GetAdminUserService().ForcePasswordReset(c.Request.Context(), c.GetInt("userID"), userID)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Password reset code sent"})
+}
+
+// ResendUserActivation sends a pending user a new activation code.
+// @Summary Resend User Activation
+// @Description Emails a new activation code to a pending account. The limits of the public resend endpoint apply.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "The account is not pending activation"
+// @Failure 429 {object} data.BaseResponse "Sent too recently or too often, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/admin/accounts/{user_id}/activation/resend [post]
+func ResendUserActivation(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+	err := service.GetAdminUserService().ResendActivation(c.Request.Context(), c.GetInt("userID"), userID)
+	if err != nil {
+		if respondActivationThrottled(c, err) {
+			return
+		}
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Activation code sent"})
+}
+
+// ActivateUser activates a pending user without the code.
+// @Summary Activate User
+// @Description Activates a pending account by hand, e.g. when the activation emails do not arrive.
+// @Tags Admin
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "The account is not pending activation"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/admin/accounts/{user_id}/activation [post]
+func ActivateUser(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+	err := service.GetAdminUserService().Activate(c.Request.Context(), c.GetInt("userID"), userID)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "User activated"})
+}
+
+// ImpersonateUser lets an admin act as a user.
+// @Summary Impersonate User
+// @Description Issues an access token for an active customer or merchant that records the admin as the impersonator. The token is valid for one access token lifetime and cannot be refreshed; use it as the auth-token cookie of the user's client. Changing the password or email, deleting the account and the other account management endpoints refuse it. The reason and every request made with the token are recorded.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Param req body data.ImpersonationReq true "Reason of the impersonation"
+// @Success 200 {object} data.BaseResponse{data=data.ImpersonationVO}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse "Admins and the own account cannot be impersonated, and API keys cannot start an impersonation"
+// @Failure 404 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "The account is not active"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/admin/accounts/{user_id}/impersonation [post]
+func ImpersonateUser(c *gin.Context) {
+	userID, ok := targetUserID(c)
+	if !ok {
+		return
+	}
+	req := &data.ImpersonationReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	grant, err := service.GetImpersonationService().Start(c.Request.Context(), c.GetInt("userID"), userID, req.Reason)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: data.ImpersonationVO{
+		ID:          grant.ID,
+		UserID:      grant.UserID,
+		Client:      grant.Audience,
+		AccessToken: grant.AccessToken,
+		ExpiresAt:   grant.ExpiresAt,
+	}})
+}
+
+// targetUserID reads the :user_id an admin endpoint acts on, answering 400
+// if it is not a valid ID.
+func targetUserID(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: "Invalid user ID"})
+		return 0, false
+	}
+	return userID, true
+}
+
+func respondAdminError(c *gin.Context, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, service.ErrCannotManageSelf), errors.Is(err, service.ErrCannotImpersonate):
+		code = http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidStatusTransition), errors.Is(err, service.ErrStatusChanged),
+		errors.Is(err, service.ErrUserNotPending), errors.Is(err, utils.ErrAccountUnavailable):
+		code = http.StatusConflict
+	}
+	c.JSON(code, data.BaseResponse{Code: code, ErrMsg: err.Error()})
+}
+
+func toAdminUserVO(user *model.User) data.AdminUserVO {
+	return data.AdminUserVO{
+		ID:           user.ID,
+		Email:        user.Email,
+		Name:         user.Name,
+		Role:         user.Role,
+		Status:       model.UserStatusNames[user.Status],
+		CreatedAt:    user.CreatedAt,
+		ActivateTime: user.ActivateTime,
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateApiKey creates an API key for the current user.
+// @Summary Create API Key
+// @Description Creates a key to send as "Authorization: ApiKey <key>". Its scopes must be permissions of the user's role. The key is only returned in this response. API keys cannot manage API keys.
+// @Tags ApiKey
+// @Accept json
+// @Produce json
+// @Param req body data.ApiKeyCreateReq true "Name, scopes and lifetime of the key"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 201 {object} data.BaseResponse{data=data.ApiKeyCreatedVO}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/api-keys [post]
+func CreateApiKey(c *gin.Context) {
+	req := &data.ApiKeyCreateReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		expiry := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &expiry
+	}
+	created, err := service.GetApiKeyService().Create(c.Request.Context(), userId.(int), req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		respondApiKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, data.BaseResponse{Code: http.StatusCreated, Data: data.ApiKeyCreatedVO{
+		ApiKeyVO: toApiKeyVO(created.ApiKey),
+		Key:      created.Key,
+	}})
+}
+
+// ListApiKeys lists the API keys of the current user.
+// @Summary List API Keys
+// @Description Lists the keys of the current user without their secrets.
+// @Tags ApiKey
+// @Produce json
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=[]data.ApiKeyVO}
+// @Failure 403 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/api-keys [get]
+func ListApiKeys(c *gin.Context) {
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	apiKeys, err := service.GetApiKeyService().List(c.Request.Context(), userId.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	vos := make([]data.ApiKeyVO, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		vos = append(vos, toApiKeyVO(apiKey))
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: vos})
+}
+
+// UpdateApiKey renames an API key and replaces its scopes.
+// @Summary Update API Key
+// @Description Renames a key of the current user and replaces its scopes.
+// @Tags ApiKey
+// @Accept json
+// @Produce json
+// @Param key_id path int true "API key ID"
+// @Param req body data.ApiKeyUpdateReq true "New name and scopes"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=data.ApiKeyVO}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/api-keys/{key_id} [put]
+func UpdateApiKey(c *gin.Context) {
+	req := &data.ApiKeyUpdateReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	keyId, err := strconv.ParseInt(c.Param("key_id"), 10, 64)
+	if err != nil || keyId <= 0 {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: "Invalid API key ID"})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	apiKey, err := service.GetApiKeyService().Update(c.Request.Context(), userId.(int), keyId, req.Name, req.Scopes)
+	if err != nil {
+		respondApiKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: toApiKeyVO(apiKey)})
+}
+
+// DeleteApiKey revokes an API key.
+// @Summary Delete API Key
+// @Description Revokes a key of the current user immediately.
+// @Tags ApiKey
+// @Produce json
+// @Param key_id path int true "API key ID"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/api-keys/{key_id} [delete]
+func DeleteApiKey(c *gin.Context) {
+	keyId, err := strconv.ParseInt(c.Param("key_id"), 10, 64)
+	if err != nil || keyId <= 0 {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: "Invalid API key ID"})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	if err := service.GetApiKeyService().Delete(c.Request.Context(), userId.(int), keyId); err != nil {
+		respondApiKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "API key deleted"})
+}
+
+func respondApiKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidApiKeyScope), errors.Is(err, service.ErrApiKeyLimit):
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+	case errors.Is(err, service.ErrApiKeyNotFound), errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+	}
+}
+
+func toApiKeyVO(apiKey *model.ApiKey) data.ApiKeyVO {
+	return data.ApiKeyVO{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
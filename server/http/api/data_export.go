@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestDataExport starts an export of the personal data of the current
+// user.
+// @Summary Request Data Export
+// @Description Starts collecting the profile, all addresses including deleted ones, the login sessions, social logins, API keys, status history and support staff impersonations of the current user into an archive. The archive is built in the background; poll GET /{client}/users/self/data-export until it is ready, then download it with the returned token, which is not shown again. The user is also emailed when it is ready.
+// @Tags Data Export
+// @Produce json
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 202 {object} data.BaseResponse{data=data.DataExportVO}
+// @Failure 403 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "An export is already being prepared"
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/data-export [post]
+func RequestDataExport(c *gin.Context) {
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	export, token, err := service.GetDataExportService().RequestExport(c.Request.Context(), userId.(int))
+	if err != nil {
+		if errors.Is(err, service.ErrDataExportInProgress) {
+			c.JSON(http.StatusConflict, data.BaseResponse{Code: http.StatusConflict, ErrMsg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	vo := toDataExportVO(export)
+	vo.DownloadToken = token
+	c.JSON(http.StatusAccepted, data.BaseResponse{Code: http.StatusAccepted, Data: vo})
+}
+
+// GetDataExport shows the status of the latest data export.
+// @Summary Get Data Export Status
+// @Description Returns the latest data export of the current user. Its status is pending while the archive is built, then ready until expires_at, or failed.
+// @Tags Data Export
+// @Produce json
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=data.DataExportVO}
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/data-export [get]
+func GetDataExport(c *gin.Context) {
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	export, err := service.GetDataExportService().GetLatest(c.Request.Context(), userId.(int))
+	if err != nil {
+		if errors.Is(err, service.ErrDataExportNotFound) {
+			c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: toDataExportVO(export)})
+}
+
+// DownloadDataExport returns the archive of a ready data export.
+// @Summary Download Data Export
+// @Description Downloads the archive of a data export with its token, as a JSON document or a ZIP file holding it. The token works until the export expires.
+// @Tags Data Export
+// @Produce json
+// @Produce application/zip
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Param token query string true "Download token"
+// @Param format query string false "Archive format" Enums(json, zip) default(json)
+// @Success 200 {file} file
+// @Failure 400 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse "Unknown or expired token"
+// @Failure 409 {object} data.BaseResponse "The export is not ready yet"
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/data-export/download [get]
+func DownloadDataExport(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: "format must be json or zip"})
+		return
+	}
+	export, err := service.GetDataExportService().Download(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidDataExportToken):
+			c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: err.Error()})
+		case errors.Is(err, service.ErrDataExportNotReady):
+			c.JSON(http.StatusConflict, data.BaseResponse{Code: http.StatusConflict, ErrMsg: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		}
+		return
+	}
+	fileName := fmt.Sprintf("cermicraft-data-%s", export.CreatedAt.Format("20060102"))
+	c.Header("Cache-Control", "no-store")
+	if format == "zip" {
+		archive, err := service.DataExportZip(export)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName+".zip"))
+		c.Data(http.StatusOK, "application/zip", archive)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName+".json"))
+	c.Data(http.StatusOK, "application/json", export.Archive)
+}
+
+func toDataExportVO(export *model.UserDataExport) data.DataExportVO {
+	return data.DataExportVO{
+		ID:          export.ID,
+		Status:      export.Status,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestEmailChange starts changing the login email of the current user.
+// @Summary Request Email Change
+// @Description Checks the current password and emails a code to the new address. The email only changes once the code is confirmed. A new request replaces the pending one.
+// @Tags Email
+// @Accept json
+// @Produce json
+// @Param req body data.EmailChangeReq true "New email and current password"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse "Wrong password or the email is unchanged"
+// @Failure 403 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "The email is already in use"
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/email [post]
+func RequestEmailChange(c *gin.Context) {
+	req := &data.EmailChangeReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	err := service.GetEmailChangeService().RequestChange(c.Request.Context(), userId.(int), req.Password, req.NewEmail)
+	if err != nil {
+		respondEmailChangeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Please check the new email for the confirmation code"})
+}
+
+// ConfirmEmailChange switches the current user to the new email.
+// @Summary Confirm Email Change
+// @Description Confirms the pending email change with the code sent to the new address. The old address is notified and gets a link to undo the change. A code expires after 15 minutes and 5 wrong attempts.
+// @Tags Email
+// @Accept json
+// @Produce json
+// @Param req body data.EmailChangeConfirmReq true "Code sent to the new email"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse "The email was taken in the meantime"
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/email [put]
+func ConfirmEmailChange(c *gin.Context) {
+	req := &data.EmailChangeConfirmReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	if err := service.GetEmailChangeService().ConfirmChange(c.Request.Context(), userId.(int), req.Code); err != nil {
+		respondEmailChangeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Email changed"})
+}
+
+// RevertEmailChange undoes an email change with the link sent to the old
+// address.
+// @Summary Revert Email Change
+// @Description Follows the link emailed to the old address after an email change. It restores the old email, signs the user out everywhere and redirects to the frontend with email_revert=success, invalid, email_taken or error.
+// @Tags Email
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Param token query string true "Token of the revert link"
+// @Success 302
+// @Router /user-ms/v1/{client}/users/email/revert [get]
+func RevertEmailChange(c *gin.Context) {
+	target := "/"
+	if cfg := config.Config.EmailChangeConfig; cfg != nil && cfg.FrontendURL != "" {
+		target = cfg.FrontendURL
+	}
+	err := service.GetEmailChangeService().RevertChange(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		reason := "error"
+		switch {
+		case errors.Is(err, service.ErrInvalidRevertLink):
+			reason = "invalid"
+		case errors.Is(err, service.ErrEmailTaken):
+			reason = "email_taken"
+		default:
+			log.Logger.Errorf("Email change revert error: %v", err)
+		}
+		redirectWithParam(c, target, "email_revert", reason)
+		return
+	}
+	clearAuthCookies(c)
+	redirectWithParam(c, target, "email_revert", "success")
+}
+
+func respondEmailChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrIncorrectPassword), errors.Is(err, service.ErrSameEmail), errors.Is(err, service.ErrInvalidEmailChangeCode):
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+	case errors.Is(err, service.ErrEmailTaken):
+		c.JSON(http.StatusConflict, data.BaseResponse{Code: http.StatusConflict, ErrMsg: err.Error()})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+	}
+}
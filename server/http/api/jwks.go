@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publishes the public keys that verify access tokens.
+//
+// @Summary JSON Web Key Set
+// @Description Returns the public keys of the active and previous JWT signing keys. Other services use it to validate access tokens without sharing a secret.
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} map[string]interface{} "JWK set as defined by RFC 7517"
+// @Router /user-ms/v1/.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.PublicJWKS())
+}
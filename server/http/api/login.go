@@ -1,29 +1,42 @@
 package api
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-const tokenExpireDuration = 3600 * 24 * 365 // 1 year
+const (
+	authCookieName    = "auth-token"
+	refreshCookieName = "refresh-token"
+	// The refresh token is only needed by the refresh and logout endpoints
+	refreshCookiePath = "/user-ms/v1"
+)
 
 // UserLogin handles user login requests.
 //
 // @Summary User Login
-// @Description Authenticates a user with their email and password and returns a token.
+// @Description Authenticates a user with their email and password and returns a token. For users with two-factor authentication enabled no token is issued; the response carries a challenge to complete at /{client}/login/mfa instead.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param user body data.UserLoginVO true "User login information"
-// @Param client path string true "Client identifier" Enums(customer, merchant)
-// @Success 200	{object} data.BaseResponse{data=string} "Login successful, returns auth token in cookie"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200	{object} data.BaseResponse{data=string} "Login successful, returns auth and refresh tokens in cookies"
 // @Failure 400 {object} data.BaseResponse{data=string}
+// @Failure 401 {object} data.BaseResponse{data=string} "Unknown email or wrong password, the two are not told apart"
+// @Failure 403 {object} data.BaseResponse{data=string} "The account cannot sign in to this client or is not active, e.g. suspended"
+// @Failure 429 {object} data.BaseResponse{data=string} "Too many failed attempts for the account or IP, see the Retry-After header"
 // @Failure 500 {object} data.BaseResponse{data=string}
 // @Router /user-ms/v1/{client}/login [post]
 func UserLogin(c *gin.Context) {
@@ -32,34 +45,143 @@ func UserLogin(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, data.BaseResponse{ErrMsg: err.Error()})
 		return
 	}
-	token, err := service.GetLoginService().Login(c.Request.Context(), user.Email, user.Password)
+	result, err := service.GetLoginService().Login(c.Request.Context(), c.Param("client"), user.Email, user.Password, c.ClientIP())
 	if err != nil {
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, data.BaseResponse{Code: http.StatusTooManyRequests, ErrMsg: err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrClientNotAllowed) || errors.Is(err, utils.ErrAccountUnavailable) {
+			c.JSON(http.StatusForbidden, data.BaseResponse{Code: http.StatusForbidden, ErrMsg: err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: err.Error()})
+			return
+		}
 		log.Logger.Errorf("Login error: %v", err)
-		c.JSON(http.StatusInternalServerError, data.BaseResponse{ErrMsg: err.Error()})
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	respondLoginResult(c, result)
+}
+
+// accountUnavailableReason names the status of an account refused with a
+// service.AccountStateError for redirects, e.g. account_suspended.
+func accountUnavailableReason(err error) string {
+	var stateErr *service.AccountStateError
+	if errors.As(err, &stateErr) {
+		return "account_" + model.UserStatusNames[stateErr.Status]
+	}
+	return "account_unavailable"
+}
+
+// respondLoginResult sets the auth cookies of a completed login or returns
+// the challenge of a login that still needs the second factor.
+func respondLoginResult(c *gin.Context, result *service.LoginResult) {
+	if result.MfaChallenge != "" {
+		c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: data.MfaChallengeVO{
+			MfaRequired: true,
+			Challenge:   result.MfaChallenge,
+			ExpiresIn:   int(service.MfaChallengeTTL.Seconds()),
+		}})
+		return
+	}
+	setAuthCookies(c, result.Tokens)
+	c.JSON(http.StatusOK, data.BaseResponse{Data: "Login successful"})
+}
+
+// RefreshToken handles access token renewal.
+//
+// @Summary Refresh Token
+// @Description Exchanges the refresh token cookie for a new access token and a rotated refresh token. Replaying a used refresh token revokes all tokens derived from the same login.
+// @Tags Authentication
+// @Produce json
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string} "returns new auth and refresh tokens in cookies"
+// @Failure 401 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse "The account is no longer active, e.g. suspended"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/token/refresh [post]
+func RefreshToken(c *gin.Context) {
+	refreshToken, err := c.Cookie(refreshCookieName)
+	if err != nil || refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Refresh token cookie is required"})
 		return
 	}
+	tokens, err := service.GetTokenService().Refresh(c.Request.Context(), c.Param("client"), refreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			clearAuthCookies(c)
+			c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: err.Error()})
+			return
+		}
+		if errors.Is(err, utils.ErrAccountUnavailable) {
+			clearAuthCookies(c)
+			c.JSON(http.StatusForbidden, data.BaseResponse{Code: http.StatusForbidden, ErrMsg: err.Error()})
+			return
+		}
+		log.Logger.Errorf("Refresh token error: %v", err)
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	setAuthCookies(c, tokens)
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Token refreshed"})
+}
+
+func setAuthCookies(c *gin.Context, tokens *service.AuthTokens) {
+	// Keep the access token cookie as long as the refresh token so that an
+	// expired access token yields 401 and the client knows to refresh.
+	expires := time.Now().Add(service.RefreshTokenTTL())
 	http.SetCookie(c.Writer, &http.Cookie{
-		Name:     "auth-token",
-		Value:    token,
+		Name:     authCookieName,
+		Value:    tokens.AccessToken,
 		Path:     "/",
 		Domain:   c.Request.Host,
-		Expires:  time.Now().Add(time.Duration(tokenExpireDuration) * time.Second),
+		Expires:  expires,
 		Secure:   false,
 		HttpOnly: true,
 	})
-	c.JSON(http.StatusOK, data.BaseResponse{Data: "Login successful"})
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    tokens.RefreshToken,
+		Path:     refreshCookiePath,
+		Domain:   c.Request.Host,
+		Expires:  expires,
+		Secure:   false,
+		HttpOnly: true,
+	})
+}
+
+func clearAuthCookies(c *gin.Context) {
+	// Invalidate the cookies by setting their MaxAge to -1
+	c.SetCookie(authCookieName, "", -1, "/", c.Request.Host, true, true)
+	c.SetCookie(refreshCookieName, "", -1, refreshCookiePath, c.Request.Host, true, true)
 }
 
 // UserLogout handles user logout requests.
 //
 // @Summary User Logout
-// @Description invalidates the user's auth token cookie.
+// @Description Revokes the user's refresh token family and, while it is still valid, the access token, then clears the cookies. No valid access token is needed, so clients can log out after it expired.
 // @Tags Authentication
-// @Param client path string true "Client identifier" Enums(customer, merchant)
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
 // @Success 200 object data.BaseResponse{data=string} "Logout successful"
+// @Failure 500 {object} data.BaseResponse
 // @Router /user-ms/v1/{client}/logout [post]
 func UserLogout(c *gin.Context) {
-	// Invalidate the auth-token cookie by setting its MaxAge to -1
-	c.SetCookie("auth-token", "", -1, "/", c.Request.Host, true, true)
+	var claims *utils.Claims
+	if authCookie, err := c.Cookie(authCookieName); err == nil && authCookie != "" {
+		// An expired or invalid access token has nothing left to revoke
+		claims, _ = utils.ParseJWTToken(authCookie)
+	}
+	refreshToken, _ := c.Cookie(refreshCookieName)
+	clearAuthCookies(c)
+	if err := service.GetLoginService().Logout(c.Request.Context(), claims, refreshToken); err != nil {
+		log.Logger.Errorf("Logout error: %v", err)
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{ErrMsg: err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, data.BaseResponse{Data: "Logout successful"})
 }
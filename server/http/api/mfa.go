@@ -0,0 +1,185 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyMfaLogin completes a login that requires a second factor.
+// @Summary Verify Second Factor
+// @Description Exchanges the challenge returned by login and a TOTP or recovery code for auth and refresh tokens.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param req body data.MfaLoginReq true "Login challenge and code"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string} "returns auth and refresh tokens in cookies"
+// @Failure 400 {object} data.BaseResponse
+// @Failure 401 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse "The account is not active, e.g. suspended"
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/login/mfa [post]
+func VerifyMfaLogin(c *gin.Context) {
+	req := &data.MfaLoginReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	tokens, err := service.GetMfaService().VerifyChallenge(c.Request.Context(), c.Param("client"), req.Challenge, req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMfaChallenge) || errors.Is(err, service.ErrInvalidMfaCode) {
+			c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: err.Error()})
+			return
+		}
+		if errors.Is(err, utils.ErrAccountUnavailable) {
+			c.JSON(http.StatusForbidden, data.BaseResponse{Code: http.StatusForbidden, ErrMsg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	setAuthCookies(c, tokens)
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Login successful"})
+}
+
+// EnrollMfa starts two-factor enrollment.
+// @Summary Start 2FA Enrollment
+// @Description Generates a TOTP secret for the current user. The otpauth URI is the payload of the QR code to scan with an authenticator app. 2FA is only enabled after confirming a code.
+// @Tags MFA
+// @Produce json
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=data.MfaEnrollmentVO}
+// @Failure 409 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/mfa [post]
+func EnrollMfa(c *gin.Context) {
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	enrollment, err := service.GetMfaService().BeginEnrollment(c.Request.Context(), userId.(int))
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: data.MfaEnrollmentVO{
+		Secret:     enrollment.Secret,
+		OtpauthURI: enrollment.URI,
+	}})
+}
+
+// ConfirmMfa enables two-factor authentication.
+// @Summary Confirm 2FA Enrollment
+// @Description Enables 2FA after verifying a code from the authenticator app and returns single-use recovery codes. They are shown only once. After 5 wrong codes the enrollment has to be started again.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param req body data.MfaCodeReq true "TOTP code"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=data.MfaRecoveryCodesVO}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 409 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/mfa [put]
+func ConfirmMfa(c *gin.Context) {
+	req := &data.MfaCodeReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	codes, err := service.GetMfaService().ConfirmEnrollment(c.Request.Context(), userId.(int), req.Code)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: data.MfaRecoveryCodesVO{RecoveryCodes: codes}})
+}
+
+// DisableMfa turns two-factor authentication off.
+// @Summary Disable 2FA
+// @Description Disables 2FA for the current user after verifying the password and a TOTP or recovery code.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param req body data.MfaDisableReq true "Password and code"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/mfa [delete]
+func DisableMfa(c *gin.Context) {
+	req := &data.MfaDisableReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	err := service.GetMfaService().Disable(c.Request.Context(), userId.(int), req.Password, req.Code)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes.
+// @Summary Regenerate Recovery Codes
+// @Description Invalidates all recovery codes of the current user and returns new ones after verifying a TOTP or recovery code.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param req body data.MfaCodeReq true "TOTP or recovery code"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=data.MfaRecoveryCodesVO}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	req := &data.MfaCodeReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	codes, err := service.GetMfaService().RegenerateRecoveryCodes(c.Request.Context(), userId.(int), req.Code)
+	if err != nil {
+		respondMfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: data.MfaRecoveryCodesVO{RecoveryCodes: codes}})
+}
+
+func respondMfaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrMfaAlreadyEnabled):
+		c.JSON(http.StatusConflict, data.BaseResponse{Code: http.StatusConflict, ErrMsg: err.Error()})
+	case errors.Is(err, service.ErrInvalidMfaCode), errors.Is(err, service.ErrIncorrectPassword),
+		errors.Is(err, service.ErrMfaNotEnabled), errors.Is(err, service.ErrMfaNotEnrolled),
+		errors.Is(err, service.ErrMfaEnrollmentSpent):
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: "User not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+	}
+}
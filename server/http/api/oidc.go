@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookieName = "oidc-state"
+	oidcCookiePath      = "/user-ms/v1/customer/oidc"
+)
+
+// OidcAuthorize starts a social login.
+// @Summary Social Login
+// @Description Redirects the browser to the identity provider. After signing in there, the provider sends the browser back to the callback endpoint.
+// @Tags Authentication
+// @Param provider path string true "Identity provider as configured, e.g. google"
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/customer/oidc/{provider}/authorize [get]
+func OidcAuthorize(c *gin.Context) {
+	auth, err := service.GetOidcLoginService().BeginLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownOidcProvider) {
+			c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	// Binds the state to this browser so a callback cannot be replayed into
+	// another user's session. A form_post callback is a cross-site POST,
+	// which only carries SameSite=None cookies.
+	sameSite := http.SameSiteLaxMode
+	if auth.FormPost {
+		sameSite = http.SameSiteNoneMode
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    auth.State,
+		Path:     oidcCookiePath,
+		MaxAge:   int(service.OidcLoginStateTTL.Seconds()),
+		Secure:   auth.FormPost,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+	c.Redirect(http.StatusFound, auth.URL)
+}
+
+// OidcCallback completes a social login.
+// @Summary Social Login Callback
+// @Description Called by the identity provider. Signs the user in, creating an account for unknown verified emails, and redirects to the frontend. The redirect carries mfa_challenge when a second factor is needed, or oidc_error when the login failed.
+// @Tags Authentication
+// @Param provider path string true "Identity provider as configured, e.g. google"
+// @Param code query string false "Authorization code"
+// @Param state query string false "Login state"
+// @Success 302 "Redirect to the frontend, with auth and refresh tokens in cookies"
+// @Router /user-ms/v1/customer/oidc/{provider}/callback [get]
+// @Router /user-ms/v1/customer/oidc/{provider}/callback [post]
+func OidcCallback(c *gin.Context) {
+	state := c.Request.FormValue("state")
+	code := c.Request.FormValue("code")
+	cookieState, _ := c.Cookie(oidcStateCookieName)
+	c.SetCookie(oidcStateCookieName, "", -1, oidcCookiePath, "", false, true)
+	if providerErr := c.Request.FormValue("error"); providerErr != "" {
+		log.Logger.Warnf("Identity provider %s returned error: %s", c.Param("provider"), providerErr)
+		redirectToFrontend(c, "oidc_error", "provider_error")
+		return
+	}
+	if state == "" || code == "" || cookieState != state {
+		redirectToFrontend(c, "oidc_error", "invalid_state")
+		return
+	}
+	result, err := service.GetOidcLoginService().CompleteLogin(c.Request.Context(), c.Param("provider"), state, code)
+	if err != nil {
+		log.Logger.Errorf("Social login error: %v", err)
+		reason := "login_failed"
+		switch {
+		case errors.Is(err, service.ErrInvalidOidcState), errors.Is(err, service.ErrUnknownOidcProvider):
+			reason = "invalid_state"
+		case errors.Is(err, service.ErrOidcEmailNotVerified):
+			reason = "email_not_verified"
+		case errors.Is(err, service.ErrClientNotAllowed):
+			reason = "client_not_allowed"
+		case errors.Is(err, utils.ErrAccountUnavailable):
+			reason = accountUnavailableReason(err)
+		}
+		redirectToFrontend(c, "oidc_error", reason)
+		return
+	}
+	if result.MfaChallenge != "" {
+		redirectToFrontend(c, "mfa_challenge", result.MfaChallenge)
+		return
+	}
+	setAuthCookies(c, result.Tokens)
+	redirectToFrontend(c, "", "")
+}
+
+func redirectToFrontend(c *gin.Context, key, value string) {
+	target := "/"
+	if config.Config.OidcConfig != nil && config.Config.OidcConfig.FrontendURL != "" {
+		target = config.Config.OidcConfig.FrontendURL
+	}
+	redirectWithParam(c, target, key, value)
+}
+
+// redirectWithParam redirects to target with key=value added to its query,
+// unless key is empty.
+func redirectWithParam(c *gin.Context, target, key, value string) {
+	if key != "" {
+		u, err := url.Parse(target)
+		if err == nil {
+			query := u.Query()
+			query.Set(key, value)
+			u.RawQuery = query.Encode()
+			target = u.String()
+		}
+	}
+	c.Redirect(http.StatusFound, target)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestPasswordReset sends a password reset code to the user's email.
+// @Summary Request Password Reset
+// @Description This endpoint emails a one-time reset code to the given address if it belongs to an active account.
+// @Tags Password
+// @Accept json
+// @Produce json
+// @Param req body data.PasswordResetRequestReq true "Password reset request"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/password-reset [post]
+func RequestPasswordReset(c *gin.Context) {
+	req := &data.PasswordResetRequestReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	err := service.GetPasswordResetService().RequestReset(c.Request.Context(), req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "If the email is registered, a reset code has been sent"})
+}
+
+// ConfirmPasswordReset sets a new password using the emailed reset code.
+// @Summary Confirm Password Reset
+// @Description This endpoint verifies the one-time reset code and sets the new password.
+// @Tags Password
+// @Accept json
+// @Produce json
+// @Param req body data.PasswordResetConfirmReq true "Password reset confirmation"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse{data=[]string} "Invalid code or a password refused by the password policy, data lists the broken rules"
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/password-reset [put]
+func ConfirmPasswordReset(c *gin.Context) {
+	req := &data.PasswordResetConfirmReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	err := service.GetPasswordResetService().ConfirmReset(c.Request.Context(), req.Email, req.Code, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidResetCode) {
+			c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+			return
+		}
+		if violations, ok := passwordViolations(err); ok {
+			c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error(), Data: violations})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Password reset successful, you can now log in"})
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLoginCode handles requests for a passwordless login.
+// @Summary Request Login Code
+// @Description Emails a one-time login code and link to an active account. The response is the same whether or not the email is registered, and a new code is sent at most once a minute.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param req body data.LoginCodeRequestReq true "Email to send the code to"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/login/code [post]
+func RequestLoginCode(c *gin.Context) {
+	req := &data.LoginCodeRequestReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	err := service.GetPasswordlessLoginService().RequestCode(c.Request.Context(), c.Param("client"), req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "If the email is registered, a login code has been sent"})
+}
+
+// VerifyLoginCode signs in with an emailed login code.
+// @Summary Verify Login Code
+// @Description Exchanges an emailed login code for the same auth and refresh cookies as a password login. A code works once, for 10 minutes and for 5 attempts. Users with two-factor authentication enabled get a challenge to complete at /{client}/login/mfa instead.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param req body data.LoginCodeVerifyReq true "Email and login code"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string} "Login successful, returns auth and refresh tokens in cookies"
+// @Failure 400 {object} data.BaseResponse
+// @Failure 401 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse "The account cannot sign in to this client or is not active, e.g. suspended"
+// @Failure 429 {object} data.BaseResponse "Too many failed attempts for the account or IP, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/login/code [put]
+func VerifyLoginCode(c *gin.Context) {
+	req := &data.LoginCodeVerifyReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	result, err := service.GetPasswordlessLoginService().VerifyCode(c.Request.Context(), c.Param("client"), req.Email, req.Code, c.ClientIP())
+	if err != nil {
+		var throttled *service.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, data.BaseResponse{Code: http.StatusTooManyRequests, ErrMsg: err.Error()})
+		case errors.Is(err, service.ErrInvalidLoginCode):
+			c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: err.Error()})
+		case errors.Is(err, service.ErrClientNotAllowed), errors.Is(err, utils.ErrAccountUnavailable):
+			c.JSON(http.StatusForbidden, data.BaseResponse{Code: http.StatusForbidden, ErrMsg: err.Error()})
+		default:
+			log.Logger.Errorf("Passwordless login error: %v", err)
+			c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		}
+		return
+	}
+	respondLoginResult(c, result)
+}
+
+// LoginByLink handles the login link from the login code email.
+// @Summary Login by Link
+// @Description Signs in with the link from the login code email and redirects to the frontend with the usual auth cookies set, with mfa_challenge to complete at /{client}/login/mfa, or with login_error.
+// @Tags Authentication
+// @Param token query string true "Login token from the email"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 302
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Router /user-ms/v1/{client}/login/link [get]
+func LoginByLink(c *gin.Context) {
+	target := "/"
+	if cfg := config.Config.PasswordlessConfig; cfg != nil && cfg.FrontendURL != "" {
+		target = cfg.FrontendURL
+	}
+	result, err := service.GetPasswordlessLoginService().VerifyLink(c.Request.Context(), c.Param("client"), c.Query("token"), c.ClientIP())
+	if err != nil {
+		reason := "server_error"
+		switch {
+		case errors.Is(err, service.ErrInvalidLoginCode):
+			reason = "invalid_link"
+		case errors.Is(err, service.ErrLoginThrottled):
+			reason = "throttled"
+		case errors.Is(err, service.ErrClientNotAllowed):
+			reason = "client_not_allowed"
+		case errors.Is(err, utils.ErrAccountUnavailable):
+			reason = accountUnavailableReason(err)
+		default:
+			log.Logger.Errorf("Passwordless login error: %v", err)
+		}
+		redirectWithParam(c, target, "login_error", reason)
+		return
+	}
+	if result.MfaChallenge != "" {
+		redirectWithParam(c, target, "mfa_challenge", result.MfaChallenge)
+		return
+	}
+	setAuthCookies(c, result.Tokens)
+	redirectWithParam(c, target, "", "")
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
 	"github.com/gin-gonic/gin"
@@ -10,13 +14,15 @@ import (
 
 // Register handles the user registration process.
 // @Summary Register a new user
-// @Description This endpoint allows a new user to register by providing their details in JSON format.
+// @Description This endpoint allows a new user to register by providing their details in JSON format. Registering a pending email again sends a new code; the new password only replaces the old one when that code is used. A password refused by the password policy is answered with 400 and the broken rules under "violations".
 // @Tags Register
 // @Accept json
 // @Produce json
 // @Param user body data.UserLoginVO true "User registration details"
-// @Param client path string true "Client identifier" Enums(customer, merchant)
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
 // @Success 200
+// @Failure 400 {object} data.BaseResponse
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
 // @Failure 500 {object} data.BaseResponse
 // @Router /user-ms/v1/{client}/users [post]
 func Register(c *gin.Context) {
@@ -27,21 +33,68 @@ func Register(c *gin.Context) {
 	}
 	err := service.GetRegisterService().Register(c.Request.Context(), user.Email, user.Password)
 	if err != nil {
+		if respondActivationThrottled(c, err) {
+			return
+		}
+		if violations, ok := passwordViolations(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "violations": violations})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Registration successful, please check your email to activate your account"})
 }
 
+// ResendActivation sends a new activation code.
+// @Summary Resend Activation Code
+// @Description Emails a new activation code to a pending account, replacing the previous code. The new code activates the account with the password it was first registered with. Codes can be requested once a minute and 5 times a day. Unknown or already active emails get the same response without an email.
+// @Tags Register
+// @Accept json
+// @Produce json
+// @Param req body data.ActivationResendReq true "Email of the pending account"
+// @Success 200
+// @Failure 400 {object} data.BaseResponse
+// @Failure 429 {object} data.BaseResponse "Sent too recently or too often, see the Retry-After header"
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/customer/users/activation/resend [post]
+func ResendActivation(c *gin.Context) {
+	req := &data.ActivationResendReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := service.GetRegisterService().ResendActivation(c.Request.Context(), req.Email)
+	if err != nil {
+		if respondActivationThrottled(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "If the account is pending activation, a new code has been sent"})
+}
+
+func respondActivationThrottled(c *gin.Context, err error) bool {
+	var throttled *service.ActivationThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	return true
+}
+
 // Activate handles the user registration activation process.
 // @Summary Activate a new user
-// @Description This endpoint allows a new user to activate by providing their verification code in JSON format.
+// @Description This endpoint allows a new user to activate by providing their email and verification code in JSON format. A code stops working after 5 wrong attempts.
 // @Tags Register
 // @Accept json
 // @Produce json
 // @Param user body data.UserActivateReq true "User activate request"
-// @Param client path string true "Client identifier" Enums(customer, merchant)
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
 // @Success 200
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
 // @Failure 500 {object} data.BaseResponse
 // @Router /user-ms/v1/{client}/users/activate [put]
 func Validate(c *gin.Context) {
@@ -50,10 +103,39 @@ func Validate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err := service.GetRegisterService().VerifyAndActivate(c.Request.Context(), req.Code)
+	err := service.GetRegisterService().VerifyAndActivate(c.Request.Context(), req.Email, req.Code)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidActivationCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Activation successful, you can now log in"})
 }
+
+// ActivateByLink handles the activation link from the activation email.
+// @Summary Activate a new user by link
+// @Description Activates the account an emailed link was issued for and redirects to the frontend with activation=success or activation=invalid. A link works once and only until a newer code is sent.
+// @Tags Register
+// @Param token query string true "Signed activation token from the email"
+// @Success 302
+// @Failure 429 {object} data.BaseResponse "Rate limited, see the Retry-After header"
+// @Router /user-ms/v1/customer/users/activate [get]
+func ActivateByLink(c *gin.Context) {
+	target := "/"
+	if cfg := config.Config.ActivationConfig; cfg != nil && cfg.FrontendURL != "" {
+		target = cfg.FrontendURL
+	}
+	err := service.GetRegisterService().ActivateByLink(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidActivationCode) {
+			redirectWithParam(c, target, "activation", "invalid")
+			return
+		}
+		redirectWithParam(c, target, "activation", "error")
+		return
+	}
+	redirectWithParam(c, target, "activation", "success")
+}
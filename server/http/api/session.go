@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/middleware"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions lists the devices the current user is signed in on.
+// @Summary List Sessions
+// @Description Lists the active sessions of the current user, one per login, with the device they were started from. The session of the request is marked as current.
+// @Tags Session
+// @Produce json
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=[]data.SessionVO}
+// @Failure 403 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/sessions [get]
+func ListSessions(c *gin.Context) {
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	sessions, err := service.GetSessionService().List(c.Request.Context(), userId.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	current := currentSessionID(c)
+	vos := make([]data.SessionVO, 0, len(sessions))
+	for _, session := range sessions {
+		vos = append(vos, data.SessionVO{
+			ID:         session.ID,
+			Client:     session.Audience,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == current,
+		})
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: vos})
+}
+
+// RevokeSession signs the current user out of one session.
+// @Summary Revoke Session
+// @Description Signs the current user out of a session. Its refresh token stops working and its access tokens are rejected right away. Revoking the current session also clears the auth cookies.
+// @Tags Session
+// @Produce json
+// @Param session_id path int true "Session ID"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string}
+// @Failure 400 {object} data.BaseResponse
+// @Failure 403 {object} data.BaseResponse
+// @Failure 404 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/sessions/{session_id} [delete]
+func RevokeSession(c *gin.Context) {
+	sessionId, err := strconv.ParseInt(c.Param("session_id"), 10, 64)
+	if err != nil || sessionId <= 0 {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: "Invalid session ID"})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	if err := service.GetSessionService().Revoke(c.Request.Context(), userId.(int), sessionId); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	if sessionId == currentSessionID(c) {
+		clearAuthCookies(c)
+	}
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Session revoked"})
+}
+
+func currentSessionID(c *gin.Context) int64 {
+	value, _ := c.Get(middleware.ClaimsKey)
+	if claims, ok := value.(*utils.Claims); ok {
+		return claims.SessionID
+	}
+	return 0
+}
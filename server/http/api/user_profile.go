@@ -2,6 +2,7 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
@@ -20,6 +21,7 @@ import (
 // @Success 200 {object} data.BaseResponse "data is UserProfileVO"
 // @Failure 500 {object} data.BaseResponse
 // @Router /user-ms/v1/customer/users/self [get]
+// @Router /user-ms/v1/merchant/users/self [get]
 func GetUserProfile(c *gin.Context) {
 	userId, exist := c.Get("userID")
 	if !exist || userId.(int) <= 0 {
@@ -87,3 +89,58 @@ func UpdateUserProfile(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: updatedUserProfile})
 }
+
+// Change Password.
+// @Summary Change Password
+// @Description This endpoint allows current login user change his/her password. All other sessions are logged out.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param req body data.ChangePasswordReq true "Current and new password"
+// @Param client path string true "Client identifier" Enums(customer, merchant, admin)
+// @Success 200 {object} data.BaseResponse{data=string} "returns refreshed auth and refresh tokens in cookies"
+// @Failure 400 {object} data.BaseResponse{data=[]string} "Wrong current password or a new password refused by the password policy, data lists the broken rules"
+// @Failure 404 {object} data.BaseResponse
+// @Failure 500 {object} data.BaseResponse
+// @Router /user-ms/v1/{client}/users/self/password [put]
+func ChangePassword(c *gin.Context) {
+	req := &data.ChangePasswordReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+		return
+	}
+	userId, exist := c.Get("userID")
+	if !exist || userId.(int) <= 0 {
+		c.JSON(http.StatusUnauthorized, data.BaseResponse{Code: http.StatusUnauthorized, ErrMsg: "Unauthorized"})
+		return
+	}
+	tokens, err := service.GetUserProfileService().ChangePassword(c.Request.Context(), userId.(int), c.GetString("audience"), req.OldPassword, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, service.ErrIncorrectPassword) || errors.Is(err, service.ErrSamePassword) {
+			c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error()})
+			return
+		}
+		if violations, ok := passwordViolations(err); ok {
+			c.JSON(http.StatusBadRequest, data.BaseResponse{Code: http.StatusBadRequest, ErrMsg: err.Error(), Data: violations})
+			return
+		}
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, data.BaseResponse{Code: http.StatusNotFound, ErrMsg: "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, data.BaseResponse{Code: http.StatusInternalServerError, ErrMsg: err.Error()})
+		return
+	}
+	setAuthCookies(c, tokens)
+	c.JSON(http.StatusOK, data.BaseResponse{Code: http.StatusOK, Data: "Password changed successfully"})
+}
+
+// passwordViolations returns the rules of the password policy a refused
+// password breaks.
+func passwordViolations(err error) ([]string, bool) {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil, false
+	}
+	return policyErr.Violations, true
+}
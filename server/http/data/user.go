@@ -1,18 +1,26 @@
 package data
 
+import "time"
+
 type UserLoginVO struct {
 	ID       int    `json:"id"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,password"`
+	Password string `json:"password" binding:"required"`
 }
 
 type UserActivateReq struct {
-	Code string `json:"code" binding:"required,min=6,max=6"`
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,min=6,max=6"`
+}
+
+type ActivationResendReq struct {
+	Email string `json:"email" binding:"required,email"`
 }
 
 type UserProfileVO struct {
 	ID             int            `json:"id"`
 	Email          string         `json:"email"`
+	Role           string         `json:"role,omitempty"`
 	Name           string         `json:"name"`
 	Avatar         string         `json:"avatar"`
 	DefaultAddress *UserAddressVO `json:"default_address,omitempty"`
@@ -31,3 +39,187 @@ type UserAddressVO struct {
 	ContactPhone string `json:"contact_phone" binding:"required,e164"`
 	IsDefault    bool   `json:"is_default"`
 }
+
+type PasswordResetRequestReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type PasswordResetConfirmReq struct {
+	Email    string `json:"email" binding:"required,email"`
+	Code     string `json:"code" binding:"required,min=6,max=6"`
+	Password string `json:"password" binding:"required"`
+}
+
+type LoginCodeRequestReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type LoginCodeVerifyReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,min=6,max=6"`
+}
+
+type MfaChallengeVO struct {
+	MfaRequired bool   `json:"mfa_required"`
+	Challenge   string `json:"challenge"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type MfaLoginReq struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
+
+type MfaEnrollmentVO struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type MfaCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MfaDisableReq struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type MfaRecoveryCodesVO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type ApiKeyCreateReq struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresInDays of 0 creates a key that does not expire
+	ExpiresInDays int `json:"expires_in_days" binding:"min=0,max=365"`
+}
+
+type ApiKeyUpdateReq struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+}
+
+type ApiKeyVO struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ApiKeyCreatedVO struct {
+	ApiKeyVO
+	// Key is only returned once, at creation
+	Key string `json:"key"`
+}
+
+type SessionVO struct {
+	ID         int64     `json:"id"`
+	Client     string    `json:"client"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Current marks the session the request was made with
+	Current bool `json:"current"`
+}
+
+type EmailChangeReq struct {
+	NewEmail string `json:"new_email" binding:"required,email,max=128"`
+	Password string `json:"password" binding:"required"`
+}
+
+type EmailChangeConfirmReq struct {
+	Code string `json:"code" binding:"required,min=6,max=6"`
+}
+
+type AccountDeletionReq struct {
+	Password string `json:"password" binding:"required"`
+	// Code is a TOTP or recovery code, required with 2FA enabled
+	Code string `json:"code"`
+}
+
+type AccountDeletionVO struct {
+	// DeleteTime is when the account is anonymized unless cancelled
+	DeleteTime time.Time `json:"delete_time"`
+}
+
+type DataExportVO struct {
+	ID          int64      `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	// DownloadToken is only returned when the export is requested
+	DownloadToken string `json:"download_token,omitempty"`
+}
+
+type AdminUserSearchReq struct {
+	// Email matches the users whose email starts with it
+	Email  string `form:"email"`
+	Status string `form:"status" binding:"omitempty,oneof=pending active locked suspended pending_deletion deleted"`
+	// CreatedFrom and CreatedTo are RFC 3339 times, CreatedTo is exclusive
+	CreatedFrom *time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo   *time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page        int        `form:"page" binding:"omitempty,min=1"`
+	PageSize    int        `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type AdminUserVO struct {
+	ID           int        `json:"id"`
+	Email        string     `json:"email"`
+	Name         string     `json:"name"`
+	Role         string     `json:"role"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ActivateTime *time.Time `json:"activate_time,omitempty"`
+}
+
+type AdminUserPageVO struct {
+	Users    []AdminUserVO `json:"users"`
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+}
+
+type StatusChangeVO struct {
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Reason     string `json:"reason"`
+	// ActorID is the staff member who made the change, absent for changes
+	// made by the user or the system
+	ActorID   *int      `json:"actor_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AdminUserDetailVO struct {
+	AdminUserVO
+	Addresses     []*UserAddressVO `json:"addresses"`
+	StatusHistory []StatusChangeVO `json:"status_history"`
+}
+
+type AdminSuspendReq struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+type ImpersonationReq struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+type ImpersonationVO struct {
+	ID     int64  `json:"id"`
+	UserID int    `json:"user_id"`
+	Client string `json:"client"`
+	// AccessToken is used as the auth-token cookie of the client, it is
+	// only returned here
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
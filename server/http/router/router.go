@@ -1,15 +1,19 @@
 package router
 
 import (
-	"regexp"
+	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
-	"github.com/go-playground/validator/v10"
 
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/middleware"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
 	_ "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/docs"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/api"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
 	swaggerFiles "github.com/swaggo/files"
 	gs "github.com/swaggo/gin-swagger"
 )
@@ -20,13 +24,10 @@ const (
 
 func NewRouter() *gin.Engine {
 	r := gin.Default()
-	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		err := v.RegisterValidation("password", passwordStrengthValidator)
-		if err != nil {
-			panic(err)
-		}
+	if err := r.SetTrustedProxies(config.Config.HttpConfig.TrustedProxies); err != nil {
+		log.Logger.Fatalf("Invalid trusted proxies: %v", err)
 	}
-
+	r.Use(clientInfo(), impersonationAudit())
 	basicGroup := r.Group(serviceURIPrefix)
 	{
 		basicGroup.GET("/swagger/*any", gs.WrapHandler(
@@ -38,41 +39,152 @@ func NewRouter() *gin.Engine {
 				"message": "pong",
 			})
 		})
+		basicGroup.GET("/.well-known/jwks.json", api.GetJWKS)
 	}
 
-	v1UnAuthed := basicGroup.Group("")
+	// Endpoints shared by both clients. The token audience must match the
+	// :client segment, so customer tokens are rejected by the merchant portal
+	// and vice versa.
+	clientUnAuthed := basicGroup.Group("/:client", middleware.ValidateClient())
 	{
-		v1UnAuthed.POST("/customer/login", api.UserLogin)
-		v1UnAuthed.POST("/customer/users", api.Register)
-		v1UnAuthed.PUT("/customer/users/activate", api.Validate)
+		clientUnAuthed.POST("/login", rateLimit("login"), api.UserLogin)
+		clientUnAuthed.POST("/login/mfa", rateLimit("login_mfa"), api.VerifyMfaLogin)
+		clientUnAuthed.POST("/login/code", rateLimit("login_code_request"), api.RequestLoginCode)
+		clientUnAuthed.PUT("/login/code", rateLimit("login_code"), api.VerifyLoginCode)
+		clientUnAuthed.GET("/login/link", rateLimit("login_link"), api.LoginByLink)
+		clientUnAuthed.POST("/token/refresh", api.RefreshToken)
+		// Logout works on the refresh cookie alone, after the access token expired
+		clientUnAuthed.POST("/logout", api.UserLogout)
+		clientUnAuthed.POST("/users/password-reset", rateLimit("password_reset"), api.RequestPasswordReset)
+		clientUnAuthed.PUT("/users/password-reset", rateLimit("password_reset_confirm"), api.ConfirmPasswordReset)
+		clientUnAuthed.GET("/users/email/revert", rateLimit("email_change_revert"), api.RevertEmailChange)
+		clientUnAuthed.GET("/users/data-export/download", rateLimit("data_export_download"), api.DownloadDataExport)
+	}
+	// Account management is refused to API keys and impersonation tokens.
+	clientAuthed := basicGroup.Group("/:client", middleware.ValidateClient(), middleware.AuthMiddleware())
+	{
+		clientAuthed.PUT("/users/self/password", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.ChangePassword)
+		clientAuthed.POST("/users/self/email", middleware.DenyApiKeys(), middleware.DenyImpersonation(), rateLimit("email_change"), api.RequestEmailChange)
+		clientAuthed.PUT("/users/self/email", middleware.DenyApiKeys(), middleware.DenyImpersonation(), rateLimit("email_change_confirm"), api.ConfirmEmailChange)
+		clientAuthed.POST("/users/self/mfa", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.EnrollMfa)
+		clientAuthed.PUT("/users/self/mfa", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.ConfirmMfa)
+		clientAuthed.DELETE("/users/self/mfa", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.DisableMfa)
+		clientAuthed.POST("/users/self/mfa/recovery-codes", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.RegenerateRecoveryCodes)
+		clientAuthed.GET("/users/self/api-keys", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.ListApiKeys)
+		clientAuthed.POST("/users/self/api-keys", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.CreateApiKey)
+		clientAuthed.PUT("/users/self/api-keys/:key_id", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.UpdateApiKey)
+		clientAuthed.DELETE("/users/self/api-keys/:key_id", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.DeleteApiKey)
+		clientAuthed.GET("/users/self/sessions", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.ListSessions)
+		clientAuthed.DELETE("/users/self/sessions/:session_id", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.RevokeSession)
+		clientAuthed.POST("/users/self/data-export", middleware.DenyApiKeys(), middleware.DenyImpersonation(), rateLimit("data_export"), api.RequestDataExport)
+		clientAuthed.GET("/users/self/data-export", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.GetDataExport)
+	}
 
-		v1UnAuthed.POST("/merchant/login", api.UserLogin)
+	v1UnAuthed := basicGroup.Group("")
+	{
+		v1UnAuthed.POST("/customer/users", rateLimit("register"), api.Register)
+		v1UnAuthed.PUT("/customer/users/activate", rateLimit("activate"), api.Validate)
+		v1UnAuthed.GET("/customer/users/activate", rateLimit("activate_link"), api.ActivateByLink)
+		v1UnAuthed.POST("/customer/users/activation/resend", rateLimit("activation_resend"), api.ResendActivation)
+		v1UnAuthed.GET("/customer/users/deletion/cancel", rateLimit("account_deletion_cancel"), api.CancelAccountDeletion)
+		v1UnAuthed.GET("/customer/oidc/:provider/authorize", api.OidcAuthorize)
+		v1UnAuthed.GET("/customer/oidc/:provider/callback", api.OidcCallback)
+		v1UnAuthed.POST("/customer/oidc/:provider/callback", api.OidcCallback)
 	}
 	v1Authed := basicGroup.Group("")
 	{
-		v1Authed.Use(middleware.AuthMiddleware())
-		v1Authed.POST("/customer/logout", api.UserLogout)
-		v1Authed.GET("/customer/users/self", api.GetUserProfile)
-		v1Authed.PUT("/customer/users/self", api.UpdateUserProfile)
-		v1Authed.GET("/customer/users/self/addresses", api.ListUserAddresses)
-		v1Authed.POST("/customer/users/self/addresses", api.AddUserAddress)
-		v1Authed.PUT("/customer/users/self/addresses/:address_id", api.UpdateUserAddress)
-		v1Authed.DELETE("/customer/users/self/addresses/:address_id", api.DeleteUserAddress)
-
-		v1Authed.POST("/merchant/logout", api.UserLogout)
+		v1Authed.Use(middleware.AuthMiddleware(utils.AudienceCustomer))
+		v1Authed.GET("/customer/users/self", middleware.RequirePermission(utils.PermProfileRead), api.GetUserProfile)
+		v1Authed.PUT("/customer/users/self", middleware.RequirePermission(utils.PermProfileWrite), api.UpdateUserProfile)
+		v1Authed.DELETE("/customer/users/self", middleware.DenyApiKeys(), middleware.DenyImpersonation(), api.DeleteAccount)
+		v1Authed.GET("/customer/users/self/addresses", middleware.RequirePermission(utils.PermAddressRead), api.ListUserAddresses)
+		v1Authed.POST("/customer/users/self/addresses", middleware.RequirePermission(utils.PermAddressWrite), api.AddUserAddress)
+		v1Authed.PUT("/customer/users/self/addresses/:address_id", middleware.RequirePermission(utils.PermAddressWrite), api.UpdateUserAddress)
+		v1Authed.DELETE("/customer/users/self/addresses/:address_id", middleware.RequirePermission(utils.PermAddressWrite), api.DeleteUserAddress)
+	}
+	merchantAuthed := basicGroup.Group("")
+	{
+		merchantAuthed.Use(middleware.AuthMiddleware(utils.AudienceMerchant), middleware.RequireRole(model.UserRoleMerchant))
+		merchantAuthed.GET("/merchant/users/self", middleware.RequirePermission(utils.PermProfileRead), api.GetUserProfile)
+	}
+	// Staff endpoints, for admin tokens and API keys granted users:admin.
+	// Accounts are managed under /admin/accounts, a :user_id under
+	// /admin/users would shadow the /:client/users/self routes of admins.
+	adminAuthed := basicGroup.Group("/admin")
+	{
+		adminAuthed.Use(middleware.AuthMiddleware(utils.AudienceAdmin), middleware.RequireRole(model.UserRoleAdmin), middleware.RequirePermission(utils.PermUsersAdmin))
+		adminAuthed.GET("/accounts", api.SearchUsers)
+		adminAuthed.GET("/accounts/:user_id", api.GetUser)
+		adminAuthed.PUT("/accounts/:user_id/suspension", api.SuspendUser)
+		adminAuthed.DELETE("/accounts/:user_id/suspension", api.UnsuspendUser)
+		adminAuthed.POST("/accounts/:user_id/password-reset", api.ForcePasswordReset)
+		adminAuthed.POST("/accounts/:user_id/activation", api.ActivateUser)
+		adminAuthed.POST("/accounts/:user_id/activation/resend", api.ResendUserActivation)
+		adminAuthed.DELETE("/accounts/:user_id/lockout", api.UnlockUser)
+		adminAuthed.POST("/accounts/:user_id/impersonation", middleware.DenyApiKeys(), api.ImpersonateUser)
 	}
 	return r
 }
 
-// Custom password validation rules
-var passwordStrengthValidator validator.Func = func(fl validator.FieldLevel) bool {
-	password := fl.Field().String()
-	// atleast one letter
-	hasLetter := regexp.MustCompile(`[A-Za-z]`).MatchString(password)
-	// atleast one digit
-	hasDigit := regexp.MustCompile(`\d`).MatchString(password)
-	// min length 8
-	isValidLength := len(password) >= 8
+var rateLimitKeys = map[string]middleware.RateLimitKeyFunc{
+	"ip":    middleware.KeyByIP,
+	"email": middleware.KeyByJSONField("email"),
+	"route": middleware.KeyByRoute,
+}
+
+// rateLimit applies the limits configured for the named route under
+// rate_limit.routes. Routes without configured limits are not throttled.
+func rateLimit(name string) gin.HandlerFunc {
+	var rules []config.RateLimitRule
+	if config.Config.RateLimitConfig != nil {
+		rules = config.Config.RateLimitConfig.Routes[name]
+	}
+	limits := make([]middleware.RateLimit, 0, len(rules))
+	for _, rule := range rules {
+		keyFunc, ok := rateLimitKeys[rule.Key]
+		if !ok || rule.Requests <= 0 || rule.PeriodSeconds <= 0 {
+			panic(fmt.Sprintf("invalid rate limit for route %s: %+v", name, rule))
+		}
+		limits = append(limits, middleware.RateLimit{
+			Name:     fmt.Sprintf("%s:%d", name, rule.PeriodSeconds),
+			Key:      keyFunc,
+			Requests: rule.Requests,
+			Period:   time.Duration(rule.PeriodSeconds) * time.Second,
+			Burst:    rule.Burst,
+		})
+	}
+	if len(limits) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return middleware.RateLimitMiddleware(service.GetRateLimitStore(), limits...)
+}
 
-	return hasLetter && hasDigit && isValidLength
+// clientInfo passes the device of the request to the services so logins
+// can record it on the session they start.
+func clientInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := service.WithClientInfo(c.Request.Context(), service.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// impersonationAudit records every request made with an impersonation token,
+// once it is handled so the response status is known.
+func impersonationAudit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		value, _ := c.Get(middleware.ClaimsKey)
+		claims, ok := value.(*utils.Claims)
+		if !ok || !claims.IsImpersonated() {
+			return
+		}
+		err := service.GetImpersonationService().RecordRequest(c.Request.Context(), claims, c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+		if err != nil {
+			log.Logger.Errorf("Failed to audit impersonated request of user %d by admin %d: %v", claims.ID, claims.ImpersonatorID, err)
+		}
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"os/signal"
 	"runtime/debug"
 	"syscall"
+	"time"
 
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
@@ -14,6 +15,7 @@ import (
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/service"
 )
 
 var (
@@ -25,10 +27,12 @@ func main() {
 	config.Init()
 	log.InitLogger()
 	log.Logger.Info("Logger initialized.")
-	utils.InitJwtSecret()
-	log.Logger.Info("JWT secret initialized.")
+	initJwt()
 	repository.Init()
 	log.Logger.Info("Database initialized.")
+	utils.RegisterClaimsChecker(service.GetLoginService().CheckClaims)
+	utils.RegisterApiKeyValidator(service.GetApiKeyService().Validate)
+	go service.StartExpiryPruner(service.GetTokenRevocationStore(), service.GetTokenService(), service.GetMfaService(), service.GetOidcLoginService(), service.GetPasswordlessLoginService(), service.GetSessionService(), service.GetEmailChangeService(), service.GetAccountDeletionService(), service.GetDataExportService(), service.GetLoginGuard(), service.GetRateLimitStore())
 	mq.InitKafka()
 	log.Logger.Info("Kafka initialized.")
 	go grpc.Init(sigCh)
@@ -39,3 +43,26 @@ func main() {
 	debug.PrintStack()
 	log.Logger.Infof("Received signal: %v, shutting down", sig)
 }
+
+func initJwt() {
+	authConfig := config.Config.AuthConfig
+	utils.SetAccessTokenTTL(time.Duration(authConfig.AccessTokenTTLMinutes) * time.Minute)
+	if authConfig.SigningKeyFile == "" {
+		utils.InitJwtSecret()
+		log.Logger.Info("JWT secret initialized.")
+		return
+	}
+	if err := utils.InitJwtKeys(authConfig.SigningKeyFile, authConfig.PreviousSigningKeyFiles...); err != nil {
+		panic(err)
+	}
+	// Keep accepting HS256 tokens issued before the switch until the cutoff
+	if authConfig.LegacySecretUntil != "" {
+		until, err := time.Parse(time.RFC3339, authConfig.LegacySecretUntil)
+		if err != nil {
+			panic(fmt.Sprintf("invalid auth.legacy_secret_until: %v", err))
+		}
+		utils.InitJwtSecret()
+		utils.AcceptLegacyTokensUntil(until)
+	}
+	log.Logger.Info("JWT signing keys initialized.")
+}
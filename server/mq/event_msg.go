@@ -19,3 +19,72 @@ func (u *UserActivatedEvent) ToBytes() []byte {
 	}
 	return ret
 }
+
+type PasswordResetEvent struct {
+	UserID    int   `json:"user_id"`
+	ResetTime int64 `json:"reset_time"`
+}
+
+func (p *PasswordResetEvent) ToBytes() []byte {
+	ret, err := json.Marshal(p)
+	if err != nil {
+		log.Logger.Errorf("Failed to marshal PasswordResetEvent: %v", err)
+		return nil
+	}
+	return ret
+}
+
+// UserEmailChangedEvent is sent when a user confirms a new login email, and
+// again with Reverted set when the owner of the old address undoes it.
+type UserEmailChangedEvent struct {
+	UserID     int    `json:"user_id"`
+	OldEmail   string `json:"old_email"`
+	NewEmail   string `json:"new_email"`
+	Reverted   bool   `json:"reverted"`
+	ChangeTime int64  `json:"change_time"`
+}
+
+func (u *UserEmailChangedEvent) ToBytes() []byte {
+	ret, err := json.Marshal(u)
+	if err != nil {
+		log.Logger.Errorf("Failed to marshal UserEmailChangedEvent: %v", err)
+		return nil
+	}
+	return ret
+}
+
+// UserDeletedEvent is sent when an account is anonymized at the end of its
+// deletion grace period. Consumers should scrub what they keep about UserID.
+type UserDeletedEvent struct {
+	UserID     int   `json:"user_id"`
+	DeleteTime int64 `json:"delete_time"`
+}
+
+func (u *UserDeletedEvent) ToBytes() []byte {
+	ret, err := json.Marshal(u)
+	if err != nil {
+		log.Logger.Errorf("Failed to marshal UserDeletedEvent: %v", err)
+		return nil
+	}
+	return ret
+}
+
+// UserStatusChangedEvent is sent for every transition of the account
+// lifecycle. Statuses are given by name, e.g. "active" or "suspended".
+type UserStatusChangedEvent struct {
+	UserID     int    `json:"user_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Reason     string `json:"reason"`
+	ActorID    *int   `json:"actor_id,omitempty"`
+	ChangeTime int64  `json:"change_time"`
+}
+
+func (u *UserStatusChangedEvent) ToBytes() []byte {
+	ret, err := json.Marshal(u)
+	if err != nil {
+		log.Logger.Errorf("Failed to marshal UserStatusChangedEvent: %v", err)
+		return nil
+	}
+	return ret
+}
@@ -0,0 +1,133 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	proxy "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OidcProvider is an autogenerated mock type for the OidcProvider type
+type OidcProvider struct {
+	mock.Mock
+}
+
+// AuthCodeURL provides a mock function with given fields: ctx, redirectURI, state, nonce, codeChallenge
+func (_m *OidcProvider) AuthCodeURL(ctx context.Context, redirectURI string, state string, nonce string, codeChallenge string) (string, error) {
+	ret := _m.Called(ctx, redirectURI, state, nonce, codeChallenge)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AuthCodeURL")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) (string, error)); ok {
+		return rf(ctx, redirectURI, state, nonce, codeChallenge)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) string); ok {
+		r0 = rf(ctx, redirectURI, state, nonce, codeChallenge)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) error); ok {
+		r1 = rf(ctx, redirectURI, state, nonce, codeChallenge)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Exchange provides a mock function with given fields: ctx, redirectURI, code, codeVerifier
+func (_m *OidcProvider) Exchange(ctx context.Context, redirectURI string, code string, codeVerifier string) (string, error) {
+	ret := _m.Called(ctx, redirectURI, code, codeVerifier)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Exchange")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (string, error)); ok {
+		return rf(ctx, redirectURI, code, codeVerifier)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) string); ok {
+		r0 = rf(ctx, redirectURI, code, codeVerifier)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, redirectURI, code, codeVerifier)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UsesFormPost provides a mock function with no fields
+func (_m *OidcProvider) UsesFormPost() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for UsesFormPost")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// VerifyIDToken provides a mock function with given fields: ctx, rawIDToken, nonce
+func (_m *OidcProvider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*proxy.OidcIdentity, error) {
+	ret := _m.Called(ctx, rawIDToken, nonce)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyIDToken")
+	}
+
+	var r0 *proxy.OidcIdentity
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*proxy.OidcIdentity, error)); ok {
+		return rf(ctx, rawIDToken, nonce)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *proxy.OidcIdentity); ok {
+		r0 = rf(ctx, rawIDToken, nonce)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*proxy.OidcIdentity)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, rawIDToken, nonce)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOidcProvider creates a new instance of OidcProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOidcProvider(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OidcProvider {
+	mock := &OidcProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
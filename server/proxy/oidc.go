@@ -0,0 +1,245 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OidcIdentity is the identity asserted by a verified ID token.
+type OidcIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OidcProvider is an OpenID Connect provider used for social login with the
+// authorization code flow and PKCE.
+type OidcProvider interface {
+	AuthCodeURL(ctx context.Context, redirectURI, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, redirectURI, code, codeVerifier string) (string, error)
+	VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OidcIdentity, error)
+	UsesFormPost() bool
+}
+
+// OidcClient talks to any OIDC compliant issuer. Endpoints and signing keys
+// are discovered from the issuer's /.well-known/openid-configuration.
+type OidcClient struct {
+	config     *config.OidcProviderConfig
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      *utils.JWKSVerifier
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type idTokenClaims struct {
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified flexibleBool `json:"email_verified"`
+	Name          string       `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// flexibleBool accepts both true and "true"; some providers, Apple among
+// them, send email_verified as a string.
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	*b = flexibleBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+var (
+	oidcProviders     map[string]OidcProvider
+	oidcProvidersOnce sync.Once
+)
+
+// GetOidcProviders returns a client for every provider in the config, keyed
+// by provider name.
+func GetOidcProviders() map[string]OidcProvider {
+	oidcProvidersOnce.Do(func() {
+		oidcProviders = map[string]OidcProvider{}
+		if config.Config.OidcConfig == nil {
+			return
+		}
+		for name, provider := range config.Config.OidcConfig.Providers {
+			oidcProviders[name] = NewOidcClient(provider)
+		}
+	})
+	return oidcProviders
+}
+
+func NewOidcClient(providerConfig *config.OidcProviderConfig) *OidcClient {
+	return &OidcClient{
+		config:     providerConfig,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *OidcClient) UsesFormPost() bool {
+	return c.config.ResponseMode == "form_post"
+}
+
+func (c *OidcClient) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, codeChallenge string) (string, error) {
+	discovery, _, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := c.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email"}
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.config.ClientID)
+	params.Set("redirect_uri", redirectURI)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+	if c.config.ResponseMode != "" {
+		params.Set("response_mode", c.config.ResponseMode)
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the raw ID token.
+func (c *OidcClient) Exchange(ctx context.Context, redirectURI, code, codeVerifier string) (string, error) {
+	discovery, _, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("client_id", c.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if c.config.ClientSecret != "" {
+		form.Set("client_secret", c.config.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	var token oidcTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return token.IDToken, nil
+}
+
+// VerifyIDToken checks the signature against the provider's JWKS as well as
+// issuer, audience, expiry and nonce.
+func (c *OidcClient) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OidcIdentity, error) {
+	discovery, keys, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := &idTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return keys.Key(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(c.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id token: missing subject")
+	}
+	return &OidcIdentity{
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// discover loads the provider metadata once; failures are retried on the
+// next call.
+func (c *OidcClient) discover(ctx context.Context) (*oidcDiscovery, *utils.JWKSVerifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.discovery != nil {
+		return c.discovery, c.keys, nil
+	}
+	issuer := strings.TrimSuffix(c.config.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch oidc discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to fetch oidc discovery document: status %d", resp.StatusCode)
+	}
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode oidc discovery document: %w", err)
+	}
+	if discovery.Issuer != issuer && discovery.Issuer != c.config.Issuer {
+		return nil, nil, fmt.Errorf("oidc discovery issuer %q does not match %q", discovery.Issuer, c.config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, nil, errors.New("oidc discovery document is missing endpoints")
+	}
+	c.discovery = &discovery
+	c.keys = utils.NewJWKSVerifier(discovery.JwksURI, time.Hour)
+	return c.discovery, c.keys, nil
+}
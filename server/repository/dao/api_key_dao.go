@@ -0,0 +1,138 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type ApiKeyDao interface {
+	Create(ctx context.Context, apiKey *model.ApiKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*model.ApiKey, error)
+	GetByUserIdAndId(ctx context.Context, userId int, id int64) (*model.ApiKey, error)
+	ListByUserId(ctx context.Context, userId int) ([]*model.ApiKey, error)
+	CountByUserId(ctx context.Context, userId int) (int64, error)
+	Update(ctx context.Context, apiKey *model.ApiKey) error
+	UpdateLastUsed(ctx context.Context, id int64, at time.Time) error
+	Delete(ctx context.Context, userId int, id int64) (bool, error)
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type ApiKeyDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	apiKeyOnce sync.Once
+	apiKeyDao  *ApiKeyDaoImpl
+)
+
+func GetApiKeyDao() *ApiKeyDaoImpl {
+	apiKeyOnce.Do(func() {
+		if apiKeyDao == nil {
+			apiKeyDao = &ApiKeyDaoImpl{db: repository.DB}
+		}
+	})
+	return apiKeyDao
+}
+
+func (dao *ApiKeyDaoImpl) Create(ctx context.Context, apiKey *model.ApiKey) error {
+	ret := dao.db.WithContext(ctx).Create(apiKey)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create api key: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *ApiKeyDaoImpl) GetByPrefix(ctx context.Context, prefix string) (*model.ApiKey, error) {
+	var apiKey model.ApiKey
+	ret := dao.db.WithContext(ctx).Where("prefix = ?", prefix).First(&apiKey)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get api key: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &apiKey, nil
+}
+
+func (dao *ApiKeyDaoImpl) GetByUserIdAndId(ctx context.Context, userId int, id int64) (*model.ApiKey, error) {
+	var apiKey model.ApiKey
+	ret := dao.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userId).First(&apiKey)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get api key: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &apiKey, nil
+}
+
+func (dao *ApiKeyDaoImpl) ListByUserId(ctx context.Context, userId int) ([]*model.ApiKey, error) {
+	var apiKeys []*model.ApiKey
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id").Find(&apiKeys)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list api keys: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return apiKeys, nil
+}
+
+func (dao *ApiKeyDaoImpl) CountByUserId(ctx context.Context, userId int) (int64, error) {
+	var count int64
+	ret := dao.db.WithContext(ctx).Model(&model.ApiKey{}).Where("user_id = ?", userId).Count(&count)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to count api keys: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return count, nil
+}
+
+// Update saves the name and scopes of the key.
+func (dao *ApiKeyDaoImpl) Update(ctx context.Context, apiKey *model.ApiKey) error {
+	ret := dao.db.WithContext(ctx).Model(&model.ApiKey{}).Where("id = ?", apiKey.ID).
+		Select("name", "scopes", "updated_at").Updates(&model.ApiKey{Name: apiKey.Name, Scopes: apiKey.Scopes, UpdatedAt: time.Now()})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to update api key: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *ApiKeyDaoImpl) UpdateLastUsed(ctx context.Context, id int64, at time.Time) error {
+	ret := dao.db.WithContext(ctx).Model(&model.ApiKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to update api key last use: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// Delete revokes the key of the user. It returns false if the user has no
+// such key.
+func (dao *ApiKeyDaoImpl) Delete(ctx context.Context, userId int, id int64) (bool, error) {
+	ret := dao.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userId).Delete(&model.ApiKey{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete api key: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *ApiKeyDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.ApiKey{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete api keys: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"context"
+	"sync"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type ImpersonationDao interface {
+	Create(ctx context.Context, impersonation *model.Impersonation) error
+	CreateRequest(ctx context.Context, request *model.ImpersonationRequest) error
+	ListByUserId(ctx context.Context, userId int) ([]*model.Impersonation, error)
+	ListRequestsByUserId(ctx context.Context, userId int) ([]*model.ImpersonationRequest, error)
+}
+
+type ImpersonationDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	impersonationOnce sync.Once
+	impersonationDao  *ImpersonationDaoImpl
+)
+
+func GetImpersonationDao() *ImpersonationDaoImpl {
+	impersonationOnce.Do(func() {
+		if impersonationDao == nil {
+			impersonationDao = &ImpersonationDaoImpl{db: repository.DB}
+		}
+	})
+	return impersonationDao
+}
+
+func (dao *ImpersonationDaoImpl) Create(ctx context.Context, impersonation *model.Impersonation) error {
+	ret := dao.db.WithContext(ctx).Create(impersonation)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create impersonation: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *ImpersonationDaoImpl) CreateRequest(ctx context.Context, request *model.ImpersonationRequest) error {
+	ret := dao.db.WithContext(ctx).Create(request)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to record impersonated request: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// ListByUserId returns the impersonations of a user, oldest first.
+func (dao *ImpersonationDaoImpl) ListByUserId(ctx context.Context, userId int) ([]*model.Impersonation, error) {
+	var impersonations []*model.Impersonation
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id asc").Find(&impersonations)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list impersonations: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return impersonations, nil
+}
+
+// ListRequestsByUserId returns the requests made while impersonating a user,
+// oldest first.
+func (dao *ImpersonationDaoImpl) ListRequestsByUserId(ctx context.Context, userId int) ([]*model.ImpersonationRequest, error) {
+	var requests []*model.ImpersonationRequest
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id asc").Find(&requests)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list impersonated requests: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return requests, nil
+}
@@ -0,0 +1,111 @@
+package dao
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginFailureDao interface {
+	GetBySubjects(ctx context.Context, subjects []string) ([]*model.LoginFailure, error)
+	RecordFailure(ctx context.Context, subject string, now, windowStart time.Time) (*model.LoginFailure, error)
+	Block(ctx context.Context, subject string, until time.Time, locked bool) error
+	DeleteBySubject(ctx context.Context, subject string) error
+	DeleteExpired(ctx context.Context, lastFailedBefore, now time.Time) (int64, error)
+}
+
+type LoginFailureDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	loginFailureOnce sync.Once
+	loginFailureDao  *LoginFailureDaoImpl
+)
+
+func GetLoginFailureDao() *LoginFailureDaoImpl {
+	loginFailureOnce.Do(func() {
+		if loginFailureDao == nil {
+			loginFailureDao = &LoginFailureDaoImpl{db: repository.DB}
+		}
+	})
+	return loginFailureDao
+}
+
+func (dao *LoginFailureDaoImpl) GetBySubjects(ctx context.Context, subjects []string) ([]*model.LoginFailure, error) {
+	var failures []*model.LoginFailure
+	ret := dao.db.WithContext(ctx).Where("subject IN ?", subjects).Find(&failures)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to get login failures: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return failures, nil
+}
+
+// RecordFailure atomically increments the failure count of the subject and
+// returns the updated row. Counts older than windowStart start over.
+func (dao *LoginFailureDaoImpl) RecordFailure(ctx context.Context, subject string, now, windowStart time.Time) (*model.LoginFailure, error) {
+	ret := dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "subject"}},
+		// failures is assigned first, while last_failed_at still holds the
+		// previous failure
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("CASE WHEN last_failed_at < ? THEN 1 ELSE failures + 1 END", windowStart)},
+			{Column: clause.Column{Name: "locked"}, Value: gorm.Expr("CASE WHEN blocked_until < ? THEN ? ELSE locked END", now, false)},
+			{Column: clause.Column{Name: "last_failed_at"}, Value: now},
+		},
+	}).Create(&model.LoginFailure{
+		Subject:      subject,
+		Failures:     1,
+		LastFailedAt: now,
+		BlockedUntil: now,
+		CreatedAt:    now,
+	})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to record login failure: %v", ret.Error)
+		return nil, ret.Error
+	}
+	var failure model.LoginFailure
+	ret = dao.db.WithContext(ctx).Where("subject = ?", subject).First(&failure)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to get login failure: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &failure, nil
+}
+
+func (dao *LoginFailureDaoImpl) Block(ctx context.Context, subject string, until time.Time, locked bool) error {
+	ret := dao.db.WithContext(ctx).Model(&model.LoginFailure{}).Where("subject = ?", subject).
+		Updates(map[string]interface{}{"blocked_until": until, "locked": locked})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to block login subject: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *LoginFailureDaoImpl) DeleteBySubject(ctx context.Context, subject string) error {
+	ret := dao.db.WithContext(ctx).Where("subject = ?", subject).Delete(&model.LoginFailure{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete login failures: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// DeleteExpired removes counters that fell out of the failure window and are
+// no longer blocking.
+func (dao *LoginFailureDaoImpl) DeleteExpired(ctx context.Context, lastFailedBefore, now time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("last_failed_at < ? AND blocked_until < ?", lastFailedBefore, now).Delete(&model.LoginFailure{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired login failures: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
@@ -0,0 +1,102 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type MfaChallengeDao interface {
+	Create(ctx context.Context, challenge *model.MfaChallenge) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.MfaChallenge, error)
+	IncrementAttempts(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id int64) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type MfaChallengeDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	mfaChallengeOnce sync.Once
+	mfaChallengeDao  *MfaChallengeDaoImpl
+)
+
+func GetMfaChallengeDao() *MfaChallengeDaoImpl {
+	mfaChallengeOnce.Do(func() {
+		if mfaChallengeDao == nil {
+			mfaChallengeDao = &MfaChallengeDaoImpl{db: repository.DB}
+		}
+	})
+	return mfaChallengeDao
+}
+
+func (dao *MfaChallengeDaoImpl) Create(ctx context.Context, challenge *model.MfaChallenge) error {
+	ret := dao.db.WithContext(ctx).Create(challenge)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create mfa challenge: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *MfaChallengeDaoImpl) GetByTokenHash(ctx context.Context, tokenHash string) (*model.MfaChallenge, error) {
+	var challenge model.MfaChallenge
+	ret := dao.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&challenge)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get mfa challenge: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &challenge, nil
+}
+
+func (dao *MfaChallengeDaoImpl) IncrementAttempts(ctx context.Context, id int64) error {
+	ret := dao.db.WithContext(ctx).Model(&model.MfaChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to record mfa challenge attempt: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// Delete consumes the challenge. It returns false if it was already consumed,
+// so each challenge yields tokens at most once.
+func (dao *MfaChallengeDaoImpl) Delete(ctx context.Context, id int64) (bool, error) {
+	ret := dao.db.WithContext(ctx).Where("id = ?", id).Delete(&model.MfaChallenge{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete mfa challenge: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *MfaChallengeDaoImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.MfaChallenge{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired mfa challenges: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
+
+func (dao *MfaChallengeDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.MfaChallenge{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete mfa challenges: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// ImpersonationDao is an autogenerated mock type for the ImpersonationDao type
+type ImpersonationDao struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, impersonation
+func (_m *ImpersonationDao) Create(ctx context.Context, impersonation *model.Impersonation) error {
+	ret := _m.Called(ctx, impersonation)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Impersonation) error); ok {
+		r0 = rf(ctx, impersonation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateRequest provides a mock function with given fields: ctx, request
+func (_m *ImpersonationDao) CreateRequest(ctx context.Context, request *model.ImpersonationRequest) error {
+	ret := _m.Called(ctx, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateRequest")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.ImpersonationRequest) error); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListByUserId provides a mock function with given fields: ctx, userId
+func (_m *ImpersonationDao) ListByUserId(ctx context.Context, userId int) ([]*model.Impersonation, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByUserId")
+	}
+
+	var r0 []*model.Impersonation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*model.Impersonation, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*model.Impersonation); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Impersonation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRequestsByUserId provides a mock function with given fields: ctx, userId
+func (_m *ImpersonationDao) ListRequestsByUserId(ctx context.Context, userId int) ([]*model.ImpersonationRequest, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRequestsByUserId")
+	}
+
+	var r0 []*model.ImpersonationRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*model.ImpersonationRequest, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*model.ImpersonationRequest); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.ImpersonationRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewImpersonationDao creates a new instance of ImpersonationDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewImpersonationDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ImpersonationDao {
+	mock := &ImpersonationDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
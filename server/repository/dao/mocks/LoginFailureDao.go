@@ -0,0 +1,156 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+
+	time "time"
+)
+
+// LoginFailureDao is an autogenerated mock type for the LoginFailureDao type
+type LoginFailureDao struct {
+	mock.Mock
+}
+
+// Block provides a mock function with given fields: ctx, subject, until, locked
+func (_m *LoginFailureDao) Block(ctx context.Context, subject string, until time.Time, locked bool) error {
+	ret := _m.Called(ctx, subject, until, locked)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Block")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, bool) error); ok {
+		r0 = rf(ctx, subject, until, locked)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteBySubject provides a mock function with given fields: ctx, subject
+func (_m *LoginFailureDao) DeleteBySubject(ctx context.Context, subject string) error {
+	ret := _m.Called(ctx, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBySubject")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, subject)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteExpired provides a mock function with given fields: ctx, lastFailedBefore, now
+func (_m *LoginFailureDao) DeleteExpired(ctx context.Context, lastFailedBefore time.Time, now time.Time) (int64, error) {
+	ret := _m.Called(ctx, lastFailedBefore, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpired")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time) (int64, error)); ok {
+		return rf(ctx, lastFailedBefore, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Time) int64); ok {
+		r0 = rf(ctx, lastFailedBefore, now)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, lastFailedBefore, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBySubjects provides a mock function with given fields: ctx, subjects
+func (_m *LoginFailureDao) GetBySubjects(ctx context.Context, subjects []string) ([]*model.LoginFailure, error) {
+	ret := _m.Called(ctx, subjects)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBySubjects")
+	}
+
+	var r0 []*model.LoginFailure
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]*model.LoginFailure, error)); ok {
+		return rf(ctx, subjects)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []*model.LoginFailure); ok {
+		r0 = rf(ctx, subjects)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.LoginFailure)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, subjects)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordFailure provides a mock function with given fields: ctx, subject, now, windowStart
+func (_m *LoginFailureDao) RecordFailure(ctx context.Context, subject string, now time.Time, windowStart time.Time) (*model.LoginFailure, error) {
+	ret := _m.Called(ctx, subject, now, windowStart)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordFailure")
+	}
+
+	var r0 *model.LoginFailure
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) (*model.LoginFailure, error)); ok {
+		return rf(ctx, subject, now, windowStart)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) *model.LoginFailure); ok {
+		r0 = rf(ctx, subject, now, windowStart)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.LoginFailure)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, subject, now, windowStart)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewLoginFailureDao creates a new instance of LoginFailureDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLoginFailureDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LoginFailureDao {
+	mock := &LoginFailureDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
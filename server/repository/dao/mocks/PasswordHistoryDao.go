@@ -0,0 +1,116 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// PasswordHistoryDao is an autogenerated mock type for the PasswordHistoryDao type
+type PasswordHistoryDao struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, entry, tx
+func (_m *PasswordHistoryDao) Create(ctx context.Context, entry *model.PasswordHistory, tx *gorm.DB) error {
+	ret := _m.Called(ctx, entry, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.PasswordHistory, *gorm.DB) error); ok {
+		r0 = rf(ctx, entry, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUserIdInTransaction provides a mock function with given fields: ctx, userId, tx
+func (_m *PasswordHistoryDao) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUserIdInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListRecentByUserId provides a mock function with given fields: ctx, userId, limit
+func (_m *PasswordHistoryDao) ListRecentByUserId(ctx context.Context, userId int, limit int) ([]*model.PasswordHistory, error) {
+	ret := _m.Called(ctx, userId, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRecentByUserId")
+	}
+
+	var r0 []*model.PasswordHistory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) ([]*model.PasswordHistory, error)); ok {
+		return rf(ctx, userId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) []*model.PasswordHistory); ok {
+		r0 = rf(ctx, userId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.PasswordHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, userId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Trim provides a mock function with given fields: ctx, userId, keep, tx
+func (_m *PasswordHistoryDao) Trim(ctx context.Context, userId int, keep int, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, keep, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Trim")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, keep, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewPasswordHistoryDao creates a new instance of PasswordHistoryDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPasswordHistoryDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PasswordHistoryDao {
+	mock := &PasswordHistoryDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
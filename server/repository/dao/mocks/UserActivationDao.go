@@ -53,29 +53,29 @@ func (_m *UserActivationDao) DeleteByUserId(ctx context.Context, userId int, tx
 	return r0
 }
 
-// GetByCode provides a mock function with given fields: ctx, code
-func (_m *UserActivationDao) GetByCode(ctx context.Context, code string) (*model.UserActivation, error) {
-	ret := _m.Called(ctx, code)
+// GetById provides a mock function with given fields: ctx, id
+func (_m *UserActivationDao) GetById(ctx context.Context, id int64) (*model.UserActivation, error) {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetByCode")
+		panic("no return value specified for GetById")
 	}
 
 	var r0 *model.UserActivation
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.UserActivation, error)); ok {
-		return rf(ctx, code)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*model.UserActivation, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *model.UserActivation); ok {
-		r0 = rf(ctx, code)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *model.UserActivation); ok {
+		r0 = rf(ctx, id)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.UserActivation)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, code)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -83,6 +83,54 @@ func (_m *UserActivationDao) GetByCode(ctx context.Context, code string) (*model
 	return r0, r1
 }
 
+// GetByUserId provides a mock function with given fields: ctx, userId
+func (_m *UserActivationDao) GetByUserId(ctx context.Context, userId int) (*model.UserActivation, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByUserId")
+	}
+
+	var r0 *model.UserActivation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*model.UserActivation, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *model.UserActivation); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserActivation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrementAttempts provides a mock function with given fields: ctx, id
+func (_m *UserActivationDao) IncrementAttempts(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrementAttempts")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Replace provides a mock function with given fields: ctx, activation
 func (_m *UserActivationDao) Replace(ctx context.Context, activation *model.UserActivation) error {
 	ret := _m.Called(ctx, activation)
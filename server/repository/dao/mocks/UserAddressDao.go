@@ -5,6 +5,8 @@ package mocks
 import (
 	context "context"
 
+	gorm "gorm.io/gorm"
+
 	mock "github.com/stretchr/testify/mock"
 
 	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
@@ -43,6 +45,34 @@ func (_m *UserAddressDao) CreateUserAddress(ctx context.Context, address *model.
 	return r0, r1
 }
 
+// DeleteByUserIdInTransaction provides a mock function with given fields: ctx, userID, tx
+func (_m *UserAddressDao) DeleteByUserIdInTransaction(ctx context.Context, userID int, tx *gorm.DB) (int64, error) {
+	ret := _m.Called(ctx, userID, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUserIdInTransaction")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) (int64, error)); ok {
+		return rf(ctx, userID, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) int64); ok {
+		r0 = rf(ctx, userID, tx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *gorm.DB) error); ok {
+		r1 = rf(ctx, userID, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetDefaultAddress provides a mock function with given fields: ctx, userID
 func (_m *UserAddressDao) GetDefaultAddress(ctx context.Context, userID int) (*model.UserAddress, error) {
 	ret := _m.Called(ctx, userID)
@@ -103,6 +133,36 @@ func (_m *UserAddressDao) GetUserAddresses(ctx context.Context, userID int) ([]*
 	return r0, r1
 }
 
+// ListAllByUserId provides a mock function with given fields: ctx, userID
+func (_m *UserAddressDao) ListAllByUserId(ctx context.Context, userID int) ([]*model.UserAddress, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAllByUserId")
+	}
+
+	var r0 []*model.UserAddress
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*model.UserAddress, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*model.UserAddress); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserAddress)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateUserAddress provides a mock function with given fields: ctx, address
 func (_m *UserAddressDao) UpdateUserAddress(ctx context.Context, address *model.UserAddress) (int, error) {
 	ret := _m.Called(ctx, address)
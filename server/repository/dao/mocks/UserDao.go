@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	dao "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
 	gorm "gorm.io/gorm"
 
 	mock "github.com/stretchr/testify/mock"
@@ -17,6 +18,62 @@ type UserDao struct {
 	mock.Mock
 }
 
+// AnonymizeInTransaction provides a mock function with given fields: ctx, userId, placeholderEmail, tx
+func (_m *UserDao) AnonymizeInTransaction(ctx context.Context, userId int, placeholderEmail string, tx *gorm.DB) (bool, error) {
+	ret := _m.Called(ctx, userId, placeholderEmail, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AnonymizeInTransaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, *gorm.DB) (bool, error)); ok {
+		return rf(ctx, userId, placeholderEmail, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, *gorm.DB) bool); ok {
+		r0 = rf(ctx, userId, placeholderEmail, tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, *gorm.DB) error); ok {
+		r1 = rf(ctx, userId, placeholderEmail, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangeEmailInTransaction provides a mock function with given fields: ctx, userId, fromEmail, toEmail, revokeTokens, tx
+func (_m *UserDao) ChangeEmailInTransaction(ctx context.Context, userId int, fromEmail string, toEmail string, revokeTokens bool, tx *gorm.DB) (bool, error) {
+	ret := _m.Called(ctx, userId, fromEmail, toEmail, revokeTokens, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangeEmailInTransaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, bool, *gorm.DB) (bool, error)); ok {
+		return rf(ctx, userId, fromEmail, toEmail, revokeTokens, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, bool, *gorm.DB) bool); ok {
+		r0 = rf(ctx, userId, fromEmail, toEmail, revokeTokens, tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, string, bool, *gorm.DB) error); ok {
+		r1 = rf(ctx, userId, fromEmail, toEmail, revokeTokens, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateUser provides a mock function with given fields: ctx, user
 func (_m *UserDao) CreateUser(ctx context.Context, user *model.User) (int, error) {
 	ret := _m.Called(ctx, user)
@@ -45,6 +102,24 @@ func (_m *UserDao) CreateUser(ctx context.Context, user *model.User) (int, error
 	return r0, r1
 }
 
+// CreateUserInTransaction provides a mock function with given fields: ctx, user, tx
+func (_m *UserDao) CreateUserInTransaction(ctx context.Context, user *model.User, tx *gorm.DB) error {
+	ret := _m.Called(ctx, user, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateUserInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.User, *gorm.DB) error); ok {
+		r0 = rf(ctx, user, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetUserByEmail provides a mock function with given fields: _a0, _a1
 func (_m *UserDao) GetUserByEmail(_a0 context.Context, _a1 string) (*model.User, error) {
 	ret := _m.Called(_a0, _a1)
@@ -105,17 +180,82 @@ func (_m *UserDao) GetUserById(_a0 context.Context, _a1 int) (*model.User, error
 	return r0, r1
 }
 
-// UpdateUser provides a mock function with given fields: ctx, user
-func (_m *UserDao) UpdateUser(ctx context.Context, user *model.User) error {
-	ret := _m.Called(ctx, user)
+// RehashPassword provides a mock function with given fields: ctx, userId, oldHash, newHash
+func (_m *UserDao) RehashPassword(ctx context.Context, userId int, oldHash string, newHash string) (bool, error) {
+	ret := _m.Called(ctx, userId, oldHash, newHash)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateUser")
+		panic("no return value specified for RehashPassword")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string) (bool, error)); ok {
+		return rf(ctx, userId, oldHash, newHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string) bool); ok {
+		r0 = rf(ctx, userId, oldHash, newHash)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, string) error); ok {
+		r1 = rf(ctx, userId, oldHash, newHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchUsers provides a mock function with given fields: ctx, filter, offset, limit
+func (_m *UserDao) SearchUsers(ctx context.Context, filter dao.UserSearchFilter, offset int, limit int) ([]*model.User, int64, error) {
+	ret := _m.Called(ctx, filter, offset, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchUsers")
+	}
+
+	var r0 []*model.User
+	var r1 int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, dao.UserSearchFilter, int, int) ([]*model.User, int64, error)); ok {
+		return rf(ctx, filter, offset, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dao.UserSearchFilter, int, int) []*model.User); ok {
+		r0 = rf(ctx, filter, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dao.UserSearchFilter, int, int) int64); ok {
+		r1 = rf(ctx, filter, offset, limit)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, dao.UserSearchFilter, int, int) error); ok {
+		r2 = rf(ctx, filter, offset, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpdatePassword provides a mock function with given fields: ctx, userId, hashedPassword
+func (_m *UserDao) UpdatePassword(ctx context.Context, userId int, hashedPassword string) error {
+	ret := _m.Called(ctx, userId, hashedPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePassword")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *model.User) error); ok {
-		r0 = rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) error); ok {
+		r0 = rf(ctx, userId, hashedPassword)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -123,6 +263,70 @@ func (_m *UserDao) UpdateUser(ctx context.Context, user *model.User) error {
 	return r0
 }
 
+// UpdatePasswordInTransaction provides a mock function with given fields: ctx, userId, hashedPassword, tx
+func (_m *UserDao) UpdatePasswordInTransaction(ctx context.Context, userId int, hashedPassword string, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, hashedPassword, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdatePasswordInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, hashedPassword, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, userId, name, avatarId
+func (_m *UserDao) UpdateProfile(ctx context.Context, userId int, name string, avatarId string) error {
+	ret := _m.Called(ctx, userId, name, avatarId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string) error); ok {
+		r0 = rf(ctx, userId, name, avatarId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateStatusInTransaction provides a mock function with given fields: ctx, userId, fromStatus, toStatus, revokeTokens, tx
+func (_m *UserDao) UpdateStatusInTransaction(ctx context.Context, userId int, fromStatus int, toStatus int, revokeTokens bool, tx *gorm.DB) (bool, error) {
+	ret := _m.Called(ctx, userId, fromStatus, toStatus, revokeTokens, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateStatusInTransaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, bool, *gorm.DB) (bool, error)); ok {
+		return rf(ctx, userId, fromStatus, toStatus, revokeTokens, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int, bool, *gorm.DB) bool); ok {
+		r0 = rf(ctx, userId, fromStatus, toStatus, revokeTokens, tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, int, bool, *gorm.DB) error); ok {
+		r1 = rf(ctx, userId, fromStatus, toStatus, revokeTokens, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateUserInTransaction provides a mock function with given fields: ctx, user, tx
 func (_m *UserDao) UpdateUserInTransaction(ctx context.Context, user *model.User, tx *gorm.DB) error {
 	ret := _m.Called(ctx, user, tx)
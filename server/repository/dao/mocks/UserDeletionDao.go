@@ -0,0 +1,140 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+
+	time "time"
+)
+
+// UserDeletionDao is an autogenerated mock type for the UserDeletionDao type
+type UserDeletionDao struct {
+	mock.Mock
+}
+
+// CreateInTransaction provides a mock function with given fields: ctx, deletion, tx
+func (_m *UserDeletionDao) CreateInTransaction(ctx context.Context, deletion *model.UserDeletion, tx *gorm.DB) error {
+	ret := _m.Called(ctx, deletion, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserDeletion, *gorm.DB) error); ok {
+		r0 = rf(ctx, deletion, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteInTransaction provides a mock function with given fields: ctx, userId, tx
+func (_m *UserDeletionDao) DeleteInTransaction(ctx context.Context, userId int, tx *gorm.DB) (bool, error) {
+	ret := _m.Called(ctx, userId, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteInTransaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) (bool, error)); ok {
+		return rf(ctx, userId, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) bool); ok {
+		r0 = rf(ctx, userId, tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, *gorm.DB) error); ok {
+		r1 = rf(ctx, userId, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByCancelHash provides a mock function with given fields: ctx, cancelHash
+func (_m *UserDeletionDao) GetByCancelHash(ctx context.Context, cancelHash string) (*model.UserDeletion, error) {
+	ret := _m.Called(ctx, cancelHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByCancelHash")
+	}
+
+	var r0 *model.UserDeletion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.UserDeletion, error)); ok {
+		return rf(ctx, cancelHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.UserDeletion); ok {
+		r0 = rf(ctx, cancelHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserDeletion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, cancelHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDue provides a mock function with given fields: ctx, before, limit
+func (_m *UserDeletionDao) ListDue(ctx context.Context, before time.Time, limit int) ([]*model.UserDeletion, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDue")
+	}
+
+	var r0 []*model.UserDeletion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) ([]*model.UserDeletion, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []*model.UserDeletion); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserDeletion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewUserDeletionDao creates a new instance of UserDeletionDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserDeletionDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserDeletionDao {
+	mock := &UserDeletionDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,232 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+
+	time "time"
+)
+
+// UserEmailChangeDao is an autogenerated mock type for the UserEmailChangeDao type
+type UserEmailChangeDao struct {
+	mock.Mock
+}
+
+// ConfirmInTransaction provides a mock function with given fields: ctx, id, revertHash, confirmedAt, expiresAt, tx
+func (_m *UserEmailChangeDao) ConfirmInTransaction(ctx context.Context, id int64, revertHash string, confirmedAt time.Time, expiresAt time.Time, tx *gorm.DB) (bool, error) {
+	ret := _m.Called(ctx, id, revertHash, confirmedAt, expiresAt, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmInTransaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time, time.Time, *gorm.DB) (bool, error)); ok {
+		return rf(ctx, id, revertHash, confirmedAt, expiresAt, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time, time.Time, *gorm.DB) bool); ok {
+		r0 = rf(ctx, id, revertHash, confirmedAt, expiresAt, tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, time.Time, time.Time, *gorm.DB) error); ok {
+		r1 = rf(ctx, id, revertHash, confirmedAt, expiresAt, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteByUserIdInTransaction provides a mock function with given fields: ctx, userId, tx
+func (_m *UserEmailChangeDao) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUserIdInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteExpired provides a mock function with given fields: ctx, before
+func (_m *UserEmailChangeDao) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := _m.Called(ctx, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpired")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteInTransaction provides a mock function with given fields: ctx, id, tx
+func (_m *UserEmailChangeDao) DeleteInTransaction(ctx context.Context, id int64, tx *gorm.DB) (bool, error) {
+	ret := _m.Called(ctx, id, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteInTransaction")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *gorm.DB) (bool, error)); ok {
+		return rf(ctx, id, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *gorm.DB) bool); ok {
+		r0 = rf(ctx, id, tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *gorm.DB) error); ok {
+		r1 = rf(ctx, id, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByRevertHash provides a mock function with given fields: ctx, revertHash
+func (_m *UserEmailChangeDao) GetByRevertHash(ctx context.Context, revertHash string) (*model.UserEmailChange, error) {
+	ret := _m.Called(ctx, revertHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByRevertHash")
+	}
+
+	var r0 *model.UserEmailChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.UserEmailChange, error)); ok {
+		return rf(ctx, revertHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.UserEmailChange); ok {
+		r0 = rf(ctx, revertHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserEmailChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, revertHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPendingByUserId provides a mock function with given fields: ctx, userId
+func (_m *UserEmailChangeDao) GetPendingByUserId(ctx context.Context, userId int) (*model.UserEmailChange, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPendingByUserId")
+	}
+
+	var r0 *model.UserEmailChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*model.UserEmailChange, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *model.UserEmailChange); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserEmailChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrementAttempts provides a mock function with given fields: ctx, id
+func (_m *UserEmailChangeDao) IncrementAttempts(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrementAttempts")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Replace provides a mock function with given fields: ctx, change
+func (_m *UserEmailChangeDao) Replace(ctx context.Context, change *model.UserEmailChange) error {
+	ret := _m.Called(ctx, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Replace")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserEmailChange) error); ok {
+		r0 = rf(ctx, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewUserEmailChangeDao creates a new instance of UserEmailChangeDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserEmailChangeDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserEmailChangeDao {
+	mock := &UserEmailChangeDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
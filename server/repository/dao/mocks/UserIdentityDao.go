@@ -0,0 +1,128 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// UserIdentityDao is an autogenerated mock type for the UserIdentityDao type
+type UserIdentityDao struct {
+	mock.Mock
+}
+
+// CreateInTransaction provides a mock function with given fields: ctx, identity, tx
+func (_m *UserIdentityDao) CreateInTransaction(ctx context.Context, identity *model.UserIdentity, tx *gorm.DB) error {
+	ret := _m.Called(ctx, identity, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserIdentity, *gorm.DB) error); ok {
+		r0 = rf(ctx, identity, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUserIdInTransaction provides a mock function with given fields: ctx, userId, tx
+func (_m *UserIdentityDao) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUserIdInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByProviderSubject provides a mock function with given fields: ctx, provider, subject
+func (_m *UserIdentityDao) GetByProviderSubject(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	ret := _m.Called(ctx, provider, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByProviderSubject")
+	}
+
+	var r0 *model.UserIdentity
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.UserIdentity, error)); ok {
+		return rf(ctx, provider, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.UserIdentity); ok {
+		r0 = rf(ctx, provider, subject)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserIdentity)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, provider, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListByUserId provides a mock function with given fields: ctx, userId
+func (_m *UserIdentityDao) ListByUserId(ctx context.Context, userId int) ([]*model.UserIdentity, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByUserId")
+	}
+
+	var r0 []*model.UserIdentity
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*model.UserIdentity, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*model.UserIdentity); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserIdentity)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewUserIdentityDao creates a new instance of UserIdentityDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserIdentityDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserIdentityDao {
+	mock := &UserIdentityDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
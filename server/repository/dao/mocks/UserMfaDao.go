@@ -0,0 +1,174 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+
+	time "time"
+)
+
+// UserMfaDao is an autogenerated mock type for the UserMfaDao type
+type UserMfaDao struct {
+	mock.Mock
+}
+
+// DeleteByUserId provides a mock function with given fields: ctx, userId, tx
+func (_m *UserMfaDao) DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUserId")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnableInTransaction provides a mock function with given fields: ctx, userId, step, enabledAt, tx
+func (_m *UserMfaDao) EnableInTransaction(ctx context.Context, userId int, step int64, enabledAt time.Time, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, step, enabledAt, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, time.Time, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, step, enabledAt, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByUserId provides a mock function with given fields: ctx, userId
+func (_m *UserMfaDao) GetByUserId(ctx context.Context, userId int) (*model.UserMfa, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByUserId")
+	}
+
+	var r0 *model.UserMfa
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*model.UserMfa, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *model.UserMfa); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserMfa)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrementEnrollmentAttempts provides a mock function with given fields: ctx, userId, maxAttempts
+func (_m *UserMfaDao) IncrementEnrollmentAttempts(ctx context.Context, userId int, maxAttempts int) (bool, error) {
+	ret := _m.Called(ctx, userId, maxAttempts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrementEnrollmentAttempts")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (bool, error)); ok {
+		return rf(ctx, userId, maxAttempts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) bool); ok {
+		r0 = rf(ctx, userId, maxAttempts)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, userId, maxAttempts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLastUsedStep provides a mock function with given fields: ctx, userId, step
+func (_m *UserMfaDao) UpdateLastUsedStep(ctx context.Context, userId int, step int64) (bool, error) {
+	ret := _m.Called(ctx, userId, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateLastUsedStep")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64) (bool, error)); ok {
+		return rf(ctx, userId, step)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64) bool); ok {
+		r0 = rf(ctx, userId, step)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64) error); ok {
+		r1 = rf(ctx, userId, step)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Upsert provides a mock function with given fields: ctx, mfa
+func (_m *UserMfaDao) Upsert(ctx context.Context, mfa *model.UserMfa) error {
+	ret := _m.Called(ctx, mfa)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Upsert")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserMfa) error); ok {
+		r0 = rf(ctx, mfa)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewUserMfaDao creates a new instance of UserMfaDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserMfaDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserMfaDao {
+	mock := &UserMfaDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
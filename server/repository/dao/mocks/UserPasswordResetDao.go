@@ -0,0 +1,126 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// UserPasswordResetDao is an autogenerated mock type for the UserPasswordResetDao type
+type UserPasswordResetDao struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, reset, tx
+func (_m *UserPasswordResetDao) Create(ctx context.Context, reset *model.UserPasswordReset, tx *gorm.DB) error {
+	ret := _m.Called(ctx, reset, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserPasswordReset, *gorm.DB) error); ok {
+		r0 = rf(ctx, reset, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUserId provides a mock function with given fields: ctx, userId, tx
+func (_m *UserPasswordResetDao) DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUserId")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetLatestByUserId provides a mock function with given fields: ctx, userId
+func (_m *UserPasswordResetDao) GetLatestByUserId(ctx context.Context, userId int) (*model.UserPasswordReset, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLatestByUserId")
+	}
+
+	var r0 *model.UserPasswordReset
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*model.UserPasswordReset, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *model.UserPasswordReset); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserPasswordReset)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrementAttempts provides a mock function with given fields: ctx, id, maxAttempts
+func (_m *UserPasswordResetDao) IncrementAttempts(ctx context.Context, id int64, maxAttempts int) (bool, error) {
+	ret := _m.Called(ctx, id, maxAttempts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IncrementAttempts")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) (bool, error)); ok {
+		return rf(ctx, id, maxAttempts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) bool); ok {
+		r0 = rf(ctx, id, maxAttempts)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, id, maxAttempts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewUserPasswordResetDao creates a new instance of UserPasswordResetDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserPasswordResetDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserPasswordResetDao {
+	mock := &UserPasswordResetDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,98 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+
+	time "time"
+)
+
+// UserRecoveryCodeDao is an autogenerated mock type for the UserRecoveryCodeDao type
+type UserRecoveryCodeDao struct {
+	mock.Mock
+}
+
+// CreateBatch provides a mock function with given fields: ctx, codes, tx
+func (_m *UserRecoveryCodeDao) CreateBatch(ctx context.Context, codes []*model.UserRecoveryCode, tx *gorm.DB) error {
+	ret := _m.Called(ctx, codes, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.UserRecoveryCode, *gorm.DB) error); ok {
+		r0 = rf(ctx, codes, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUserId provides a mock function with given fields: ctx, userId, tx
+func (_m *UserRecoveryCodeDao) DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUserId")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkUsed provides a mock function with given fields: ctx, userId, codeHash, usedAt
+func (_m *UserRecoveryCodeDao) MarkUsed(ctx context.Context, userId int, codeHash string, usedAt time.Time) (bool, error) {
+	ret := _m.Called(ctx, userId, codeHash, usedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkUsed")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, time.Time) (bool, error)); ok {
+		return rf(ctx, userId, codeHash, usedAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, time.Time) bool); ok {
+		r0 = rf(ctx, userId, codeHash, usedAt)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, time.Time) error); ok {
+		r1 = rf(ctx, userId, codeHash, usedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewUserRecoveryCodeDao creates a new instance of UserRecoveryCodeDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserRecoveryCodeDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserRecoveryCodeDao {
+	mock := &UserRecoveryCodeDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,300 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+
+	time "time"
+)
+
+// UserSessionDao is an autogenerated mock type for the UserSessionDao type
+type UserSessionDao struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, session
+func (_m *UserSessionDao) Create(ctx context.Context, session *model.UserSession) error {
+	ret := _m.Called(ctx, session)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserSession) error); ok {
+		r0 = rf(ctx, session)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUserIdInTransaction provides a mock function with given fields: ctx, userId, tx
+func (_m *UserSessionDao) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := _m.Called(ctx, userId, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUserIdInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, *gorm.DB) error); ok {
+		r0 = rf(ctx, userId, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteExpired provides a mock function with given fields: ctx, before
+func (_m *UserSessionDao) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := _m.Called(ctx, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpired")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Extend provides a mock function with given fields: ctx, id, lastSeenAt, expiresAt
+func (_m *UserSessionDao) Extend(ctx context.Context, id int64, lastSeenAt time.Time, expiresAt time.Time) error {
+	ret := _m.Called(ctx, id, lastSeenAt, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Extend")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time, time.Time) error); ok {
+		r0 = rf(ctx, id, lastSeenAt, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByFamilyId provides a mock function with given fields: ctx, familyID
+func (_m *UserSessionDao) GetByFamilyId(ctx context.Context, familyID string) (*model.UserSession, error) {
+	ret := _m.Called(ctx, familyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByFamilyId")
+	}
+
+	var r0 *model.UserSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.UserSession, error)); ok {
+		return rf(ctx, familyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.UserSession); ok {
+		r0 = rf(ctx, familyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, familyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetById provides a mock function with given fields: ctx, id
+func (_m *UserSessionDao) GetById(ctx context.Context, id int64) (*model.UserSession, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetById")
+	}
+
+	var r0 *model.UserSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*model.UserSession, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *model.UserSession); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListActiveByUserId provides a mock function with given fields: ctx, userId, now
+func (_m *UserSessionDao) ListActiveByUserId(ctx context.Context, userId int, now time.Time) ([]*model.UserSession, error) {
+	ret := _m.Called(ctx, userId, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListActiveByUserId")
+	}
+
+	var r0 []*model.UserSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) ([]*model.UserSession, error)); ok {
+		return rf(ctx, userId, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) []*model.UserSession); ok {
+		r0 = rf(ctx, userId, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, userId, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListByUserId provides a mock function with given fields: ctx, userId
+func (_m *UserSessionDao) ListByUserId(ctx context.Context, userId int) ([]*model.UserSession, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByUserId")
+	}
+
+	var r0 []*model.UserSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*model.UserSession, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*model.UserSession); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Revoke provides a mock function with given fields: ctx, userId, id, revokedAt
+func (_m *UserSessionDao) Revoke(ctx context.Context, userId int, id int64, revokedAt time.Time) (bool, error) {
+	ret := _m.Called(ctx, userId, id, revokedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, time.Time) (bool, error)); ok {
+		return rf(ctx, userId, id, revokedAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64, time.Time) bool); ok {
+		r0 = rf(ctx, userId, id, revokedAt)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64, time.Time) error); ok {
+		r1 = rf(ctx, userId, id, revokedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeByFamilyId provides a mock function with given fields: ctx, familyID, revokedAt
+func (_m *UserSessionDao) RevokeByFamilyId(ctx context.Context, familyID string, revokedAt time.Time) error {
+	ret := _m.Called(ctx, familyID, revokedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeByFamilyId")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, familyID, revokedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateLastSeen provides a mock function with given fields: ctx, id, lastSeenAt
+func (_m *UserSessionDao) UpdateLastSeen(ctx context.Context, id int64, lastSeenAt time.Time) error {
+	ret := _m.Called(ctx, id, lastSeenAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateLastSeen")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) error); ok {
+		r0 = rf(ctx, id, lastSeenAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewUserSessionDao creates a new instance of UserSessionDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserSessionDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserSessionDao {
+	mock := &UserSessionDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,80 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	gorm "gorm.io/gorm"
+
+	mock "github.com/stretchr/testify/mock"
+
+	model "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// UserStatusHistoryDao is an autogenerated mock type for the UserStatusHistoryDao type
+type UserStatusHistoryDao struct {
+	mock.Mock
+}
+
+// CreateInTransaction provides a mock function with given fields: ctx, entry, tx
+func (_m *UserStatusHistoryDao) CreateInTransaction(ctx context.Context, entry *model.UserStatusHistory, tx *gorm.DB) error {
+	ret := _m.Called(ctx, entry, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateInTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserStatusHistory, *gorm.DB) error); ok {
+		r0 = rf(ctx, entry, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListByUserId provides a mock function with given fields: ctx, userId
+func (_m *UserStatusHistoryDao) ListByUserId(ctx context.Context, userId int) ([]*model.UserStatusHistory, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByUserId")
+	}
+
+	var r0 []*model.UserStatusHistory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]*model.UserStatusHistory, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*model.UserStatusHistory); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserStatusHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewUserStatusHistoryDao creates a new instance of UserStatusHistoryDao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewUserStatusHistoryDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *UserStatusHistoryDao {
+	mock := &UserStatusHistoryDao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
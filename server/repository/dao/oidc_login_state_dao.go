@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type OidcLoginStateDao interface {
+	Create(ctx context.Context, state *model.OidcLoginState) error
+	GetByStateHash(ctx context.Context, stateHash string) (*model.OidcLoginState, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OidcLoginStateDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	oidcLoginStateOnce sync.Once
+	oidcLoginStateDao  *OidcLoginStateDaoImpl
+)
+
+func GetOidcLoginStateDao() *OidcLoginStateDaoImpl {
+	oidcLoginStateOnce.Do(func() {
+		if oidcLoginStateDao == nil {
+			oidcLoginStateDao = &OidcLoginStateDaoImpl{db: repository.DB}
+		}
+	})
+	return oidcLoginStateDao
+}
+
+func (dao *OidcLoginStateDaoImpl) Create(ctx context.Context, state *model.OidcLoginState) error {
+	ret := dao.db.WithContext(ctx).Create(state)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create oidc login state: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *OidcLoginStateDaoImpl) GetByStateHash(ctx context.Context, stateHash string) (*model.OidcLoginState, error) {
+	var state model.OidcLoginState
+	ret := dao.db.WithContext(ctx).Where("state_hash = ?", stateHash).First(&state)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get oidc login state: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &state, nil
+}
+
+// Delete consumes the state. It returns false if it was already consumed.
+func (dao *OidcLoginStateDaoImpl) Delete(ctx context.Context, id int64) (bool, error) {
+	ret := dao.db.WithContext(ctx).Where("id = ?", id).Delete(&model.OidcLoginState{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete oidc login state: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *OidcLoginStateDaoImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.OidcLoginState{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired oidc login states: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
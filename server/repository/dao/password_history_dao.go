@@ -0,0 +1,84 @@
+package dao
+
+import (
+	"context"
+	"sync"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryDao interface {
+	Create(ctx context.Context, entry *model.PasswordHistory, tx *gorm.DB) error
+	ListRecentByUserId(ctx context.Context, userId int, limit int) ([]*model.PasswordHistory, error)
+	// Trim deletes all but the newest keep entries of the user
+	Trim(ctx context.Context, userId int, keep int, tx *gorm.DB) error
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type PasswordHistoryDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	passwordHistoryOnce sync.Once
+	passwordHistoryDao  *PasswordHistoryDaoImpl
+)
+
+func GetPasswordHistoryDao() *PasswordHistoryDaoImpl {
+	passwordHistoryOnce.Do(func() {
+		if passwordHistoryDao == nil {
+			passwordHistoryDao = &PasswordHistoryDaoImpl{db: repository.DB}
+		}
+	})
+	return passwordHistoryDao
+}
+
+func (dao *PasswordHistoryDaoImpl) Create(ctx context.Context, entry *model.PasswordHistory, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Create(entry)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create password history: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *PasswordHistoryDaoImpl) ListRecentByUserId(ctx context.Context, userId int, limit int) ([]*model.PasswordHistory, error) {
+	var entries []*model.PasswordHistory
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id desc").Limit(limit).Find(&entries)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list password history: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return entries, nil
+}
+
+func (dao *PasswordHistoryDaoImpl) Trim(ctx context.Context, userId int, keep int, tx *gorm.DB) error {
+	var ids []int64
+	ret := tx.WithContext(ctx).Model(&model.PasswordHistory{}).Where("user_id = ?", userId).
+		Order("id desc").Offset(keep).Limit(1000).Pluck("id", &ids)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list old password history: %v", ret.Error)
+		return ret.Error
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	ret = tx.WithContext(ctx).Where("id IN ?", ids).Delete(&model.PasswordHistory{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to trim password history: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *PasswordHistoryDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.PasswordHistory{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete password history: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RateLimitBucketDao interface {
+	// Update locks the bucket, which is nil if it does not exist yet, and
+	// saves the bucket returned by fn.
+	Update(ctx context.Context, key string, fn func(bucket *model.RateLimitBucket) *model.RateLimitBucket) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type RateLimitBucketDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	rateLimitBucketOnce sync.Once
+	rateLimitBucketDao  *RateLimitBucketDaoImpl
+)
+
+func GetRateLimitBucketDao() *RateLimitBucketDaoImpl {
+	rateLimitBucketOnce.Do(func() {
+		if rateLimitBucketDao == nil {
+			rateLimitBucketDao = &RateLimitBucketDaoImpl{db: repository.DB}
+		}
+	})
+	return rateLimitBucketDao
+}
+
+func (dao *RateLimitBucketDaoImpl) Update(ctx context.Context, key string, fn func(bucket *model.RateLimitBucket) *model.RateLimitBucket) error {
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var buckets []*model.RateLimitBucket
+		ret := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("bucket_key = ?", key).Limit(1).Find(&buckets)
+		if ret.Error != nil {
+			return ret.Error
+		}
+		var current *model.RateLimitBucket
+		if len(buckets) > 0 {
+			current = buckets[0]
+		}
+		updated := fn(current)
+		updated.BucketKey = key
+		// A new bucket has no row to lock, so two instances may both create
+		// it; the upsert lets the later one win
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(updated).Error
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to update rate limit bucket: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (dao *RateLimitBucketDaoImpl) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("full_at < ?", now).Delete(&model.RateLimitBucket{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired rate limit buckets: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenDao interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	MarkUsed(ctx context.Context, id int64, usedAt time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+	RevokeByUserId(ctx context.Context, userId int, revokedAt time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type RefreshTokenDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	refreshTokenOnce sync.Once
+	refreshTokenDao  *RefreshTokenDaoImpl
+)
+
+func GetRefreshTokenDao() *RefreshTokenDaoImpl {
+	refreshTokenOnce.Do(func() {
+		if refreshTokenDao == nil {
+			refreshTokenDao = &RefreshTokenDaoImpl{db: repository.DB}
+		}
+	})
+	return refreshTokenDao
+}
+
+func (dao *RefreshTokenDaoImpl) Create(ctx context.Context, token *model.RefreshToken) error {
+	ret := dao.db.WithContext(ctx).Create(token)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create refresh token: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *RefreshTokenDaoImpl) GetByTokenHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	ret := dao.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get refresh token: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &token, nil
+}
+
+// MarkUsed flags the token as consumed. It returns false if the token had
+// already been used, so two concurrent refreshes cannot both succeed.
+func (dao *RefreshTokenDaoImpl) MarkUsed(ctx context.Context, id int64, usedAt time.Time) (bool, error) {
+	ret := dao.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("id = ? and used_at is null", id).
+		Update("used_at", usedAt)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to mark refresh token used: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *RefreshTokenDaoImpl) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	ret := dao.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("family_id = ? and revoked_at is null", familyID).
+		Update("revoked_at", revokedAt)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to revoke refresh token family: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *RefreshTokenDaoImpl) RevokeByUserId(ctx context.Context, userId int, revokedAt time.Time) error {
+	ret := dao.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("user_id = ? and revoked_at is null", userId).
+		Update("revoked_at", revokedAt)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to revoke refresh tokens of user: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *RefreshTokenDaoImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.RefreshToken{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired refresh tokens: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
+
+func (dao *RefreshTokenDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.RefreshToken{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete refresh tokens: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
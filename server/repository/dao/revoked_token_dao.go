@@ -0,0 +1,66 @@
+package dao
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedTokenDao interface {
+	Create(ctx context.Context, token *model.RevokedToken) error
+	ExistsByJti(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type RevokedTokenDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	revokedTokenOnce sync.Once
+	revokedTokenDao  *RevokedTokenDaoImpl
+)
+
+func GetRevokedTokenDao() *RevokedTokenDaoImpl {
+	revokedTokenOnce.Do(func() {
+		if revokedTokenDao == nil {
+			revokedTokenDao = &RevokedTokenDaoImpl{db: repository.DB}
+		}
+	})
+	return revokedTokenDao
+}
+
+// Create stores the revoked token; revoking the same jti twice is a no-op.
+func (dao *RevokedTokenDaoImpl) Create(ctx context.Context, token *model.RevokedToken) error {
+	ret := dao.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create revoked token: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *RevokedTokenDaoImpl) ExistsByJti(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	ret := dao.db.WithContext(ctx).Model(&model.RevokedToken{}).Where("jti = ?", jti).Count(&count)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to check revoked token: %v", ret.Error)
+		return false, ret.Error
+	}
+	return count > 0, nil
+}
+
+func (dao *RevokedTokenDaoImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.RevokedToken{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired revoked tokens: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
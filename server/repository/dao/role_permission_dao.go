@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"context"
+	"sync"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RolePermissionDao interface {
+	GetPermissionsByRole(ctx context.Context, role string) ([]string, error)
+	Grant(ctx context.Context, role, permission string) error
+	Revoke(ctx context.Context, role, permission string) error
+}
+
+type RolePermissionDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	rolePermissionOnce sync.Once
+	rolePermissionDao  *RolePermissionDaoImpl
+)
+
+func GetRolePermissionDao() *RolePermissionDaoImpl {
+	rolePermissionOnce.Do(func() {
+		if rolePermissionDao == nil {
+			rolePermissionDao = &RolePermissionDaoImpl{db: repository.DB}
+		}
+	})
+	return rolePermissionDao
+}
+
+func (dao *RolePermissionDaoImpl) GetPermissionsByRole(ctx context.Context, role string) ([]string, error) {
+	var permissions []string
+	ret := dao.db.WithContext(ctx).Model(&model.RolePermission{}).Where("role = ?", role).Order("permission").Pluck("permission", &permissions)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to get permissions of role %s: %v", role, ret.Error)
+		return nil, ret.Error
+	}
+	return permissions, nil
+}
+
+// Grant adds the permission to the role; granting it twice is a no-op.
+func (dao *RolePermissionDaoImpl) Grant(ctx context.Context, role, permission string) error {
+	ret := dao.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.RolePermission{Role: role, Permission: permission})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to grant permission %s to role %s: %v", permission, role, ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *RolePermissionDaoImpl) Revoke(ctx context.Context, role, permission string) error {
+	ret := dao.db.WithContext(ctx).Where("role = ? AND permission = ?", role, permission).Delete(&model.RolePermission{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to revoke permission %s from role %s: %v", permission, role, ret.Error)
+		return ret.Error
+	}
+	return nil
+}
@@ -13,7 +13,9 @@ import (
 type UserActivationDao interface {
 	DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error
 	Create(ctx context.Context, activation *model.UserActivation, tx *gorm.DB) error
-	GetByCode(ctx context.Context, code string) (*model.UserActivation, error)
+	GetByUserId(ctx context.Context, userId int) (*model.UserActivation, error)
+	GetById(ctx context.Context, id int64) (*model.UserActivation, error)
+	IncrementAttempts(ctx context.Context, id int64) error
 	Update(ctx context.Context, activation *model.UserActivation, tx *gorm.DB) error
 	Replace(ctx context.Context, activation *model.UserActivation) error
 }
@@ -36,9 +38,15 @@ func GetUserActivationDao() *UserActivationDaoImpl {
 	return userActivationDao
 }
 
+// Replace makes the activation the only one of its user, so earlier codes
+// stop working.
 func (dao *UserActivationDaoImpl) Replace(ctx context.Context, activation *model.UserActivation) error {
-	ret := dao.db.WithContext(ctx).Save(activation)
-	return ret.Error
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", activation.UserID).Delete(&model.UserActivation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(activation).Error
+	})
 }
 
 func (dao *UserActivationDaoImpl) DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error {
@@ -51,9 +59,21 @@ func (dao *UserActivationDaoImpl) Create(ctx context.Context, activation *model.
 	return ret.Error
 }
 
-func (dao *UserActivationDaoImpl) GetByCode(ctx context.Context, code string) (*model.UserActivation, error) {
+func (dao *UserActivationDaoImpl) GetByUserId(ctx context.Context, userId int) (*model.UserActivation, error) {
+	var activation model.UserActivation
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id DESC").First(&activation)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, ret.Error
+	}
+	return &activation, nil
+}
+
+func (dao *UserActivationDaoImpl) GetById(ctx context.Context, id int64) (*model.UserActivation, error) {
 	var activation model.UserActivation
-	ret := dao.db.WithContext(ctx).Where("code = ?", code).First(&activation)
+	ret := dao.db.WithContext(ctx).Where("id = ?", id).First(&activation)
 	if ret.Error != nil {
 		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -63,6 +83,12 @@ func (dao *UserActivationDaoImpl) GetByCode(ctx context.Context, code string) (*
 	return &activation, nil
 }
 
+func (dao *UserActivationDaoImpl) IncrementAttempts(ctx context.Context, id int64) error {
+	ret := dao.db.WithContext(ctx).Model(&model.UserActivation{}).Where("id = ?", id).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	return ret.Error
+}
+
 func (dao *UserActivationDaoImpl) Update(ctx context.Context, activation *model.UserActivation, tx *gorm.DB) error {
 	ret := tx.WithContext(ctx).Save(activation)
 	return ret.Error
@@ -13,9 +13,11 @@ import (
 
 type UserAddressDao interface {
 	GetUserAddresses(ctx context.Context, userID int) ([]*model.UserAddress, error)
+	ListAllByUserId(ctx context.Context, userID int) ([]*model.UserAddress, error)
 	CreateUserAddress(ctx context.Context, address *model.UserAddress) (int, error)
 	UpdateUserAddress(ctx context.Context, address *model.UserAddress) (int, error)
 	GetDefaultAddress(ctx context.Context, userID int) (*model.UserAddress, error)
+	DeleteByUserIdInTransaction(ctx context.Context, userID int, tx *gorm.DB) (int64, error)
 }
 
 type UserAddressDaoImpl struct {
@@ -46,6 +48,18 @@ func (dao *UserAddressDaoImpl) GetUserAddresses(ctx context.Context, userID int)
 	return addresses, nil
 }
 
+// ListAllByUserId returns every address of the user, including the ones the
+// user deleted.
+func (dao *UserAddressDaoImpl) ListAllByUserId(ctx context.Context, userID int) ([]*model.UserAddress, error) {
+	var addresses []*model.UserAddress
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userID).Order("id asc").Find(&addresses)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list all user addresses: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return addresses, nil
+}
+
 func (dao *UserAddressDaoImpl) CreateUserAddress(ctx context.Context, address *model.UserAddress) (int, error) {
 	ret := dao.db.WithContext(ctx).Create(address)
 	if ret.Error != nil {
@@ -81,3 +95,14 @@ func (dao *UserAddressDaoImpl) GetDefaultAddress(ctx context.Context, userID int
 	}
 	return &address, nil
 }
+
+// DeleteByUserIdInTransaction removes every address of the user, including
+// the ones deleted by the user before.
+func (dao *UserAddressDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userID int, tx *gorm.DB) (int64, error) {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserAddress{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete user addresses: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
@@ -3,7 +3,9 @@ package dao
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
@@ -14,10 +16,27 @@ import (
 
 type UserDao interface {
 	CreateUser(ctx context.Context, user *model.User) (int, error)
+	CreateUserInTransaction(ctx context.Context, user *model.User, tx *gorm.DB) error
 	UpdateUserInTransaction(ctx context.Context, user *model.User, tx *gorm.DB) error
-	UpdateUser(ctx context.Context, user *model.User) error
+	UpdateProfile(ctx context.Context, userId int, name, avatarId string) error
+	UpdatePassword(ctx context.Context, userId int, hashedPassword string) error
+	UpdatePasswordInTransaction(ctx context.Context, userId int, hashedPassword string, tx *gorm.DB) error
+	RehashPassword(ctx context.Context, userId int, oldHash, newHash string) (bool, error)
+	ChangeEmailInTransaction(ctx context.Context, userId int, fromEmail, toEmail string, revokeTokens bool, tx *gorm.DB) (bool, error)
+	UpdateStatusInTransaction(ctx context.Context, userId int, fromStatus, toStatus int, revokeTokens bool, tx *gorm.DB) (bool, error)
+	AnonymizeInTransaction(ctx context.Context, userId int, placeholderEmail string, tx *gorm.DB) (bool, error)
 	GetUserByEmail(context.Context, string) (*model.User, error)
 	GetUserById(context.Context, int) (*model.User, error)
+	SearchUsers(ctx context.Context, filter UserSearchFilter, offset, limit int) ([]*model.User, int64, error)
+}
+
+// UserSearchFilter narrows SearchUsers. Zero fields match every user.
+type UserSearchFilter struct {
+	EmailPrefix string
+	Status      *int
+	// CreatedFrom is inclusive, CreatedTo exclusive
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
 }
 
 type UserDaoImpl struct {
@@ -51,6 +70,16 @@ func (dao *UserDaoImpl) CreateUser(ctx context.Context, user *model.User) (int,
 	return user.ID, nil
 }
 
+func (dao *UserDaoImpl) CreateUserInTransaction(ctx context.Context, user *model.User, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Create(user)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create user: %v", ret.Error)
+		return ret.Error
+	}
+	log.Logger.Infof("User created with ID: %d", user.ID)
+	return nil
+}
+
 func (dao *UserDaoImpl) UpdateUserInTransaction(ctx context.Context, user *model.User, tx *gorm.DB) error {
 	ret := tx.WithContext(ctx).Model(&model.User{}).Where("id = ?", user.ID).Updates(user)
 	if ret.Error != nil {
@@ -60,15 +89,99 @@ func (dao *UserDaoImpl) UpdateUserInTransaction(ctx context.Context, user *model
 	return nil
 }
 
-func (dao *UserDaoImpl) UpdateUser(ctx context.Context, user *model.User) error {
-	ret := dao.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", user.ID).Updates(user)
+// UpdateProfile writes only the profile columns, so a status, password or
+// email change made meanwhile is kept.
+func (dao *UserDaoImpl) UpdateProfile(ctx context.Context, userId int, name, avatarId string) error {
+	ret := dao.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"name":      name,
+		"avatar_id": avatarId,
+	})
 	if ret.Error != nil {
-		log.Logger.Errorf("Failed to update user: %v", ret.Error)
+		log.Logger.Errorf("Failed to update user profile: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *UserDaoImpl) UpdatePassword(ctx context.Context, userId int, hashedPassword string) error {
+	return dao.UpdatePasswordInTransaction(ctx, userId, hashedPassword, dao.db)
+}
+
+// UpdatePasswordInTransaction stores the new password hash and bumps the
+// credential version so tokens issued with the old password stop validating.
+func (dao *UserDaoImpl) UpdatePasswordInTransaction(ctx context.Context, userId int, hashedPassword string, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Model(&model.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"password":           hashedPassword,
+		"credential_version": gorm.Expr("credential_version + 1"),
+	})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to update user password: %v", ret.Error)
 		return ret.Error
 	}
 	return nil
 }
 
+// RehashPassword replaces the hash of an unchanged password with a stronger
+// one. Unlike UpdatePassword it keeps the credential version, and it does
+// nothing if the password was changed since oldHash was read.
+func (dao *UserDaoImpl) RehashPassword(ctx context.Context, userId int, oldHash, newHash string) (bool, error) {
+	ret := dao.db.WithContext(ctx).Model(&model.User{}).Where("id = ? AND password = ?", userId, oldHash).Update("password", newHash)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to rehash user password: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+// ChangeEmailInTransaction replaces the email of the user if it is still
+// fromEmail. With revokeTokens the credential version is bumped as well, so
+// every token issued before stops validating.
+func (dao *UserDaoImpl) ChangeEmailInTransaction(ctx context.Context, userId int, fromEmail, toEmail string, revokeTokens bool, tx *gorm.DB) (bool, error) {
+	updates := map[string]interface{}{"email": toEmail}
+	if revokeTokens {
+		updates["credential_version"] = gorm.Expr("credential_version + 1")
+	}
+	ret := tx.WithContext(ctx).Model(&model.User{}).Where("id = ? AND email = ?", userId, fromEmail).Updates(updates)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to change user email: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+// UpdateStatusInTransaction moves the user from fromStatus to toStatus. It
+// returns false if the user is no longer in fromStatus. With revokeTokens the
+// credential version is bumped as well.
+func (dao *UserDaoImpl) UpdateStatusInTransaction(ctx context.Context, userId int, fromStatus, toStatus int, revokeTokens bool, tx *gorm.DB) (bool, error) {
+	updates := map[string]interface{}{"status": toStatus}
+	if revokeTokens {
+		updates["credential_version"] = gorm.Expr("credential_version + 1")
+	}
+	ret := tx.WithContext(ctx).Model(&model.User{}).Where("id = ? AND status = ?", userId, fromStatus).Updates(updates)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to update user status: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+// AnonymizeInTransaction strips the personal data of a deleted user. The row
+// is kept so references from other services stay valid; placeholderEmail
+// frees the real address for a new account.
+func (dao *UserDaoImpl) AnonymizeInTransaction(ctx context.Context, userId int, placeholderEmail string, tx *gorm.DB) (bool, error) {
+	ret := tx.WithContext(ctx).Model(&model.User{}).Where("id = ? AND status = ?", userId, model.UserStatusDeleted).Updates(map[string]interface{}{
+		"email":     placeholderEmail,
+		"password":  "",
+		"name":      "",
+		"avatar_id": "",
+	})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to anonymize user: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
 func (dao *UserDaoImpl) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
 	ret := dao.db.WithContext(ctx).Where("email = ?", email).First(&user)
@@ -94,3 +207,36 @@ func (dao *UserDaoImpl) GetUserById(ctx context.Context, id int) (*model.User, e
 	}
 	return &user, nil
 }
+
+// likeEscaper escapes the LIKE wildcards with '!', which unlike a backslash
+// is written the same way in MySQL and SQLite
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// SearchUsers returns a page of the users matching filter, newest first, and
+// the number of matching users.
+func (dao *UserDaoImpl) SearchUsers(ctx context.Context, filter UserSearchFilter, offset, limit int) ([]*model.User, int64, error) {
+	query := dao.db.WithContext(ctx).Model(&model.User{})
+	if filter.EmailPrefix != "" {
+		query = query.Where("email LIKE ? ESCAPE '!'", likeEscaper.Replace(filter.EmailPrefix)+"%")
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Logger.Errorf("Failed to count users: %v", err)
+		return nil, 0, err
+	}
+	var users []*model.User
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		log.Logger.Errorf("Failed to search users: %v", err)
+		return nil, 0, err
+	}
+	return users, total, nil
+}
@@ -0,0 +1,122 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserDataExportDao interface {
+	Create(ctx context.Context, export *model.UserDataExport) error
+	GetLatestByUserId(ctx context.Context, userId int) (*model.UserDataExport, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.UserDataExport, error)
+	Complete(ctx context.Context, id int64, archive []byte, completedAt, expiresAt time.Time) error
+	MarkFailed(ctx context.Context, id int64, completedAt time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type UserDataExportDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userDataExportOnce sync.Once
+	userDataExportDao  *UserDataExportDaoImpl
+)
+
+func GetUserDataExportDao() *UserDataExportDaoImpl {
+	userDataExportOnce.Do(func() {
+		if userDataExportDao == nil {
+			userDataExportDao = &UserDataExportDaoImpl{db: repository.DB}
+		}
+	})
+	return userDataExportDao
+}
+
+func (dao *UserDataExportDaoImpl) Create(ctx context.Context, export *model.UserDataExport) error {
+	ret := dao.db.WithContext(ctx).Create(export)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create data export: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// GetLatestByUserId returns the newest export of the user without its
+// archive.
+func (dao *UserDataExportDaoImpl) GetLatestByUserId(ctx context.Context, userId int) (*model.UserDataExport, error) {
+	var export model.UserDataExport
+	ret := dao.db.WithContext(ctx).Omit("archive").Where("user_id = ?", userId).Order("id desc").First(&export)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get latest data export: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &export, nil
+}
+
+func (dao *UserDataExportDaoImpl) GetByTokenHash(ctx context.Context, tokenHash string) (*model.UserDataExport, error) {
+	var export model.UserDataExport
+	ret := dao.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&export)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get data export by token: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &export, nil
+}
+
+func (dao *UserDataExportDaoImpl) Complete(ctx context.Context, id int64, archive []byte, completedAt, expiresAt time.Time) error {
+	ret := dao.db.WithContext(ctx).Model(&model.UserDataExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.DataExportStatusReady,
+		"archive":      archive,
+		"completed_at": completedAt,
+		"expires_at":   expiresAt,
+	})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to complete data export: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *UserDataExportDaoImpl) MarkFailed(ctx context.Context, id int64, completedAt time.Time) error {
+	ret := dao.db.WithContext(ctx).Model(&model.UserDataExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.DataExportStatusFailed,
+		"completed_at": completedAt,
+	})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to mark data export failed: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *UserDataExportDaoImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.UserDataExport{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired data exports: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
+
+func (dao *UserDataExportDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserDataExport{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete user data exports: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserDeletionDao interface {
+	CreateInTransaction(ctx context.Context, deletion *model.UserDeletion, tx *gorm.DB) error
+	GetByCancelHash(ctx context.Context, cancelHash string) (*model.UserDeletion, error)
+	ListDue(ctx context.Context, before time.Time, limit int) ([]*model.UserDeletion, error)
+	DeleteInTransaction(ctx context.Context, userId int, tx *gorm.DB) (bool, error)
+}
+
+type UserDeletionDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userDeletionOnce sync.Once
+	userDeletionDao  *UserDeletionDaoImpl
+)
+
+func GetUserDeletionDao() *UserDeletionDaoImpl {
+	userDeletionOnce.Do(func() {
+		if userDeletionDao == nil {
+			userDeletionDao = &UserDeletionDaoImpl{db: repository.DB}
+		}
+	})
+	return userDeletionDao
+}
+
+func (dao *UserDeletionDaoImpl) CreateInTransaction(ctx context.Context, deletion *model.UserDeletion, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Create(deletion)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create user deletion: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *UserDeletionDaoImpl) GetByCancelHash(ctx context.Context, cancelHash string) (*model.UserDeletion, error) {
+	var deletion model.UserDeletion
+	ret := dao.db.WithContext(ctx).Where("cancel_hash = ?", cancelHash).First(&deletion)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get user deletion by cancel link: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &deletion, nil
+}
+
+// ListDue returns up to limit deletions whose grace period ended before the
+// given time, oldest first.
+func (dao *UserDeletionDaoImpl) ListDue(ctx context.Context, before time.Time, limit int) ([]*model.UserDeletion, error) {
+	var deletions []*model.UserDeletion
+	ret := dao.db.WithContext(ctx).Where("purge_at < ?", before).Order("purge_at asc").Limit(limit).Find(&deletions)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list due user deletions: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return deletions, nil
+}
+
+func (dao *UserDeletionDaoImpl) DeleteInTransaction(ctx context.Context, userId int, tx *gorm.DB) (bool, error) {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserDeletion{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete user deletion: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
@@ -0,0 +1,136 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserEmailChangeDao interface {
+	Replace(ctx context.Context, change *model.UserEmailChange) error
+	GetPendingByUserId(ctx context.Context, userId int) (*model.UserEmailChange, error)
+	GetByRevertHash(ctx context.Context, revertHash string) (*model.UserEmailChange, error)
+	IncrementAttempts(ctx context.Context, id int64) error
+	ConfirmInTransaction(ctx context.Context, id int64, revertHash string, confirmedAt, expiresAt time.Time, tx *gorm.DB) (bool, error)
+	DeleteInTransaction(ctx context.Context, id int64, tx *gorm.DB) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type UserEmailChangeDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userEmailChangeOnce sync.Once
+	userEmailChangeDao  *UserEmailChangeDaoImpl
+)
+
+func GetUserEmailChangeDao() *UserEmailChangeDaoImpl {
+	userEmailChangeOnce.Do(func() {
+		if userEmailChangeDao == nil {
+			userEmailChangeDao = &UserEmailChangeDaoImpl{db: repository.DB}
+		}
+	})
+	return userEmailChangeDao
+}
+
+// Replace makes the change the only pending one of its user. Confirmed
+// changes are kept so their revert links keep working.
+func (dao *UserEmailChangeDaoImpl) Replace(ctx context.Context, change *model.UserEmailChange) error {
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND confirmed_at IS NULL", change.UserID).Delete(&model.UserEmailChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to replace email change: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (dao *UserEmailChangeDaoImpl) GetPendingByUserId(ctx context.Context, userId int) (*model.UserEmailChange, error) {
+	var change model.UserEmailChange
+	ret := dao.db.WithContext(ctx).Where("user_id = ? AND confirmed_at IS NULL", userId).Order("id desc").First(&change)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get pending email change: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &change, nil
+}
+
+func (dao *UserEmailChangeDaoImpl) GetByRevertHash(ctx context.Context, revertHash string) (*model.UserEmailChange, error) {
+	var change model.UserEmailChange
+	ret := dao.db.WithContext(ctx).Where("revert_hash = ?", revertHash).First(&change)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get email change by revert link: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &change, nil
+}
+
+func (dao *UserEmailChangeDaoImpl) IncrementAttempts(ctx context.Context, id int64) error {
+	ret := dao.db.WithContext(ctx).Model(&model.UserEmailChange{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to increment email change attempts: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// ConfirmInTransaction marks a pending change as confirmed. It returns false
+// if the change was confirmed or replaced concurrently.
+func (dao *UserEmailChangeDaoImpl) ConfirmInTransaction(ctx context.Context, id int64, revertHash string, confirmedAt, expiresAt time.Time, tx *gorm.DB) (bool, error) {
+	ret := tx.WithContext(ctx).Model(&model.UserEmailChange{}).Where("id = ? AND confirmed_at IS NULL", id).Updates(map[string]interface{}{
+		"revert_hash":  revertHash,
+		"confirmed_at": confirmedAt,
+		"expires_at":   expiresAt,
+	})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to confirm email change: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *UserEmailChangeDaoImpl) DeleteInTransaction(ctx context.Context, id int64, tx *gorm.DB) (bool, error) {
+	ret := tx.WithContext(ctx).Where("id = ?", id).Delete(&model.UserEmailChange{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete email change: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *UserEmailChangeDaoImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.UserEmailChange{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired email changes: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
+
+func (dao *UserEmailChangeDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserEmailChange{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete user email changes: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
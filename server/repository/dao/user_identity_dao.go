@@ -0,0 +1,78 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserIdentityDao interface {
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	ListByUserId(ctx context.Context, userId int) ([]*model.UserIdentity, error)
+	CreateInTransaction(ctx context.Context, identity *model.UserIdentity, tx *gorm.DB) error
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type UserIdentityDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userIdentityOnce sync.Once
+	userIdentityDao  *UserIdentityDaoImpl
+)
+
+func GetUserIdentityDao() *UserIdentityDaoImpl {
+	userIdentityOnce.Do(func() {
+		if userIdentityDao == nil {
+			userIdentityDao = &UserIdentityDaoImpl{db: repository.DB}
+		}
+	})
+	return userIdentityDao
+}
+
+func (dao *UserIdentityDaoImpl) GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	ret := dao.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get user identity: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &identity, nil
+}
+
+func (dao *UserIdentityDaoImpl) ListByUserId(ctx context.Context, userId int) ([]*model.UserIdentity, error) {
+	var identities []*model.UserIdentity
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id asc").Find(&identities)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list user identities: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return identities, nil
+}
+
+func (dao *UserIdentityDaoImpl) CreateInTransaction(ctx context.Context, identity *model.UserIdentity, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Create(identity)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create user identity: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *UserIdentityDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserIdentity{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete user identities: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
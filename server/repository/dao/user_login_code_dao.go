@@ -0,0 +1,123 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserLoginCodeDao interface {
+	Replace(ctx context.Context, loginCode *model.UserLoginCode) error
+	GetLatestByUserId(ctx context.Context, userId int) (*model.UserLoginCode, error)
+	GetByLinkHash(ctx context.Context, linkHash string) (*model.UserLoginCode, error)
+	IncrementAttempts(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id int64) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type UserLoginCodeDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userLoginCodeOnce sync.Once
+	userLoginCodeDao  *UserLoginCodeDaoImpl
+)
+
+func GetUserLoginCodeDao() *UserLoginCodeDaoImpl {
+	userLoginCodeOnce.Do(func() {
+		if userLoginCodeDao == nil {
+			userLoginCodeDao = &UserLoginCodeDaoImpl{db: repository.DB}
+		}
+	})
+	return userLoginCodeDao
+}
+
+// Replace makes the login code the only one of its user, so earlier codes
+// and links stop working.
+func (dao *UserLoginCodeDaoImpl) Replace(ctx context.Context, loginCode *model.UserLoginCode) error {
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", loginCode.UserID).Delete(&model.UserLoginCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(loginCode).Error
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to replace login code: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (dao *UserLoginCodeDaoImpl) GetLatestByUserId(ctx context.Context, userId int) (*model.UserLoginCode, error) {
+	var loginCode model.UserLoginCode
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id desc").First(&loginCode)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get login code: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &loginCode, nil
+}
+
+func (dao *UserLoginCodeDaoImpl) GetByLinkHash(ctx context.Context, linkHash string) (*model.UserLoginCode, error) {
+	var loginCode model.UserLoginCode
+	ret := dao.db.WithContext(ctx).Where("link_hash = ?", linkHash).First(&loginCode)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get login code by link: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &loginCode, nil
+}
+
+func (dao *UserLoginCodeDaoImpl) IncrementAttempts(ctx context.Context, id int64) error {
+	ret := dao.db.WithContext(ctx).Model(&model.UserLoginCode{}).
+		Where("id = ?", id).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to record login code attempt: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// Delete consumes the login code. It returns false if it was already
+// consumed, so each code signs in at most once.
+func (dao *UserLoginCodeDaoImpl) Delete(ctx context.Context, id int64) (bool, error) {
+	ret := dao.db.WithContext(ctx).Where("id = ?", id).Delete(&model.UserLoginCode{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete login code: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *UserLoginCodeDaoImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.UserLoginCode{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired login codes: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
+
+func (dao *UserLoginCodeDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserLoginCode{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete user login codes: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserMfaDao interface {
+	GetByUserId(ctx context.Context, userId int) (*model.UserMfa, error)
+	Upsert(ctx context.Context, mfa *model.UserMfa) error
+	EnableInTransaction(ctx context.Context, userId int, step int64, enabledAt time.Time, tx *gorm.DB) error
+	UpdateLastUsedStep(ctx context.Context, userId int, step int64) (bool, error)
+	IncrementEnrollmentAttempts(ctx context.Context, userId int, maxAttempts int) (bool, error)
+	DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type UserMfaDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userMfaOnce sync.Once
+	userMfaDao  *UserMfaDaoImpl
+)
+
+func GetUserMfaDao() *UserMfaDaoImpl {
+	userMfaOnce.Do(func() {
+		if userMfaDao == nil {
+			userMfaDao = &UserMfaDaoImpl{db: repository.DB}
+		}
+	})
+	return userMfaDao
+}
+
+func (dao *UserMfaDaoImpl) GetByUserId(ctx context.Context, userId int) (*model.UserMfa, error) {
+	var mfa model.UserMfa
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).First(&mfa)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get mfa of user %d: %v", userId, ret.Error)
+		return nil, ret.Error
+	}
+	return &mfa, nil
+}
+
+// Upsert stores the user's MFA row, replacing any pending enrollment.
+func (dao *UserMfaDaoImpl) Upsert(ctx context.Context, mfa *model.UserMfa) error {
+	ret := dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "last_used_step", "enrollment_attempts", "enabled", "enabled_at", "created_at"}),
+	}).Create(mfa)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to save mfa of user %d: %v", mfa.UserID, ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *UserMfaDaoImpl) EnableInTransaction(ctx context.Context, userId int, step int64, enabledAt time.Time, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Model(&model.UserMfa{}).
+		Where("user_id = ?", userId).
+		Updates(map[string]interface{}{"enabled": true, "enabled_at": enabledAt, "last_used_step": step})
+	return ret.Error
+}
+
+// UpdateLastUsedStep records an accepted TOTP step. It returns false when the
+// step, or a later one, was already used.
+func (dao *UserMfaDaoImpl) UpdateLastUsedStep(ctx context.Context, userId int, step int64) (bool, error) {
+	ret := dao.db.WithContext(ctx).Model(&model.UserMfa{}).
+		Where("user_id = ? and last_used_step < ?", userId, step).
+		Update("last_used_step", step)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to update mfa step of user %d: %v", userId, ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+// IncrementEnrollmentAttempts uses up one attempt to confirm the pending
+// enrollment of the user. It returns false when there is no pending
+// enrollment or maxAttempts are used already.
+func (dao *UserMfaDaoImpl) IncrementEnrollmentAttempts(ctx context.Context, userId int, maxAttempts int) (bool, error) {
+	ret := dao.db.WithContext(ctx).Model(&model.UserMfa{}).
+		Where("user_id = ? and enabled = ? and enrollment_attempts < ?", userId, false, maxAttempts).
+		UpdateColumn("enrollment_attempts", gorm.Expr("enrollment_attempts + 1"))
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to record mfa enrollment attempt of user %d: %v", userId, ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *UserMfaDaoImpl) DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserMfa{})
+	return ret.Error
+}
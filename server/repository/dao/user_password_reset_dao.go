@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserPasswordResetDao interface {
+	DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error
+	Create(ctx context.Context, reset *model.UserPasswordReset, tx *gorm.DB) error
+	GetLatestByUserId(ctx context.Context, userId int) (*model.UserPasswordReset, error)
+	// IncrementAttempts uses up one attempt of the reset unless maxAttempts
+	// are used already, and reports whether an attempt was left.
+	IncrementAttempts(ctx context.Context, id int64, maxAttempts int) (bool, error)
+}
+
+type UserPasswordResetDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userPasswordResetOnce sync.Once
+	userPasswordResetDao  *UserPasswordResetDaoImpl
+)
+
+func GetUserPasswordResetDao() *UserPasswordResetDaoImpl {
+	userPasswordResetOnce.Do(func() {
+		if userPasswordResetDao == nil {
+			userPasswordResetDao = &UserPasswordResetDaoImpl{db: repository.DB}
+		}
+	})
+	return userPasswordResetDao
+}
+
+func (dao *UserPasswordResetDaoImpl) DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserPasswordReset{})
+	return ret.Error
+}
+
+func (dao *UserPasswordResetDaoImpl) Create(ctx context.Context, reset *model.UserPasswordReset, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Create(reset)
+	return ret.Error
+}
+
+func (dao *UserPasswordResetDaoImpl) GetLatestByUserId(ctx context.Context, userId int) (*model.UserPasswordReset, error) {
+	var reset model.UserPasswordReset
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id desc").First(&reset)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, ret.Error
+	}
+	return &reset, nil
+}
+
+func (dao *UserPasswordResetDaoImpl) IncrementAttempts(ctx context.Context, id int64, maxAttempts int) (bool, error) {
+	ret := dao.db.WithContext(ctx).Model(&model.UserPasswordReset{}).
+		Where("id = ? AND attempts < ?", id, maxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if ret.Error != nil {
+		return false, ret.Error
+	}
+	return ret.RowsAffected == 1, nil
+}
@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserRecoveryCodeDao interface {
+	DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error
+	CreateBatch(ctx context.Context, codes []*model.UserRecoveryCode, tx *gorm.DB) error
+	MarkUsed(ctx context.Context, userId int, codeHash string, usedAt time.Time) (bool, error)
+}
+
+type UserRecoveryCodeDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userRecoveryCodeOnce sync.Once
+	userRecoveryCodeDao  *UserRecoveryCodeDaoImpl
+)
+
+func GetUserRecoveryCodeDao() *UserRecoveryCodeDaoImpl {
+	userRecoveryCodeOnce.Do(func() {
+		if userRecoveryCodeDao == nil {
+			userRecoveryCodeDao = &UserRecoveryCodeDaoImpl{db: repository.DB}
+		}
+	})
+	return userRecoveryCodeDao
+}
+
+func (dao *UserRecoveryCodeDaoImpl) DeleteByUserId(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserRecoveryCode{})
+	return ret.Error
+}
+
+func (dao *UserRecoveryCodeDaoImpl) CreateBatch(ctx context.Context, codes []*model.UserRecoveryCode, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Create(codes)
+	return ret.Error
+}
+
+// MarkUsed consumes an unused recovery code of the user. It returns false when
+// no such code exists.
+func (dao *UserRecoveryCodeDaoImpl) MarkUsed(ctx context.Context, userId int, codeHash string, usedAt time.Time) (bool, error) {
+	ret := dao.db.WithContext(ctx).Model(&model.UserRecoveryCode{}).
+		Where("user_id = ? and code_hash = ? and used_at is null", userId, codeHash).
+		Update("used_at", usedAt)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to use recovery code of user %d: %v", userId, ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
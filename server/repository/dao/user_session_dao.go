@@ -0,0 +1,173 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserSessionDao interface {
+	Create(ctx context.Context, session *model.UserSession) error
+	GetById(ctx context.Context, id int64) (*model.UserSession, error)
+	GetByFamilyId(ctx context.Context, familyID string) (*model.UserSession, error)
+	ListActiveByUserId(ctx context.Context, userId int, now time.Time) ([]*model.UserSession, error)
+	ListByUserId(ctx context.Context, userId int) ([]*model.UserSession, error)
+	UpdateLastSeen(ctx context.Context, id int64, lastSeenAt time.Time) error
+	Extend(ctx context.Context, id int64, lastSeenAt, expiresAt time.Time) error
+	Revoke(ctx context.Context, userId int, id int64, revokedAt time.Time) (bool, error)
+	RevokeByFamilyId(ctx context.Context, familyID string, revokedAt time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error
+}
+
+type UserSessionDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userSessionOnce sync.Once
+	userSessionDao  *UserSessionDaoImpl
+)
+
+func GetUserSessionDao() *UserSessionDaoImpl {
+	userSessionOnce.Do(func() {
+		if userSessionDao == nil {
+			userSessionDao = &UserSessionDaoImpl{db: repository.DB}
+		}
+	})
+	return userSessionDao
+}
+
+func (dao *UserSessionDaoImpl) Create(ctx context.Context, session *model.UserSession) error {
+	ret := dao.db.WithContext(ctx).Create(session)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create user session: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *UserSessionDaoImpl) GetById(ctx context.Context, id int64) (*model.UserSession, error) {
+	var session model.UserSession
+	ret := dao.db.WithContext(ctx).Where("id = ?", id).First(&session)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get user session: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &session, nil
+}
+
+func (dao *UserSessionDaoImpl) GetByFamilyId(ctx context.Context, familyID string) (*model.UserSession, error) {
+	var session model.UserSession
+	ret := dao.db.WithContext(ctx).Where("family_id = ?", familyID).First(&session)
+	if ret.Error != nil {
+		if errors.Is(ret.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Logger.Errorf("Failed to get user session by family: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return &session, nil
+}
+
+// ListActiveByUserId returns the sessions of the user that are neither
+// revoked nor expired, most recently seen first.
+func (dao *UserSessionDaoImpl) ListActiveByUserId(ctx context.Context, userId int, now time.Time) ([]*model.UserSession, error) {
+	var sessions []*model.UserSession
+	ret := dao.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, now).
+		Order("last_seen_at desc").
+		Find(&sessions)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list user sessions: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return sessions, nil
+}
+
+// ListByUserId returns every stored session of the user, ended ones
+// included, newest first.
+func (dao *UserSessionDaoImpl) ListByUserId(ctx context.Context, userId int) ([]*model.UserSession, error) {
+	var sessions []*model.UserSession
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("created_at desc").Find(&sessions)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list user session history: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return sessions, nil
+}
+
+func (dao *UserSessionDaoImpl) UpdateLastSeen(ctx context.Context, id int64, lastSeenAt time.Time) error {
+	ret := dao.db.WithContext(ctx).Model(&model.UserSession{}).
+		Where("id = ?", id).
+		UpdateColumn("last_seen_at", lastSeenAt)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to update user session last seen: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// Extend records a refresh of the session, which lives on as long as the
+// new refresh token.
+func (dao *UserSessionDaoImpl) Extend(ctx context.Context, id int64, lastSeenAt, expiresAt time.Time) error {
+	ret := dao.db.WithContext(ctx).Model(&model.UserSession{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_seen_at": lastSeenAt, "expires_at": expiresAt})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to extend user session: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// Revoke ends a session of the user. It returns false if the user has no
+// such active session.
+func (dao *UserSessionDaoImpl) Revoke(ctx context.Context, userId int, id int64, revokedAt time.Time) (bool, error) {
+	ret := dao.db.WithContext(ctx).Model(&model.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userId).
+		Update("revoked_at", revokedAt)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to revoke user session: %v", ret.Error)
+		return false, ret.Error
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func (dao *UserSessionDaoImpl) RevokeByFamilyId(ctx context.Context, familyID string, revokedAt time.Time) error {
+	ret := dao.db.WithContext(ctx).Model(&model.UserSession{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", revokedAt)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to revoke user session by family: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+func (dao *UserSessionDaoImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ret := dao.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&model.UserSession{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete expired user sessions: %v", ret.Error)
+		return 0, ret.Error
+	}
+	return ret.RowsAffected, nil
+}
+
+func (dao *UserSessionDaoImpl) DeleteByUserIdInTransaction(ctx context.Context, userId int, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Where("user_id = ?", userId).Delete(&model.UserSession{})
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to delete user sessions: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
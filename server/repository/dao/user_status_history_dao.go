@@ -0,0 +1,54 @@
+package dao
+
+import (
+	"context"
+	"sync"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type UserStatusHistoryDao interface {
+	CreateInTransaction(ctx context.Context, entry *model.UserStatusHistory, tx *gorm.DB) error
+	ListByUserId(ctx context.Context, userId int) ([]*model.UserStatusHistory, error)
+}
+
+type UserStatusHistoryDaoImpl struct {
+	db *gorm.DB
+}
+
+var (
+	userStatusHistoryOnce sync.Once
+	userStatusHistoryDao  *UserStatusHistoryDaoImpl
+)
+
+func GetUserStatusHistoryDao() *UserStatusHistoryDaoImpl {
+	userStatusHistoryOnce.Do(func() {
+		if userStatusHistoryDao == nil {
+			userStatusHistoryDao = &UserStatusHistoryDaoImpl{db: repository.DB}
+		}
+	})
+	return userStatusHistoryDao
+}
+
+func (dao *UserStatusHistoryDaoImpl) CreateInTransaction(ctx context.Context, entry *model.UserStatusHistory, tx *gorm.DB) error {
+	ret := tx.WithContext(ctx).Create(entry)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to create user status history: %v", ret.Error)
+		return ret.Error
+	}
+	return nil
+}
+
+// ListByUserId returns the status changes of a user, oldest first.
+func (dao *UserStatusHistoryDaoImpl) ListByUserId(ctx context.Context, userId int) ([]*model.UserStatusHistory, error) {
+	var entries []*model.UserStatusHistory
+	ret := dao.db.WithContext(ctx).Where("user_id = ?", userId).Order("id asc").Find(&entries)
+	if ret.Error != nil {
+		log.Logger.Errorf("Failed to list user status history: %v", ret.Error)
+		return nil, ret.Error
+	}
+	return entries, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
@@ -38,12 +39,90 @@ func Init() {
 	if err != nil {
 		panic(err)
 	}
+	err = dropLegacyColumns(DB)
+	if err != nil {
+		panic(err)
+	}
 	err = DB.AutoMigrate(
 		&model.User{},
 		&model.UserActivation{},
 		&model.UserAddress{},
+		&model.UserPasswordReset{},
+		&model.RevokedToken{},
+		&model.RefreshToken{},
+		&model.RolePermission{},
+		&model.SeededRolePermission{},
+		&model.UserMfa{},
+		&model.UserRecoveryCode{},
+		&model.MfaChallenge{},
+		&model.OidcLoginState{},
+		&model.UserIdentity{},
+		&model.LoginFailure{},
+		&model.RateLimitBucket{},
+		&model.UserLoginCode{},
+		&model.ApiKey{},
+		&model.UserSession{},
+		&model.PasswordHistory{},
+		&model.UserEmailChange{},
+		&model.UserDeletion{},
+		&model.UserDataExport{},
+		&model.UserStatusHistory{},
+		&model.Impersonation{},
+		&model.ImpersonationRequest{},
 	)
 	if err != nil {
 		panic(err)
 	}
+	err = seedRolePermissions(DB)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// dropLegacyColumns removes columns AutoMigrate leaves behind that would
+// break inserts of the current models.
+func dropLegacyColumns(db *gorm.DB) error {
+	// Activation codes used to be stored in plaintext in a unique column;
+	// pending activations are lost and have to be resent
+	if db.Migrator().HasTable(&model.UserActivation{}) && db.Migrator().HasColumn(&model.UserActivation{}, "code") {
+		if err := db.Migrator().DropColumn(&model.UserActivation{}, "code"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedRolePermissions adds the default grants that were never seeded before,
+// e.g. the grants of a role added later. Seeded defaults are recorded in
+// seeded_role_permissions, so a default revoked by hand stays revoked.
+func seedRolePermissions(db *gorm.DB) error {
+	var seeded []model.SeededRolePermission
+	if err := db.Find(&seeded).Error; err != nil {
+		return err
+	}
+	done := make(map[model.SeededRolePermission]bool, len(seeded))
+	for _, seed := range seeded {
+		done[seed] = true
+	}
+	var grants []*model.RolePermission
+	var seeds []*model.SeededRolePermission
+	for role, permissions := range model.DefaultRolePermissions {
+		for _, permission := range permissions {
+			seed := model.SeededRolePermission{Role: role, Permission: permission}
+			if done[seed] {
+				continue
+			}
+			grants = append(grants, &model.RolePermission{Role: role, Permission: permission})
+			seeds = append(seeds, &seed)
+		}
+	}
+	if len(grants) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(grants).Error; err != nil {
+			return err
+		}
+		return tx.Create(seeds).Error
+	})
 }
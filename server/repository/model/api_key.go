@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// ApiKey is a long-lived credential for scripts and integrations. The key is
+// shown once at creation; only its prefix, used for lookup, and a digest of
+// its secret are stored.
+type ApiKey struct {
+	ID         int64      `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID     int        `gorm:"type:int;not null;index"`
+	Name       string     `gorm:"type:varchar(64);not null"`
+	Prefix     string     `gorm:"type:varchar(16);not null;uniqueIndex"`
+	SecretHash string     `gorm:"type:varchar(64);not null"`
+	Scopes     []string   `gorm:"type:varchar(512);not null;serializer:json"`
+	ExpiresAt  *time.Time `gorm:"type:datetime"`
+	LastUsedAt *time.Time `gorm:"type:datetime"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// Impersonation is an access token an admin obtained to act as a user.
+// TokenID is the jti of the token, which ties the requests made with it to
+// the grant.
+type Impersonation struct {
+	ID             int64     `gorm:"primaryKey"`
+	ImpersonatorID int       `gorm:"not null;index"`
+	UserID         int       `gorm:"not null;index"`
+	Audience       string    `gorm:"type:varchar(16);not null"`
+	Reason         string    `gorm:"type:varchar(255);not null"`
+	TokenID        string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt      time.Time `gorm:"type:datetime;not null"`
+	CreatedAt      time.Time `gorm:"type:datetime;not null"`
+}
+
+// TableName sets the insert table name for this struct type
+func (Impersonation) TableName() string {
+	return "impersonations"
+}
+
+// ImpersonationRequest records one request made with an impersonation token.
+// For gRPC calls Method is GRPC, Path the full method name and StatusCode the
+// gRPC status code.
+type ImpersonationRequest struct {
+	ID             int64     `gorm:"primaryKey"`
+	TokenID        string    `gorm:"type:varchar(64);not null;index"`
+	ImpersonatorID int       `gorm:"not null;index"`
+	UserID         int       `gorm:"not null"`
+	Method         string    `gorm:"type:varchar(8);not null"`
+	Path           string    `gorm:"type:varchar(255);not null"`
+	StatusCode     int       `gorm:"type:int;not null"`
+	IP             string    `gorm:"type:varchar(64)"`
+	CreatedAt      time.Time `gorm:"type:datetime;not null"`
+}
+
+// TableName sets the insert table name for this struct type
+func (ImpersonationRequest) TableName() string {
+	return "impersonation_requests"
+}
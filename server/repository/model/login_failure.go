@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// LoginFailure counts recent failed logins of one subject, either an account
+// ("account:<email>") or a source IP ("ip:<address>").
+type LoginFailure struct {
+	ID           int64     `gorm:"type:bigint;primaryKey;autoIncrement"`
+	Subject      string    `gorm:"type:varchar(160);not null;uniqueIndex"`
+	Failures     int       `gorm:"type:int;not null;default:0"`
+	LastFailedAt time.Time `gorm:"type:datetime;not null;index"`
+	BlockedUntil time.Time `gorm:"type:datetime;not null"`
+	// Locked is set once the lockout threshold is reached, as opposed to the
+	// short delays before it
+	Locked    bool      `gorm:"not null;default:false"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (LoginFailure) TableName() string {
+	return "login_failures"
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// MfaChallenge is issued by a password login of a user with 2FA enabled and
+// is exchanged for tokens once the second factor is verified.
+type MfaChallenge struct {
+	ID        int64     `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID    int       `gorm:"type:int;not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Audience  string    `gorm:"type:varchar(16);not null"`
+	Attempts  int       `gorm:"type:int;not null;default:0"`
+	ExpiresAt time.Time `gorm:"type:datetime;not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (MfaChallenge) TableName() string {
+	return "mfa_challenges"
+}
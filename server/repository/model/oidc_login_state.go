@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// OidcLoginState tracks a social login between the redirect to the provider
+// and the callback. It holds the values needed to verify the response.
+type OidcLoginState struct {
+	ID           int64     `gorm:"type:bigint;primaryKey;autoIncrement"`
+	StateHash    string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Provider     string    `gorm:"type:varchar(32);not null"`
+	Nonce        string    `gorm:"type:varchar(64);not null"`
+	CodeVerifier string    `gorm:"type:varchar(128);not null"`
+	ExpiresAt    time.Time `gorm:"type:datetime;not null;index"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (OidcLoginState) TableName() string {
+	return "oidc_login_states"
+}
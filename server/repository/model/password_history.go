@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// PasswordHistory keeps the hashes of the passwords a user has set, newest
+// first, so the password policy can refuse reusing them.
+type PasswordHistory struct {
+	ID           int64     `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID       int       `gorm:"type:int;not null;index"`
+	PasswordHash string    `gorm:"type:varchar(255);not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// RateLimitBucket is a token bucket shared by all service instances.
+type RateLimitBucket struct {
+	BucketKey string  `gorm:"type:varchar(191);primaryKey"`
+	Tokens    float64 `gorm:"not null"`
+	// RefilledAtMs is the unix time in milliseconds up to which Tokens has
+	// been refilled; seconds are too coarse for short periods
+	RefilledAtMs int64 `gorm:"type:bigint;not null"`
+	// FullAt is when the bucket has refilled and can be dropped
+	FullAt time.Time `gorm:"type:datetime;not null;index"`
+}
+
+// TableName sets the insert table name for this struct type
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// RefreshToken is an opaque, single-use refresh token. Every rotation creates
+// a new row in the same family so that replaying an old token can revoke the
+// whole chain.
+type RefreshToken struct {
+	ID                int64      `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID            int        `gorm:"type:int;not null;index"`
+	FamilyID          string     `gorm:"type:varchar(64);not null;index"`
+	Audience          string     `gorm:"type:varchar(16);not null;default:'customer'"`
+	TokenHash         string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	CredentialVersion int        `gorm:"type:int;not null;default:0"`
+	ExpiresAt         time.Time  `gorm:"type:datetime;not null;index"`
+	UsedAt            *time.Time `gorm:"column:used_at"`
+	RevokedAt         *time.Time `gorm:"column:revoked_at"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
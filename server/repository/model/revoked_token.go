@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type RevokedToken struct {
+	ID        int64     `gorm:"type:bigint;primaryKey;autoIncrement"`
+	Jti       string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	UserID    int       `gorm:"type:int;not null"`
+	ExpiresAt time.Time `gorm:"type:datetime;not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
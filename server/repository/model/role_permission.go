@@ -0,0 +1,35 @@
+package model
+
+import "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+
+// RolePermission grants a permission to every user with the role.
+type RolePermission struct {
+	ID         int    `gorm:"primaryKey"`
+	Role       string `gorm:"type:varchar(16);not null;uniqueIndex:idx_role_permission"`
+	Permission string `gorm:"type:varchar(64);not null;uniqueIndex:idx_role_permission"`
+}
+
+// TableName sets the insert table name for this struct type
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// SeededRolePermission records a default grant that was added to
+// role_permissions once, so it is not added again after being revoked.
+type SeededRolePermission struct {
+	Role       string `gorm:"type:varchar(16);primaryKey"`
+	Permission string `gorm:"type:varchar(64);primaryKey"`
+}
+
+// TableName sets the insert table name for this struct type
+func (SeededRolePermission) TableName() string {
+	return "seeded_role_permissions"
+}
+
+// DefaultRolePermissions are added to role_permissions at startup, each one
+// only the first time it is seen.
+var DefaultRolePermissions = map[string][]string{
+	UserRoleCustomer: {utils.PermProfileRead, utils.PermProfileWrite, utils.PermAddressRead, utils.PermAddressWrite},
+	UserRoleMerchant: {utils.PermProfileRead, utils.PermProfileWrite},
+	UserRoleAdmin:    {utils.PermProfileRead, utils.PermProfileWrite, utils.PermUsersAdmin},
+}
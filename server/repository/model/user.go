@@ -4,21 +4,64 @@ import (
 	"time"
 )
 
+// Account statuses. Statuses only change through the transitions allowed by
+// service.UserLifecycle, which records each of them in UserStatusHistory.
 const (
+	// UserStatusInactive accounts are registered but not activated yet
 	UserStatusInactive = -1
 	UserStatusActive   = 1
+	// UserStatusPendingDeletion accounts are anonymized once the grace
+	// period of their UserDeletion ends
+	UserStatusPendingDeletion = 2
+	UserStatusDeleted         = 3
+	// UserStatusLocked accounts are locked for their own protection and
+	// unlocked by resetting the password
+	UserStatusLocked = 4
+	// UserStatusSuspended accounts are blocked by staff until unsuspended
+	UserStatusSuspended = 5
+)
+
+// UserStatusNames are the names statuses are shown with outside the
+// database.
+var UserStatusNames = map[int]string{
+	UserStatusInactive:        "pending",
+	UserStatusActive:          "active",
+	UserStatusPendingDeletion: "pending_deletion",
+	UserStatusDeleted:         "deleted",
+	UserStatusLocked:          "locked",
+	UserStatusSuspended:       "suspended",
+}
+
+// UserStatusByName is the inverse of UserStatusNames.
+func UserStatusByName(name string) (int, bool) {
+	for status, statusName := range UserStatusNames {
+		if statusName == name {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+const (
+	UserRoleCustomer = "customer"
+	UserRoleMerchant = "merchant"
+	// UserRoleAdmin accounts are staff managing other users through the
+	// /admin endpoints
+	UserRoleAdmin = "admin"
 )
 
 type User struct {
-	ID           int        `gorm:"primaryKey"`
-	Email        string     `gorm:"type:varchar(128);unique;not null"`
-	Password     string     `gorm:"type:varchar(255);not null"`
-	Status       int        `gorm:"type:int;not null"`
-	Name         string     `gorm:"type:varchar(64)"`
-	AvatarId     string     `gorm:"type:varchar(64)"`
-	ActivateTime *time.Time `gorm:"column:activate_time"`
-	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime"`
+	ID                int        `gorm:"primaryKey"`
+	Email             string     `gorm:"type:varchar(128);unique;not null"`
+	Password          string     `gorm:"type:varchar(255);not null"`
+	Status            int        `gorm:"type:int;not null"`
+	Role              string     `gorm:"type:varchar(16);not null;default:'customer'"`
+	Name              string     `gorm:"type:varchar(64)"`
+	AvatarId          string     `gorm:"type:varchar(64)"`
+	CredentialVersion int        `gorm:"type:int;not null;default:0"`
+	ActivateTime      *time.Time `gorm:"column:activate_time"`
+	CreatedAt         time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt         time.Time  `gorm:"column:updated_at;autoUpdateTime"`
 }
 
 // TableName sets the insert table name for this struct type
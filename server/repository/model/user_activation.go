@@ -3,11 +3,23 @@ package model
 import "time"
 
 type UserActivation struct {
-	ID        int64     `gorm:"type:bigint;primaryKey;autoIncrement"`
-	UserID    int       `gorm:"type:int;not null;index"`
-	Code      string    `gorm:"type:varchar(8);not null;uniqueIndex"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	ExpiresAt time.Time `gorm:"type:datetime;not null"`
+	ID     int64 `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID int   `gorm:"type:int;not null;index"`
+	// CodeHash is the hashed activation code; the code is only ever sent by
+	// email
+	CodeHash string `gorm:"type:varchar(255);not null"`
+	Attempts int    `gorm:"type:int;not null;default:0"`
+	// PendingPassword is the password hash of a repeated registration of
+	// the pending email. It replaces the user's password only when this
+	// activation is used, so registering someone else's pending email
+	// cannot set the password of their account.
+	PendingPassword string `gorm:"type:varchar(255);not null;default:''"`
+	// SendCount codes were sent since FirstSentAt, carried over when a code
+	// is replaced to enforce the daily cap
+	SendCount   int        `gorm:"type:int;not null;default:1"`
+	FirstSentAt *time.Time `gorm:"type:datetime"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	ExpiresAt   time.Time  `gorm:"type:datetime;not null"`
 }
 
 // TableName sets the insert table name for this struct type
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+const (
+	DataExportStatusPending = "pending"
+	DataExportStatusReady   = "ready"
+	DataExportStatusFailed  = "failed"
+)
+
+// UserDataExport is an archive of everything stored about a user, built in
+// the background. Archive holds the JSON document once Status is ready; it
+// can be downloaded with the token whose digest is TokenHash until ExpiresAt.
+type UserDataExport struct {
+	ID          int64      `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID      int        `gorm:"type:int;not null;index"`
+	Status      string     `gorm:"type:varchar(16);not null"`
+	TokenHash   string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Archive     []byte     `gorm:"type:longblob"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+	ExpiresAt   time.Time  `gorm:"type:datetime;not null;index"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserDataExport) TableName() string {
+	return "user_data_exports"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// UserDeletion is a requested deletion of an account. The user is anonymized
+// once PurgeAt has passed, unless the link whose digest is CancelHash is
+// followed before.
+type UserDeletion struct {
+	UserID      int       `gorm:"primaryKey;autoIncrement:false"`
+	CancelHash  string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	RequestedAt time.Time `gorm:"type:datetime;not null"`
+	PurgeAt     time.Time `gorm:"type:datetime;not null;index"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserDeletion) TableName() string {
+	return "user_deletions"
+}
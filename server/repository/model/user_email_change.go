@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UserEmailChange is a change of a user's login email. It is pending until
+// the code sent to NewEmail is confirmed; after that RevertHash, the digest
+// of the link sent to OldEmail, can undo it until ExpiresAt.
+type UserEmailChange struct {
+	ID       int64  `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID   int    `gorm:"type:int;not null;index"`
+	OldEmail string `gorm:"type:varchar(128);not null"`
+	NewEmail string `gorm:"type:varchar(128);not null"`
+	CodeHash string `gorm:"type:varchar(255);not null"`
+	Attempts int    `gorm:"type:int;not null;default:0"`
+	// RevertHash is set once the change is confirmed
+	RevertHash  *string    `gorm:"type:varchar(64);uniqueIndex"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	ConfirmedAt *time.Time `gorm:"column:confirmed_at"`
+	ExpiresAt   time.Time  `gorm:"type:datetime;not null;index"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserEmailChange) TableName() string {
+	return "user_email_changes"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// UserIdentity links a user to an account at an external OIDC provider.
+type UserIdentity struct {
+	ID        int       `gorm:"primaryKey"`
+	UserID    int       `gorm:"type:int;not null;index"`
+	Provider  string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_provider_subject"`
+	Subject   string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_provider_subject"`
+	Email     string    `gorm:"type:varchar(128)"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
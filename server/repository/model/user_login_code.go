@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// UserLoginCode is a one-time secret for passwordless login. The email
+// carries both a code to type in and a link, either of which consumes it.
+type UserLoginCode struct {
+	ID     int64 `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID int   `gorm:"type:int;not null;index"`
+	// Audience is the client the code was requested for
+	Audience string `gorm:"type:varchar(16);not null"`
+	// CodeHash is the hashed code, LinkHash the digest of the link token;
+	// both are only ever sent by email
+	CodeHash  string    `gorm:"type:varchar(255);not null"`
+	LinkHash  string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Attempts  int       `gorm:"type:int;not null;default:0"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ExpiresAt time.Time `gorm:"type:datetime;not null;index"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserLoginCode) TableName() string {
+	return "user_login_codes"
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UserMfa holds the TOTP secret of a user. It only protects logins once
+// Enabled is set, i.e. after the user proved they can generate codes.
+type UserMfa struct {
+	ID     int    `gorm:"primaryKey"`
+	UserID int    `gorm:"type:int;not null;uniqueIndex"`
+	Secret string `gorm:"type:varchar(64);not null"`
+	// LastUsedStep is the TOTP time step of the last accepted code, so a code
+	// cannot be replayed within its validity window.
+	LastUsedStep int64      `gorm:"type:bigint;not null;default:0"`
+	Enabled      bool       `gorm:"not null;default:false"`
+	EnabledAt    *time.Time `gorm:"column:enabled_at"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+	// EnrollmentAttempts counts the codes tried to confirm a pending
+	// enrollment, so its secret cannot be guessed.
+	EnrollmentAttempts int `gorm:"type:int;not null;default:0"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserMfa) TableName() string {
+	return "user_mfa"
+}
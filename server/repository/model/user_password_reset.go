@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type UserPasswordReset struct {
+	ID     int64 `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID int   `gorm:"type:int;not null;index"`
+	// CodeHash is the hashed reset code; the code is only ever sent by email
+	CodeHash  string    `gorm:"type:varchar(255);not null"`
+	Attempts  int       `gorm:"type:int;not null;default:0"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ExpiresAt time.Time `gorm:"type:datetime;not null"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserPasswordReset) TableName() string {
+	return "user_password_resets"
+}
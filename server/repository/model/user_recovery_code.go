@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// UserRecoveryCode is a single-use code that replaces a TOTP code when the
+// user has lost their authenticator. Only its hash is stored.
+type UserRecoveryCode struct {
+	ID        int64      `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID    int        `gorm:"type:int;not null;index"`
+	CodeHash  string     `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UserSession is one login of a user on a device. It lives as long as the
+// refresh token family started by the login, and revoking it ends both the
+// family and the access tokens issued for it.
+type UserSession struct {
+	ID                int64      `gorm:"type:bigint;primaryKey;autoIncrement"`
+	UserID            int        `gorm:"type:int;not null;index"`
+	FamilyID          string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Audience          string     `gorm:"type:varchar(16);not null"`
+	UserAgent         string     `gorm:"type:varchar(255);not null;default:''"`
+	IP                string     `gorm:"type:varchar(64);not null;default:''"`
+	CredentialVersion int        `gorm:"type:int;not null;default:0"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime"`
+	LastSeenAt        time.Time  `gorm:"type:datetime;not null"`
+	ExpiresAt         time.Time  `gorm:"type:datetime;not null;index"`
+	RevokedAt         *time.Time `gorm:"column:revoked_at"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// UserStatusHistory records one change of User.Status. ActorID is the staff
+// member who made the change, nil when the user or the system did.
+type UserStatusHistory struct {
+	ID         int64     `gorm:"primaryKey"`
+	UserID     int       `gorm:"not null;index"`
+	FromStatus int       `gorm:"type:int;not null"`
+	ToStatus   int       `gorm:"type:int;not null"`
+	Reason     string    `gorm:"type:varchar(255);not null"`
+	ActorID    *int      `gorm:"type:int"`
+	CreatedAt  time.Time `gorm:"type:datetime;not null"`
+}
+
+// TableName sets the insert table name for this struct type
+func (UserStatusHistory) TableName() string {
+	return "user_status_history"
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+// AccountDeletionService closes accounts at the request of their owner. A
+// requested deletion waits for a grace period in which the owner can cancel
+// it; after that the user is anonymized and downstream services are told to
+// scrub their references.
+type AccountDeletionService interface {
+	RequestDeletion(ctx context.Context, userID int, password, code string) (time.Time, error)
+	CancelDeletion(ctx context.Context, token string) error
+	// PruneExpired anonymizes the accounts whose grace period has ended
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type AccountDeletionServiceImpl struct {
+	userDao         dao.UserDao
+	userDeletionDao dao.UserDeletionDao
+	userAddressDao  dao.UserAddressDao
+	identityDao     dao.UserIdentityDao
+	mfaService      MfaService
+	lifecycle       UserLifecycleService
+	emailService    proxy.EmailService
+	txBeginner      repository.TxBeginner
+	kafkaProducer   mq.KafkaProducer
+	gracePeriod     time.Duration
+	// linkBaseURL is the service prefix cancel links point to, the email
+	// goes without a link while it is empty
+	linkBaseURL string
+	// personalData deletes the rest of what is kept about the user once the
+	// account is anonymized
+	personalData []userDataDeleter
+}
+
+var (
+	accountDeletionOnce sync.Once
+	accountDeletionInst *AccountDeletionServiceImpl
+)
+
+const (
+	defaultDeletionGraceDays = 14
+	// accountDeletionBatchSize caps the accounts anonymized per run
+	accountDeletionBatchSize = 100
+)
+
+var ErrInvalidDeletionCancelLink = errors.New("invalid or expired cancel link")
+
+// userDataDeleter deletes the rows of one table that belong to a user.
+type userDataDeleter func(ctx context.Context, userId int, tx *gorm.DB) error
+
+func GetAccountDeletionService() *AccountDeletionServiceImpl {
+	accountDeletionOnce.Do(func() {
+		if accountDeletionInst == nil {
+			accountDeletionInst = &AccountDeletionServiceImpl{
+				userDao:         dao.GetUserDao(),
+				userDeletionDao: dao.GetUserDeletionDao(),
+				userAddressDao:  dao.GetUserAddressDao(),
+				identityDao:     dao.GetUserIdentityDao(),
+				mfaService:      GetMfaService(),
+				lifecycle:       GetUserLifecycleService(),
+				emailService:    proxy.GetEmailInstance(),
+				txBeginner:      repository.DB,
+				kafkaProducer:   mq.GetKafkaProducer(),
+				gracePeriod:     defaultDeletionGraceDays * 24 * time.Hour,
+				personalData: []userDataDeleter{
+					dao.GetUserSessionDao().DeleteByUserIdInTransaction,
+					dao.GetRefreshTokenDao().DeleteByUserIdInTransaction,
+					dao.GetApiKeyDao().DeleteByUserIdInTransaction,
+					dao.GetUserMfaDao().DeleteByUserId,
+					dao.GetUserRecoveryCodeDao().DeleteByUserId,
+					dao.GetMfaChallengeDao().DeleteByUserIdInTransaction,
+					dao.GetPasswordHistoryDao().DeleteByUserIdInTransaction,
+					dao.GetUserEmailChangeDao().DeleteByUserIdInTransaction,
+					dao.GetUserDataExportDao().DeleteByUserIdInTransaction,
+					dao.GetUserLoginCodeDao().DeleteByUserIdInTransaction,
+					dao.GetUserPasswordResetDao().DeleteByUserId,
+					dao.GetUserActivationDao().DeleteByUserId,
+				},
+			}
+			if cfg := config.Config.AccountDeletionConfig; cfg != nil {
+				if cfg.GraceDays > 0 {
+					accountDeletionInst.gracePeriod = time.Duration(cfg.GraceDays) * 24 * time.Hour
+				}
+				accountDeletionInst.linkBaseURL = cfg.LinkBaseURL
+			}
+		}
+	})
+	return accountDeletionInst
+}
+
+// RequestDeletion schedules the deletion of the user after checking the
+// password and, with 2FA enabled, a TOTP or recovery code. Every session is
+// signed out and the user gets an email with a link to cancel. It returns
+// when the account will be anonymized.
+func (ads *AccountDeletionServiceImpl) RequestDeletion(ctx context.Context, userID int, password, code string) (time.Time, error) {
+	user, err := ads.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return time.Time{}, err
+	}
+	if user == nil {
+		return time.Time{}, ErrUserNotFound
+	}
+	if err := CheckAccountStatus(user.Status); err != nil {
+		return time.Time{}, err
+	}
+	if VerifyPassword(user.Password, password) != nil {
+		log.Logger.Warnf("Incorrect password when deleting the account of user %d", userID)
+		return time.Time{}, ErrIncorrectPassword
+	}
+	mfaEnabled, err := ads.mfaService.IsEnabled(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to check 2FA status: %v", err)
+		return time.Time{}, err
+	}
+	if mfaEnabled {
+		if err := ads.mfaService.VerifyCode(ctx, userID, code); err != nil {
+			return time.Time{}, err
+		}
+	}
+	cancelToken, err := generateOpaqueToken()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate cancel link: %v", err)
+		return time.Time{}, err
+	}
+	now := time.Now()
+	purgeAt := now.Add(ads.gracePeriod)
+	err = ads.txBeginner.Transaction(func(tx *gorm.DB) error {
+		err := ads.lifecycle.TransitionInTransaction(ctx, userID, model.UserStatusActive, model.UserStatusPendingDeletion, StatusReasonDeletionRequested, nil, tx)
+		if errors.Is(err, ErrStatusChanged) {
+			return ErrAccountPendingDeletion
+		}
+		if err != nil {
+			return err
+		}
+		return ads.userDeletionDao.CreateInTransaction(ctx, &model.UserDeletion{
+			UserID:      userID,
+			CancelHash:  hashToken(cancelToken),
+			RequestedAt: now,
+			PurgeAt:     purgeAt,
+		}, tx)
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to schedule deletion of user %d: %v", userID, err)
+		return time.Time{}, err
+	}
+	log.Logger.Infof("Deletion of user %d scheduled for %s", userID, purgeAt.Format(time.RFC3339))
+
+	body := fmt.Sprintf("Your CermiCraft account will be deleted on %s and you have been signed out everywhere.", purgeAt.Format("2006-01-02"))
+	if ads.linkBaseURL != "" {
+		body += fmt.Sprintf("<br>Changed your mind? <a href=\"%s\">Keep my account</a>.", DeletionCancelLinkURL(ads.linkBaseURL, cancelToken))
+	}
+	// The deletion is scheduled, a lost email must not turn it into an error
+	if err := ads.emailService.Send(body, user.Email, "CermiCraft Account Deletion"); err != nil {
+		log.Logger.Errorf("Failed to send account deletion email: %v", err)
+	}
+	return purgeAt, nil
+}
+
+// CancelDeletion keeps the account with the link emailed when the deletion
+// was requested. The user signs in again afterwards.
+func (ads *AccountDeletionServiceImpl) CancelDeletion(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidDeletionCancelLink
+	}
+	deletion, err := ads.userDeletionDao.GetByCancelHash(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if deletion == nil || !deletion.PurgeAt.After(time.Now()) {
+		log.Logger.Warn("Deletion cancel link is unknown, used or expired")
+		return ErrInvalidDeletionCancelLink
+	}
+	err = ads.txBeginner.Transaction(func(tx *gorm.DB) error {
+		// The delete decides between the cancel and a concurrent purge
+		cancelled, err := ads.userDeletionDao.DeleteInTransaction(ctx, deletion.UserID, tx)
+		if err != nil {
+			return err
+		}
+		if !cancelled {
+			return ErrInvalidDeletionCancelLink
+		}
+		err = ads.lifecycle.TransitionInTransaction(ctx, deletion.UserID, model.UserStatusPendingDeletion, model.UserStatusActive, StatusReasonDeletionCancelled, nil, tx)
+		if errors.Is(err, ErrStatusChanged) {
+			return ErrInvalidDeletionCancelLink
+		}
+		return err
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to cancel deletion of user %d: %v", deletion.UserID, err)
+		return err
+	}
+	log.Logger.Infof("Deletion of user %d cancelled", deletion.UserID)
+	return nil
+}
+
+func (ads *AccountDeletionServiceImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	due, err := ads.userDeletionDao.ListDue(ctx, now, accountDeletionBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var purged int64
+	for _, deletion := range due {
+		deleted, err := ads.purge(ctx, deletion.UserID, now)
+		if err != nil {
+			// The deletion stays due and is retried on the next run
+			log.Logger.Errorf("Failed to delete user %d: %v", deletion.UserID, err)
+			continue
+		}
+		if deleted {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purge anonymizes the user, hard-deletes the addresses, social login links,
+// sessions, API keys, 2FA, password history, pending codes, email changes and
+// data exports, and publishes a UserDeletedEvent, all or nothing. It returns false
+// if the deletion was cancelled or done by another instance in the meantime.
+func (ads *AccountDeletionServiceImpl) purge(ctx context.Context, userID int, now time.Time) (bool, error) {
+	claimed := false
+	err := ads.txBeginner.Transaction(func(tx *gorm.DB) error {
+		var err error
+		claimed, err = ads.userDeletionDao.DeleteInTransaction(ctx, userID, tx)
+		if err != nil || !claimed {
+			return err
+		}
+		err = ads.lifecycle.TransitionInTransaction(ctx, userID, model.UserStatusPendingDeletion, model.UserStatusDeleted, StatusReasonDeletionCompleted, nil, tx)
+		if err != nil {
+			return err
+		}
+		anonymized, err := ads.userDao.AnonymizeInTransaction(ctx, userID, anonymizedEmail(userID), tx)
+		if err != nil {
+			return err
+		}
+		if !anonymized {
+			return fmt.Errorf("user %d is not deleted", userID)
+		}
+		addresses, err := ads.userAddressDao.DeleteByUserIdInTransaction(ctx, userID, tx)
+		if err != nil {
+			return err
+		}
+		if err := ads.identityDao.DeleteByUserIdInTransaction(ctx, userID, tx); err != nil {
+			return err
+		}
+		for _, deleteUserData := range ads.personalData {
+			if err := deleteUserData(ctx, userID, tx); err != nil {
+				return err
+			}
+		}
+		event := &mq.UserDeletedEvent{UserID: userID, DeleteTime: now.Unix()}
+		err = ads.kafkaProducer.Produce(ctx, config.Config.KafkaConfig.UserDeletedTopic, fmt.Sprintf("%d", userID), event.ToBytes())
+		if err != nil {
+			log.Logger.Errorf("Failed to produce user deleted event: %v", err)
+			return err
+		}
+		log.Logger.Infof("User %d anonymized, %d addresses deleted", userID, addresses)
+		return nil
+	})
+	return claimed && err == nil, err
+}
+
+// anonymizedEmail is the placeholder that replaces the email of a deleted
+// user. The .invalid domain can never receive mail.
+func anonymizedEmail(userID int) string {
+	return fmt.Sprintf("deleted-%d@deleted.invalid", userID)
+}
+
+// DeletionCancelLinkURL builds the link that cancels a deletion. base is the
+// public service prefix, e.g. https://example.com/user-ms/v1
+func DeletionCancelLinkURL(base, token string) string {
+	return fmt.Sprintf("%s/customer/users/deletion/cancel?token=%s", strings.TrimRight(base, "/"), url.QueryEscape(token))
+}
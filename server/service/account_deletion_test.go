@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	mq_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq/mocks"
+	proxy_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy/mocks"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type accountDeletionMocks struct {
+	userDao         *dao_mock.UserDao
+	userDeletionDao *dao_mock.UserDeletionDao
+	userAddressDao  *dao_mock.UserAddressDao
+	identityDao     *dao_mock.UserIdentityDao
+	userMfaDao      *dao_mock.UserMfaDao
+	recoveryCodeDao *dao_mock.UserRecoveryCodeDao
+	emailSender     *proxy_mock.EmailService
+	kafkaProducer   *mq_mock.KafkaProducer
+}
+
+func newAccountDeletionService(t *testing.T) (*AccountDeletionServiceImpl, *accountDeletionMocks) {
+	m := &accountDeletionMocks{
+		userDao:         new(dao_mock.UserDao),
+		userDeletionDao: new(dao_mock.UserDeletionDao),
+		userAddressDao:  new(dao_mock.UserAddressDao),
+		identityDao:     new(dao_mock.UserIdentityDao),
+		userMfaDao:      new(dao_mock.UserMfaDao),
+		recoveryCodeDao: new(dao_mock.UserRecoveryCodeDao),
+		emailSender:     new(proxy_mock.EmailService),
+		kafkaProducer:   new(mq_mock.KafkaProducer),
+	}
+	service := &AccountDeletionServiceImpl{
+		userDao:         m.userDao,
+		userDeletionDao: m.userDeletionDao,
+		userAddressDao:  m.userAddressDao,
+		identityDao:     m.identityDao,
+		mfaService:      &MfaServiceImpl{userMfaDao: m.userMfaDao, recoveryCodeDao: m.recoveryCodeDao},
+		lifecycle:       newUserLifecycle(m.userDao, m.kafkaProducer),
+		emailService:    m.emailSender,
+		txBeginner:      &fakeTx{DB: initMemDb(t)},
+		kafkaProducer:   m.kafkaProducer,
+		gracePeriod:     14 * 24 * time.Hour,
+		linkBaseURL:     "https://shop.example.com/user-ms/v1",
+	}
+	return service, m
+}
+
+func TestRequestAccountDeletion(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	hashedPwd, _ := HashPassword("password1")
+	activeUser := func() *model.User {
+		return &model.User{ID: 1, Email: "buyer@example.com", Password: hashedPwd, Status: model.UserStatusActive, Role: model.UserRoleCustomer}
+	}
+
+	t.Run("Wrong password", func(t *testing.T) {
+		service, m := newAccountDeletionService(t)
+		m.userDao.On("GetUserById", mock.Anything, 1).Return(activeUser(), nil)
+
+		_, err := service.RequestDeletion(ctx, 1, "wrong", "")
+		assert.ErrorIs(t, err, ErrIncorrectPassword)
+		m.userDeletionDao.AssertNotCalled(t, "CreateInTransaction", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Users with 2FA need a code", func(t *testing.T) {
+		service, m := newAccountDeletionService(t)
+		m.userDao.On("GetUserById", mock.Anything, 1).Return(activeUser(), nil)
+		m.userMfaDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserMfa{UserID: 1, Secret: "JBSWY3DPEHPK3PXP", Enabled: true}, nil)
+		m.recoveryCodeDao.On("MarkUsed", mock.Anything, 1, mock.Anything, mock.Anything).Return(false, nil)
+
+		_, err := service.RequestDeletion(ctx, 1, "password1", "not-a-code")
+		assert.ErrorIs(t, err, ErrInvalidMfaCode)
+		m.userDao.AssertNotCalled(t, "UpdateStatusInTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already scheduled", func(t *testing.T) {
+		service, m := newAccountDeletionService(t)
+		pending := activeUser()
+		pending.Status = model.UserStatusPendingDeletion
+		m.userDao.On("GetUserById", mock.Anything, 1).Return(pending, nil)
+
+		_, err := service.RequestDeletion(ctx, 1, "password1", "")
+		assert.ErrorIs(t, err, ErrAccountPendingDeletion)
+	})
+
+	t.Run("Schedules the deletion and emails a cancel link", func(t *testing.T) {
+		service, m := newAccountDeletionService(t)
+		m.userDao.On("GetUserById", mock.Anything, 1).Return(activeUser(), nil)
+		m.userMfaDao.On("GetByUserId", mock.Anything, 1).Return(nil, nil)
+		// Every session is signed out
+		m.userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusActive, model.UserStatusPendingDeletion, true, mock.Anything).Return(true, nil)
+		m.kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.Anything).Return(nil)
+		var stored *model.UserDeletion
+		m.userDeletionDao.On("CreateInTransaction", mock.Anything, mock.MatchedBy(func(arg *model.UserDeletion) bool {
+			stored = arg
+			return arg.UserID == 1
+		}), mock.Anything).Return(nil)
+		var token string
+		m.emailSender.On("Send", mock.MatchedBy(func(body string) bool {
+			match := regexp.MustCompile(`<a href="https://shop\.example\.com/user-ms/v1/customer/users/deletion/cancel\?token=([^"]+)"`).FindStringSubmatch(body)
+			if match == nil {
+				return false
+			}
+			token, _ = url.QueryUnescape(match[1])
+			return true
+		}), "buyer@example.com", mock.Anything).Return(nil)
+
+		deleteTime, err := service.RequestDeletion(ctx, 1, "password1", "")
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(14*24*time.Hour), deleteTime, time.Minute)
+		assert.Equal(t, deleteTime, stored.PurgeAt)
+		assert.Equal(t, hashToken(token), stored.CancelHash)
+		m.userDao.AssertExpectations(t)
+		m.emailSender.AssertExpectations(t)
+	})
+}
+
+func TestCancelAccountDeletion(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+
+	t.Run("Keeps the account", func(t *testing.T) {
+		service, m := newAccountDeletionService(t)
+		m.userDeletionDao.On("GetByCancelHash", mock.Anything, hashToken("cancel-token")).Return(&model.UserDeletion{UserID: 1, PurgeAt: time.Now().Add(time.Hour)}, nil)
+		m.userDeletionDao.On("DeleteInTransaction", mock.Anything, 1, mock.Anything).Return(true, nil)
+		m.userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusPendingDeletion, model.UserStatusActive, false, mock.Anything).Return(true, nil)
+		m.kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.Anything).Return(nil)
+
+		assert.NoError(t, service.CancelDeletion(ctx, "cancel-token"))
+		m.userDeletionDao.AssertExpectations(t)
+		m.userDao.AssertExpectations(t)
+	})
+
+	t.Run("Unknown or expired links", func(t *testing.T) {
+		service, m := newAccountDeletionService(t)
+		m.userDeletionDao.On("GetByCancelHash", mock.Anything, hashToken("unknown")).Return(nil, nil)
+		m.userDeletionDao.On("GetByCancelHash", mock.Anything, hashToken("expired")).Return(&model.UserDeletion{UserID: 1, PurgeAt: time.Now().Add(-time.Minute)}, nil)
+
+		for _, token := range []string{"", "unknown", "expired"} {
+			assert.ErrorIs(t, service.CancelDeletion(ctx, token), ErrInvalidDeletionCancelLink, token)
+		}
+		m.userDeletionDao.AssertNotCalled(t, "DeleteInTransaction", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	initEnv()
+	config.Config.KafkaConfig.UserDeletedTopic = "user_deleted"
+	ctx := context.Background()
+	now := time.Now()
+
+	service, m := newAccountDeletionService(t)
+	m.userDeletionDao.On("ListDue", mock.Anything, now, accountDeletionBatchSize).Return([]*model.UserDeletion{{UserID: 1}, {UserID: 2}}, nil)
+	m.userDeletionDao.On("DeleteInTransaction", mock.Anything, 1, mock.Anything).Return(true, nil)
+	// User 2 cancelled in the meantime
+	m.userDeletionDao.On("DeleteInTransaction", mock.Anything, 2, mock.Anything).Return(false, nil)
+	m.userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusPendingDeletion, model.UserStatusDeleted, false, mock.Anything).Return(true, nil)
+	m.kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.Anything).Return(nil).Once()
+	m.userDao.On("AnonymizeInTransaction", mock.Anything, 1, "deleted-1@deleted.invalid", mock.Anything).Return(true, nil)
+	m.userAddressDao.On("DeleteByUserIdInTransaction", mock.Anything, 1, mock.Anything).Return(int64(2), nil)
+	m.identityDao.On("DeleteByUserIdInTransaction", mock.Anything, 1, mock.Anything).Return(nil)
+	m.kafkaProducer.On("Produce", mock.Anything, "user_deleted", "1", mock.Anything).Return(nil).Once()
+	// Everything else kept about the user goes as well
+	sessionDao := new(dao_mock.UserSessionDao)
+	refreshTokenDao := new(dao_mock.RefreshTokenDao)
+	apiKeyDao := new(dao_mock.ApiKeyDao)
+	challengeDao := new(dao_mock.MfaChallengeDao)
+	historyDao := new(dao_mock.PasswordHistoryDao)
+	emailChangeDao := new(dao_mock.UserEmailChangeDao)
+	exportDao := new(dao_mock.UserDataExportDao)
+	loginCodeDao := new(dao_mock.UserLoginCodeDao)
+	resetDao := new(dao_mock.UserPasswordResetDao)
+	activationDao := new(dao_mock.UserActivationDao)
+	inTransaction := []*mock.Mock{
+		&sessionDao.Mock, &refreshTokenDao.Mock, &apiKeyDao.Mock, &challengeDao.Mock,
+		&historyDao.Mock, &emailChangeDao.Mock, &exportDao.Mock, &loginCodeDao.Mock,
+	}
+	for _, dao := range inTransaction {
+		dao.On("DeleteByUserIdInTransaction", mock.Anything, 1, mock.Anything).Return(nil).Once()
+	}
+	byUserId := []*mock.Mock{&m.userMfaDao.Mock, &m.recoveryCodeDao.Mock, &resetDao.Mock, &activationDao.Mock}
+	for _, dao := range byUserId {
+		dao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil).Once()
+	}
+	service.personalData = []userDataDeleter{
+		sessionDao.DeleteByUserIdInTransaction,
+		refreshTokenDao.DeleteByUserIdInTransaction,
+		apiKeyDao.DeleteByUserIdInTransaction,
+		m.userMfaDao.DeleteByUserId,
+		m.recoveryCodeDao.DeleteByUserId,
+		challengeDao.DeleteByUserIdInTransaction,
+		historyDao.DeleteByUserIdInTransaction,
+		emailChangeDao.DeleteByUserIdInTransaction,
+		exportDao.DeleteByUserIdInTransaction,
+		loginCodeDao.DeleteByUserIdInTransaction,
+		resetDao.DeleteByUserId,
+		activationDao.DeleteByUserId,
+	}
+
+	purged, err := service.PruneExpired(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	m.userDao.AssertNumberOfCalls(t, "AnonymizeInTransaction", 1)
+	m.userAddressDao.AssertExpectations(t)
+	m.identityDao.AssertExpectations(t)
+	m.kafkaProducer.AssertExpectations(t)
+	for _, dao := range append(inTransaction, byUserId...) {
+		dao.AssertExpectations(t)
+	}
+}
+
+func TestPurgeDeletedAccountsAllOrNothing(t *testing.T) {
+	initEnv()
+	config.Config.KafkaConfig.UserDeletedTopic = "user_deleted"
+	ctx := context.Background()
+	now := time.Now()
+
+	service, m := newAccountDeletionService(t)
+	m.userDeletionDao.On("ListDue", mock.Anything, now, accountDeletionBatchSize).Return([]*model.UserDeletion{{UserID: 1}}, nil)
+	m.userDeletionDao.On("DeleteInTransaction", mock.Anything, 1, mock.Anything).Return(true, nil)
+	m.userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusPendingDeletion, model.UserStatusDeleted, false, mock.Anything).Return(true, nil)
+	m.kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.Anything).Return(nil)
+	m.userDao.On("AnonymizeInTransaction", mock.Anything, 1, "deleted-1@deleted.invalid", mock.Anything).Return(true, nil)
+	m.userAddressDao.On("DeleteByUserIdInTransaction", mock.Anything, 1, mock.Anything).Return(int64(0), nil)
+	m.identityDao.On("DeleteByUserIdInTransaction", mock.Anything, 1, mock.Anything).Return(nil)
+	exportDao := new(dao_mock.UserDataExportDao)
+	exportDao.On("DeleteByUserIdInTransaction", mock.Anything, 1, mock.Anything).Return(errors.New("db down"))
+	service.personalData = []userDataDeleter{exportDao.DeleteByUserIdInTransaction}
+
+	// The deletion stays due and the event is not published
+	purged, err := service.PruneExpired(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+	m.kafkaProducer.AssertNotCalled(t, "Produce", mock.Anything, "user_deleted", mock.Anything, mock.Anything)
+}
+
+func TestLoginPendingDeletion(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	hashedPwd, _ := HashPassword("password1")
+	userDao := new(dao_mock.UserDao)
+	userDao.On("GetUserByEmail", mock.Anything, "buyer@example.com").Return(&model.User{
+		ID: 1, Email: "buyer@example.com", Password: hashedPwd, Status: model.UserStatusPendingDeletion, Role: model.UserRoleCustomer,
+	}, nil)
+	loginService := &LoginServiceImpl{userDao: userDao, loginGuard: permissiveLoginGuard()}
+
+	_, err := loginService.Login(ctx, utils.AudienceCustomer, "buyer@example.com", "password1", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrAccountPendingDeletion)
+}
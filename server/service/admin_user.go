@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// AdminUserService lets staff find users and act on their accounts. Every
+// status change is made through the UserLifecycleService with the admin as
+// the actor.
+type AdminUserService interface {
+	SearchUsers(ctx context.Context, filter dao.UserSearchFilter, page, pageSize int) ([]*model.User, int64, error)
+	GetUserDetail(ctx context.Context, userID int) (*AdminUserDetail, error)
+	Suspend(ctx context.Context, adminID, userID int, reason string) error
+	Unsuspend(ctx context.Context, adminID, userID int) error
+	// ForcePasswordReset locks the account, which signs the user out, and
+	// emails a reset code. Resetting the password unlocks it again.
+	ForcePasswordReset(ctx context.Context, adminID, userID int) error
+	ResendActivation(ctx context.Context, adminID, userID int) error
+	Activate(ctx context.Context, adminID, userID int) error
+}
+
+// AdminUserDetail is a user with everything staff see about it.
+type AdminUserDetail struct {
+	User          *model.User
+	Addresses     []*data.UserAddressVO
+	StatusHistory []*model.UserStatusHistory
+}
+
+type AdminUserServiceImpl struct {
+	userDao              dao.UserDao
+	userAddressService   UserAddressService
+	lifecycle            UserLifecycleService
+	passwordResetService PasswordResetService
+	registerService      RegisterService
+}
+
+var (
+	adminUserOnce sync.Once
+	adminUserInst *AdminUserServiceImpl
+)
+
+const (
+	AdminUserDefaultPageSize = 20
+	AdminUserMaxPageSize     = 100
+)
+
+var (
+	ErrCannotManageSelf = errors.New("admins cannot manage their own account")
+	ErrUserNotPending   = errors.New("account is not pending activation")
+)
+
+func GetAdminUserService() *AdminUserServiceImpl {
+	adminUserOnce.Do(func() {
+		if adminUserInst == nil {
+			adminUserInst = &AdminUserServiceImpl{
+				userDao:              dao.GetUserDao(),
+				userAddressService:   GetUserAddressService(),
+				lifecycle:            GetUserLifecycleService(),
+				passwordResetService: GetPasswordResetService(),
+				registerService:      GetRegisterService(),
+			}
+		}
+	})
+	return adminUserInst
+}
+
+// SearchUsers returns the given page of the users matching filter, newest
+// first, and the number of matching users. Pages start at 1.
+func (as *AdminUserServiceImpl) SearchUsers(ctx context.Context, filter dao.UserSearchFilter, page, pageSize int) ([]*model.User, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > AdminUserMaxPageSize {
+		pageSize = AdminUserDefaultPageSize
+	}
+	return as.userDao.SearchUsers(ctx, filter, (page-1)*pageSize, pageSize)
+}
+
+func (as *AdminUserServiceImpl) GetUserDetail(ctx context.Context, userID int) (*AdminUserDetail, error) {
+	user, err := as.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := as.userAddressService.GetUserAddresses(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get addresses of user %d: %v", userID, err)
+		return nil, err
+	}
+	history, err := as.lifecycle.History(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get status history of user %d: %v", userID, err)
+		return nil, err
+	}
+	return &AdminUserDetail{User: user, Addresses: addresses, StatusHistory: history}, nil
+}
+
+// Suspend blocks the account until it is unsuspended. The user is signed
+// out if the account was active.
+func (as *AdminUserServiceImpl) Suspend(ctx context.Context, adminID, userID int, reason string) error {
+	user, err := as.getManagedUser(ctx, adminID, userID)
+	if err != nil {
+		return err
+	}
+	err = as.lifecycle.Transition(ctx, userID, user.Status, model.UserStatusSuspended, reason, &adminID)
+	if err != nil {
+		return err
+	}
+	log.Logger.Infof("User %d suspended by admin %d", userID, adminID)
+	return nil
+}
+
+// Unsuspend returns a suspended account to the status it was suspended from,
+// so a pending account still needs activating and a locked one a password
+// reset.
+func (as *AdminUserServiceImpl) Unsuspend(ctx context.Context, adminID, userID int) error {
+	user, err := as.getManagedUser(ctx, adminID, userID)
+	if err != nil {
+		return err
+	}
+	if user.Status != model.UserStatusSuspended {
+		return ErrInvalidStatusTransition
+	}
+	restored, err := as.statusBeforeSuspension(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = as.lifecycle.Transition(ctx, userID, model.UserStatusSuspended, restored, StatusReasonUnsuspended, &adminID)
+	if err != nil {
+		return err
+	}
+	log.Logger.Infof("User %d unsuspended by admin %d, now %s", userID, adminID, model.UserStatusNames[restored])
+	return nil
+}
+
+// statusBeforeSuspension reads the status of the latest suspension from the
+// status history. Accounts suspended without a history entry become active.
+func (as *AdminUserServiceImpl) statusBeforeSuspension(ctx context.Context, userID int) (int, error) {
+	history, err := as.lifecycle.History(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get status history of user %d: %v", userID, err)
+		return 0, err
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].ToStatus == model.UserStatusSuspended {
+			return history[i].FromStatus, nil
+		}
+	}
+	return model.UserStatusActive, nil
+}
+
+func (as *AdminUserServiceImpl) ForcePasswordReset(ctx context.Context, adminID, userID int) error {
+	user, err := as.getManagedUser(ctx, adminID, userID)
+	if err != nil {
+		return err
+	}
+	switch user.Status {
+	case model.UserStatusActive:
+		err = as.lifecycle.Transition(ctx, userID, model.UserStatusActive, model.UserStatusLocked, StatusReasonPasswordResetForced, &adminID)
+		if err != nil {
+			return err
+		}
+	case model.UserStatusLocked:
+		// Already waiting for a reset, only the code is sent again
+	default:
+		return ErrInvalidStatusTransition
+	}
+	if err := as.passwordResetService.RequestReset(ctx, user.Email); err != nil {
+		return err
+	}
+	log.Logger.Infof("Password reset of user %d forced by admin %d", userID, adminID)
+	return nil
+}
+
+// ResendActivation sends a fresh activation code to a pending account. The
+// cooldown and daily cap of the public endpoint apply.
+func (as *AdminUserServiceImpl) ResendActivation(ctx context.Context, adminID, userID int) error {
+	user, err := as.getManagedUser(ctx, adminID, userID)
+	if err != nil {
+		return err
+	}
+	if user.Status != model.UserStatusInactive {
+		return ErrUserNotPending
+	}
+	if err := as.registerService.ResendActivation(ctx, user.Email); err != nil {
+		return err
+	}
+	log.Logger.Infof("Activation of user %d resent by admin %d", userID, adminID)
+	return nil
+}
+
+// Activate activates a pending account without its code.
+func (as *AdminUserServiceImpl) Activate(ctx context.Context, adminID, userID int) error {
+	user, err := as.getManagedUser(ctx, adminID, userID)
+	if err != nil {
+		return err
+	}
+	if user.Status != model.UserStatusInactive {
+		return ErrUserNotPending
+	}
+	return as.registerService.ActivateUser(ctx, userID, adminID)
+}
+
+func (as *AdminUserServiceImpl) getUser(ctx context.Context, userID int) (*model.User, error) {
+	user, err := as.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// getManagedUser returns the user an admin acts on. Admins cannot act on
+// their own account, changes to it are left to another admin.
+func (as *AdminUserServiceImpl) getManagedUser(ctx context.Context, adminID, userID int) (*model.User, error) {
+	if adminID == userID {
+		return nil, ErrCannotManageSelf
+	}
+	return as.getUser(ctx, userID)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	mq_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq/mocks"
+	proxy_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdminUserService(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	adminID := 7
+	newService := func(t *testing.T) (*AdminUserServiceImpl, *dao_mock.UserDao, *dao_mock.UserStatusHistoryDao, *mq_mock.KafkaProducer) {
+		userDao := new(dao_mock.UserDao)
+		historyDao := new(dao_mock.UserStatusHistoryDao)
+		kafkaProducer := new(mq_mock.KafkaProducer)
+		lifecycle := &UserLifecycleServiceImpl{
+			userDao:          userDao,
+			statusHistoryDao: historyDao,
+			txBeginner:       &fakeTx{DB: initMemDb(t)},
+			kafkaProducer:    kafkaProducer,
+		}
+		return &AdminUserServiceImpl{userDao: userDao, lifecycle: lifecycle}, userDao, historyDao, kafkaProducer
+	}
+	recordedBy := func(to int, reason string) interface{} {
+		return mock.MatchedBy(func(arg *model.UserStatusHistory) bool {
+			return arg.ToStatus == to && arg.Reason == reason && arg.ActorID != nil && *arg.ActorID == adminID
+		})
+	}
+
+	t.Run("Search pages through the users", func(t *testing.T) {
+		service, userDao, _, _ := newService(t)
+		status := model.UserStatusSuspended
+		filter := dao.UserSearchFilter{EmailPrefix: "buyer", Status: &status}
+		userDao.On("SearchUsers", mock.Anything, filter, 2*AdminUserDefaultPageSize, AdminUserDefaultPageSize).
+			Return([]*model.User{{ID: 1}}, int64(41), nil)
+
+		users, total, err := service.SearchUsers(ctx, filter, 3, 0)
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, int64(41), total)
+	})
+
+	t.Run("Detail includes addresses and status history", func(t *testing.T) {
+		service, userDao, historyDao, _ := newService(t)
+		addressDao := new(dao_mock.UserAddressDao)
+		service.userAddressService = &UserAddressServiceImpl{userAddressDao: addressDao}
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive}, nil)
+		addressDao.On("GetUserAddresses", mock.Anything, 1).Return([]*model.UserAddress{{ID: 3, UserID: 1, City: "Singapore"}}, nil)
+		historyDao.On("ListByUserId", mock.Anything, 1).Return([]*model.UserStatusHistory{
+			{UserID: 1, FromStatus: model.UserStatusInactive, ToStatus: model.UserStatusActive, Reason: StatusReasonActivated},
+		}, nil)
+
+		detail, err := service.GetUserDetail(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, detail.User.ID)
+		assert.Equal(t, "Singapore", detail.Addresses[0].City)
+		assert.Len(t, detail.StatusHistory, 1)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		service, userDao, _, _ := newService(t)
+		userDao.On("GetUserById", mock.Anything, 1).Return(nil, nil)
+
+		assert.ErrorIs(t, service.Suspend(ctx, adminID, 1, "fraud"), ErrUserNotFound)
+	})
+
+	t.Run("Admins cannot manage their own account", func(t *testing.T) {
+		service, userDao, _, _ := newService(t)
+
+		assert.ErrorIs(t, service.Suspend(ctx, adminID, adminID, "fraud"), ErrCannotManageSelf)
+		assert.ErrorIs(t, service.ForcePasswordReset(ctx, adminID, adminID), ErrCannotManageSelf)
+		userDao.AssertNotCalled(t, "GetUserById", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Suspend and unsuspend record the admin", func(t *testing.T) {
+		service, userDao, historyDao, kafkaProducer := newService(t)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive}, nil).Once()
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusActive, model.UserStatusSuspended, true, mock.Anything).Return(true, nil)
+		historyDao.On("CreateInTransaction", mock.Anything, recordedBy(model.UserStatusSuspended, "chargeback fraud"), mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.Anything).Return(nil)
+
+		assert.NoError(t, service.Suspend(ctx, adminID, 1, "chargeback fraud"))
+		// Suspending again is refused
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusSuspended}, nil)
+		assert.ErrorIs(t, service.Suspend(ctx, adminID, 1, "chargeback fraud"), ErrInvalidStatusTransition)
+
+		historyDao.On("ListByUserId", mock.Anything, 1).Return([]*model.UserStatusHistory{
+			{UserID: 1, FromStatus: model.UserStatusInactive, ToStatus: model.UserStatusActive},
+			{UserID: 1, FromStatus: model.UserStatusActive, ToStatus: model.UserStatusSuspended},
+		}, nil)
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusSuspended, model.UserStatusActive, false, mock.Anything).Return(true, nil)
+		historyDao.On("CreateInTransaction", mock.Anything, recordedBy(model.UserStatusActive, StatusReasonUnsuspended), mock.Anything).Return(nil)
+		assert.NoError(t, service.Unsuspend(ctx, adminID, 1))
+		userDao.AssertExpectations(t)
+		historyDao.AssertExpectations(t)
+	})
+
+	t.Run("Unsuspend restores the status before the suspension", func(t *testing.T) {
+		histories := map[int][]*model.UserStatusHistory{
+			model.UserStatusInactive: {
+				{UserID: 1, FromStatus: model.UserStatusInactive, ToStatus: model.UserStatusSuspended},
+			},
+			// Only the latest suspension counts
+			model.UserStatusLocked: {
+				{UserID: 1, FromStatus: model.UserStatusActive, ToStatus: model.UserStatusSuspended},
+				{UserID: 1, FromStatus: model.UserStatusSuspended, ToStatus: model.UserStatusActive},
+				{UserID: 1, FromStatus: model.UserStatusActive, ToStatus: model.UserStatusLocked},
+				{UserID: 1, FromStatus: model.UserStatusLocked, ToStatus: model.UserStatusSuspended},
+			},
+		}
+		for from, history := range histories {
+			service, userDao, historyDao, kafkaProducer := newService(t)
+			userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusSuspended}, nil)
+			historyDao.On("ListByUserId", mock.Anything, 1).Return(history, nil)
+			userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusSuspended, from, false, mock.Anything).Return(true, nil)
+			historyDao.On("CreateInTransaction", mock.Anything, recordedBy(from, StatusReasonUnsuspended), mock.Anything).Return(nil)
+			kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.Anything).Return(nil)
+
+			assert.NoError(t, service.Unsuspend(ctx, adminID, 1))
+			userDao.AssertExpectations(t)
+			// Not an activation, so no UserActivated event
+			kafkaProducer.AssertNotCalled(t, "Produce", mock.Anything, "user_activated", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("Only suspended accounts are unsuspended", func(t *testing.T) {
+		service, userDao, _, _ := newService(t)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusLocked}, nil)
+
+		assert.ErrorIs(t, service.Unsuspend(ctx, adminID, 1), ErrInvalidStatusTransition)
+		userDao.AssertNotCalled(t, "UpdateStatusInTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forced password reset locks the account and sends a code", func(t *testing.T) {
+		service, userDao, historyDao, kafkaProducer := newService(t)
+		resetDao := new(dao_mock.UserPasswordResetDao)
+		emailSender := new(proxy_mock.EmailService)
+		service.passwordResetService = &PasswordResetServiceImpl{
+			userDao:          userDao,
+			passwordResetDao: resetDao,
+			emailService:     emailSender,
+			txBeginner:       &fakeTx{DB: initMemDb(t)},
+		}
+		email := "buyer@example.com"
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Email: email, Status: model.UserStatusActive}, nil)
+		// Locking signs the user out
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusActive, model.UserStatusLocked, true, mock.Anything).Return(true, nil)
+		historyDao.On("CreateInTransaction", mock.Anything, recordedBy(model.UserStatusLocked, StatusReasonPasswordResetForced), mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.Anything).Return(nil)
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(&model.User{ID: 1, Email: email, Status: model.UserStatusLocked}, nil)
+		resetDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
+		resetDao.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		emailSender.On("Send", mock.Anything, email, mock.Anything).Return(nil)
+
+		assert.NoError(t, service.ForcePasswordReset(ctx, adminID, 1))
+		userDao.AssertExpectations(t)
+		emailSender.AssertExpectations(t)
+	})
+
+	t.Run("Manual activation", func(t *testing.T) {
+		service, userDao, historyDao, kafkaProducer := newService(t)
+		activationDao := new(dao_mock.UserActivationDao)
+		service.registerService = &RegisterImpl{
+			userDao:        userDao,
+			userActivation: activationDao,
+			txBeginner:     &fakeTx{DB: initMemDb(t)},
+			kafkaProducer:  kafkaProducer,
+			lifecycle:      service.lifecycle,
+		}
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusInactive}, nil)
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusInactive, model.UserStatusActive, false, mock.Anything).Return(true, nil)
+		historyDao.On("CreateInTransaction", mock.Anything, recordedBy(model.UserStatusActive, StatusReasonActivatedByAdmin), mock.Anything).Return(nil)
+		userDao.On("UpdateUserInTransaction", mock.Anything, mock.MatchedBy(func(arg *model.User) bool {
+			return arg.ID == 1 && arg.ActivateTime != nil
+		}), mock.Anything).Return(nil)
+		activationDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, mock.Anything, "1", mock.Anything).Return(nil)
+
+		assert.NoError(t, service.Activate(ctx, adminID, 1))
+		userDao.AssertExpectations(t)
+		activationDao.AssertExpectations(t)
+		kafkaProducer.AssertCalled(t, "Produce", mock.Anything, "user_activated", "1", mock.Anything)
+	})
+
+	t.Run("Activation actions need a pending account", func(t *testing.T) {
+		service, userDao, _, _ := newService(t)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive}, nil)
+
+		assert.ErrorIs(t, service.Activate(ctx, adminID, 1), ErrUserNotPending)
+		assert.ErrorIs(t, service.ResendActivation(ctx, adminID, 1), ErrUserNotPending)
+	})
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// CreatedApiKey holds a new key. Key is the full secret and is only ever
+// returned here.
+type CreatedApiKey struct {
+	Key    string
+	ApiKey *model.ApiKey
+}
+
+type ApiKeyService interface {
+	Create(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*CreatedApiKey, error)
+	List(ctx context.Context, userID int) ([]*model.ApiKey, error)
+	Update(ctx context.Context, userID int, id int64, name string, scopes []string) (*model.ApiKey, error)
+	Delete(ctx context.Context, userID int, id int64) error
+	Validate(ctx context.Context, key string) (*utils.Claims, error)
+}
+
+type ApiKeyServiceImpl struct {
+	apiKeyDao         dao.ApiKeyDao
+	userDao           dao.UserDao
+	rolePermissionDao dao.RolePermissionDao
+}
+
+var (
+	apiKeyServiceOnce sync.Once
+	apiKeyServiceInst *ApiKeyServiceImpl
+)
+
+const (
+	// apiKeyTag starts every key so leaked keys are easy to scan for
+	apiKeyTag          = "cck_"
+	apiKeyPrefixBytes  = 6
+	maxApiKeysPerUser  = 10
+	apiKeyLastUsedStep = time.Minute
+)
+
+var (
+	ErrApiKeyNotFound     = errors.New("api key not found")
+	ErrInvalidApiKey      = errors.New("invalid or expired api key")
+	ErrApiKeyLimit        = fmt.Errorf("at most %d api keys are allowed per user", maxApiKeysPerUser)
+	ErrInvalidApiKeyScope = errors.New("api key scopes must be permissions of the owner")
+)
+
+func GetApiKeyService() *ApiKeyServiceImpl {
+	apiKeyServiceOnce.Do(func() {
+		if apiKeyServiceInst == nil {
+			apiKeyServiceInst = &ApiKeyServiceImpl{
+				apiKeyDao:         dao.GetApiKeyDao(),
+				userDao:           dao.GetUserDao(),
+				rolePermissionDao: dao.GetRolePermissionDao(),
+			}
+		}
+	})
+	return apiKeyServiceInst
+}
+
+// Create issues a key for the user limited to scopes, which must be
+// permissions of the user's role. A nil expiresAt creates a key that does
+// not expire.
+func (as *ApiKeyServiceImpl) Create(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*CreatedApiKey, error) {
+	user, err := as.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := as.checkScopes(ctx, user.Role, scopes); err != nil {
+		return nil, err
+	}
+	count, err := as.apiKeyDao.CountByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxApiKeysPerUser {
+		return nil, ErrApiKeyLimit
+	}
+	prefixBytes := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, err
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := &model.ApiKey{
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		SecretHash: hashToken(secret),
+		Scopes:     slices.Compact(slices.Sorted(slices.Values(scopes))),
+		ExpiresAt:  expiresAt,
+	}
+	if err := as.apiKeyDao.Create(ctx, apiKey); err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("Api key %s created for user %d with scopes %v", prefix, userID, apiKey.Scopes)
+	return &CreatedApiKey{Key: apiKeyTag + prefix + "_" + secret, ApiKey: apiKey}, nil
+}
+
+func (as *ApiKeyServiceImpl) List(ctx context.Context, userID int) ([]*model.ApiKey, error) {
+	return as.apiKeyDao.ListByUserId(ctx, userID)
+}
+
+// Update renames the key and replaces its scopes.
+func (as *ApiKeyServiceImpl) Update(ctx context.Context, userID int, id int64, name string, scopes []string) (*model.ApiKey, error) {
+	apiKey, err := as.apiKeyDao.GetByUserIdAndId(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrApiKeyNotFound
+	}
+	user, err := as.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := as.checkScopes(ctx, user.Role, scopes); err != nil {
+		return nil, err
+	}
+	apiKey.Name = name
+	apiKey.Scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+	if err := as.apiKeyDao.Update(ctx, apiKey); err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("Api key %s of user %d updated with scopes %v", apiKey.Prefix, userID, apiKey.Scopes)
+	return apiKey, nil
+}
+
+// Delete revokes the key immediately.
+func (as *ApiKeyServiceImpl) Delete(ctx context.Context, userID int, id int64) error {
+	deleted, err := as.apiKeyDao.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrApiKeyNotFound
+	}
+	log.Logger.Infof("Api key %d of user %d deleted", id, userID)
+	return nil
+}
+
+// Validate resolves a key to claims of its owner. The permissions are the
+// scopes of the key that the owner's role still grants, and the audience is
+// the client of the owner's role. It is registered with
+// utils.RegisterApiKeyValidator at startup.
+func (as *ApiKeyServiceImpl) Validate(ctx context.Context, key string) (*utils.Claims, error) {
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(key, apiKeyTag), "_")
+	if !ok || !strings.HasPrefix(key, apiKeyTag) {
+		return nil, ErrInvalidApiKey
+	}
+	apiKey, err := as.apiKeyDao.GetByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil || subtle.ConstantTimeCompare([]byte(apiKey.SecretHash), []byte(hashToken(secret))) != 1 {
+		log.Logger.Warnf("Unknown api key with prefix %q", prefix)
+		return nil, ErrInvalidApiKey
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		log.Logger.Warnf("Expired api key %s of user %d", apiKey.Prefix, apiKey.UserID)
+		return nil, ErrInvalidApiKey
+	}
+	user, err := as.userDao.GetUserById(ctx, apiKey.UserID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		log.Logger.Warnf("Api key %s of missing user %d", apiKey.Prefix, apiKey.UserID)
+		return nil, ErrInvalidApiKey
+	}
+	if err := CheckAccountStatus(user.Status); err != nil {
+		log.Logger.Warnf("Api key %s of user %d refused: %v", apiKey.Prefix, apiKey.UserID, err)
+		return nil, err
+	}
+	client := clientForRole(user.Role)
+	if client == "" {
+		return nil, ErrInvalidApiKey
+	}
+	granted, err := as.rolePermissionDao.GetPermissionsByRole(ctx, user.Role)
+	if err != nil {
+		log.Logger.Errorf("Failed to get role permissions: %v", err)
+		return nil, err
+	}
+	permissions := make([]string, 0, len(apiKey.Scopes))
+	for _, scope := range apiKey.Scopes {
+		if slices.Contains(granted, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+	// Recording every request would turn reads into writes, a coarse
+	// timestamp is all owners need
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedStep {
+		if err := as.apiKeyDao.UpdateLastUsed(ctx, apiKey.ID, now); err != nil {
+			log.Logger.Errorf("Failed to record api key use: %v", err)
+		}
+	}
+	return &utils.Claims{
+		ID:                user.ID,
+		Role:              user.Role,
+		Permissions:       permissions,
+		CredentialVersion: user.CredentialVersion,
+		ApiKeyID:          apiKey.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience: jwt.ClaimStrings{client},
+		},
+	}, nil
+}
+
+func (as *ApiKeyServiceImpl) checkScopes(ctx context.Context, role string, scopes []string) error {
+	granted, err := as.rolePermissionDao.GetPermissionsByRole(ctx, role)
+	if err != nil {
+		log.Logger.Errorf("Failed to get role permissions: %v", err)
+		return err
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return fmt.Errorf("%w: %s", ErrInvalidApiKeyScope, scope)
+		}
+	}
+	return nil
+}
+
+// clientForRole returns the client a role signs in to, or "" if none.
+func clientForRole(role string) string {
+	for client, roles := range clientRoles {
+		if slices.Contains(roles, role) {
+			return client
+		}
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestApiKeyCreate(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	merchant := &model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleMerchant}
+
+	t.Run("Key is returned once and stored as a digest", func(t *testing.T) {
+		apiKeyDao := new(dao_mock.ApiKeyDao)
+		userDao := new(dao_mock.UserDao)
+		service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao, userDao: userDao, rolePermissionDao: rolePermissionDaoWithDefaults()}
+		userDao.On("GetUserById", mock.Anything, 1).Return(merchant, nil)
+		apiKeyDao.On("CountByUserId", mock.Anything, 1).Return(int64(0), nil)
+		var stored *model.ApiKey
+		apiKeyDao.On("Create", mock.Anything, mock.MatchedBy(func(arg *model.ApiKey) bool {
+			stored = arg
+			return true
+		})).Return(nil)
+
+		created, err := service.Create(ctx, 1, "nightly sync", []string{utils.PermProfileRead, utils.PermProfileRead}, nil)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(created.Key, "cck_"+stored.Prefix+"_"))
+		secret := strings.TrimPrefix(created.Key, "cck_"+stored.Prefix+"_")
+		assert.Equal(t, hashToken(secret), stored.SecretHash)
+		assert.NotContains(t, stored.SecretHash, secret)
+		assert.Equal(t, []string{utils.PermProfileRead}, stored.Scopes)
+		assert.Nil(t, stored.ExpiresAt)
+	})
+
+	t.Run("Scopes beyond the owner's role are rejected", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		apiKeyDao := new(dao_mock.ApiKeyDao)
+		service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao, userDao: userDao, rolePermissionDao: rolePermissionDaoWithDefaults()}
+		userDao.On("GetUserById", mock.Anything, 1).Return(merchant, nil)
+
+		_, err := service.Create(ctx, 1, "admin", []string{utils.PermUsersAdmin}, nil)
+		assert.ErrorIs(t, err, ErrInvalidApiKeyScope)
+		apiKeyDao.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Keys per user are capped", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		apiKeyDao := new(dao_mock.ApiKeyDao)
+		service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao, userDao: userDao, rolePermissionDao: rolePermissionDaoWithDefaults()}
+		userDao.On("GetUserById", mock.Anything, 1).Return(merchant, nil)
+		apiKeyDao.On("CountByUserId", mock.Anything, 1).Return(int64(maxApiKeysPerUser), nil)
+
+		_, err := service.Create(ctx, 1, "one too many", []string{utils.PermProfileRead}, nil)
+		assert.ErrorIs(t, err, ErrApiKeyLimit)
+	})
+}
+
+func TestApiKeyValidate(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	secret := "secret-part"
+	key := "cck_abcdef012345_" + secret
+	storedKey := func() *model.ApiKey {
+		return &model.ApiKey{
+			ID: 3, UserID: 1, Prefix: "abcdef012345", SecretHash: hashToken(secret),
+			Scopes: []string{utils.PermAddressRead, utils.PermUsersAdmin},
+		}
+	}
+	customer := &model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleCustomer, CredentialVersion: 4}
+
+	t.Run("Valid key yields scoped claims of the owner", func(t *testing.T) {
+		apiKeyDao := new(dao_mock.ApiKeyDao)
+		userDao := new(dao_mock.UserDao)
+		service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao, userDao: userDao, rolePermissionDao: rolePermissionDaoWithDefaults()}
+		apiKeyDao.On("GetByPrefix", mock.Anything, "abcdef012345").Return(storedKey(), nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(customer, nil)
+		apiKeyDao.On("UpdateLastUsed", mock.Anything, int64(3), mock.Anything).Return(nil)
+
+		claims, err := service.Validate(ctx, key)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, claims.ID)
+		assert.True(t, claims.IsApiKey())
+		assert.True(t, claims.HasAudience(utils.AudienceCustomer))
+		// users:admin was never granted to customers, so the scope is dropped
+		assert.Equal(t, []string{utils.PermAddressRead}, claims.Permissions)
+		apiKeyDao.AssertCalled(t, "UpdateLastUsed", mock.Anything, int64(3), mock.Anything)
+	})
+
+	t.Run("Recent use is not recorded again", func(t *testing.T) {
+		apiKeyDao := new(dao_mock.ApiKeyDao)
+		userDao := new(dao_mock.UserDao)
+		service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao, userDao: userDao, rolePermissionDao: rolePermissionDaoWithDefaults()}
+		recent := storedKey()
+		lastUsed := time.Now().Add(-10 * time.Second)
+		recent.LastUsedAt = &lastUsed
+		apiKeyDao.On("GetByPrefix", mock.Anything, "abcdef012345").Return(recent, nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(customer, nil)
+
+		_, err := service.Validate(ctx, key)
+		assert.NoError(t, err)
+		apiKeyDao.AssertNotCalled(t, "UpdateLastUsed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Wrong secret or expired key", func(t *testing.T) {
+		expired := storedKey()
+		past := time.Now().Add(-time.Minute)
+		expired.ExpiresAt = &past
+		cases := map[string]struct {
+			key   string
+			found *model.ApiKey
+			owner *model.User
+		}{
+			"wrong secret":   {"cck_abcdef012345_other", storedKey(), customer},
+			"unknown prefix": {key, nil, customer},
+			"expired":        {key, expired, customer},
+		}
+		for name, tc := range cases {
+			apiKeyDao := new(dao_mock.ApiKeyDao)
+			userDao := new(dao_mock.UserDao)
+			service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao, userDao: userDao, rolePermissionDao: rolePermissionDaoWithDefaults()}
+			apiKeyDao.On("GetByPrefix", mock.Anything, "abcdef012345").Return(tc.found, nil)
+			userDao.On("GetUserById", mock.Anything, 1).Return(tc.owner, nil)
+			_, err := service.Validate(ctx, tc.key)
+			assert.ErrorIs(t, err, ErrInvalidApiKey, name)
+		}
+	})
+
+	t.Run("Keys of suspended owners are refused with the reason", func(t *testing.T) {
+		apiKeyDao := new(dao_mock.ApiKeyDao)
+		userDao := new(dao_mock.UserDao)
+		service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao, userDao: userDao, rolePermissionDao: rolePermissionDaoWithDefaults()}
+		apiKeyDao.On("GetByPrefix", mock.Anything, "abcdef012345").Return(storedKey(), nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusSuspended, Role: model.UserRoleCustomer}, nil)
+
+		_, err := service.Validate(ctx, key)
+		assert.ErrorIs(t, err, ErrAccountSuspended)
+	})
+
+	t.Run("Malformed keys are rejected without a lookup", func(t *testing.T) {
+		apiKeyDao := new(dao_mock.ApiKeyDao)
+		service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao}
+		for _, malformed := range []string{"", "abcdef012345_" + secret, "cck_nosecret"} {
+			_, err := service.Validate(ctx, malformed)
+			assert.ErrorIs(t, err, ErrInvalidApiKey, malformed)
+		}
+		apiKeyDao.AssertNotCalled(t, "GetByPrefix", mock.Anything, mock.Anything)
+	})
+}
+
+func TestApiKeyDelete(t *testing.T) {
+	initEnv()
+	apiKeyDao := new(dao_mock.ApiKeyDao)
+	service := &ApiKeyServiceImpl{apiKeyDao: apiKeyDao}
+	apiKeyDao.On("Delete", mock.Anything, 1, int64(3)).Return(true, nil)
+	apiKeyDao.On("Delete", mock.Anything, 2, int64(3)).Return(false, nil)
+
+	assert.NoError(t, service.Delete(context.Background(), 1, 3))
+	// Keys of other users look like missing keys
+	assert.ErrorIs(t, service.Delete(context.Background(), 2, 3), ErrApiKeyNotFound)
+}
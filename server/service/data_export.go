@@ -0,0 +1,434 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// DataExportService answers subject access requests: it collects everything
+// stored about a user into a JSON document in the background and hands it
+// out with an expiring download token.
+type DataExportService interface {
+	// RequestExport starts an export and returns it with its download token,
+	// which is only returned here
+	RequestExport(ctx context.Context, userID int) (*model.UserDataExport, string, error)
+	GetLatest(ctx context.Context, userID int) (*model.UserDataExport, error)
+	Download(ctx context.Context, token string) (*model.UserDataExport, error)
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type DataExportServiceImpl struct {
+	userDao          dao.UserDao
+	userAddressDao   dao.UserAddressDao
+	sessionDao       dao.UserSessionDao
+	identityDao      dao.UserIdentityDao
+	apiKeyDao        dao.ApiKeyDao
+	userMfaDao       dao.UserMfaDao
+	statusHistoryDao dao.UserStatusHistoryDao
+	impersonationDao dao.ImpersonationDao
+	exportDao        dao.UserDataExportDao
+	emailService     proxy.EmailService
+	downloadTTL      time.Duration
+	// linkBaseURL is the service prefix download links point to, the ready
+	// email goes without links while it is empty
+	linkBaseURL string
+	// async runs the generation of an export outside of the request
+	async func(task func())
+}
+
+var (
+	dataExportOnce sync.Once
+	dataExportInst *DataExportServiceImpl
+)
+
+const (
+	defaultDataExportDownloadTTL = 24 * time.Hour
+	// dataExportGenerationTimeout is how long a pending export blocks new
+	// requests; one that is still pending after that was lost
+	dataExportGenerationTimeout = time.Hour
+	dataExportFileName          = "personal-data.json"
+)
+
+var (
+	ErrDataExportInProgress   = errors.New("a data export is already being prepared")
+	ErrDataExportNotFound     = errors.New("no data export was requested")
+	ErrDataExportNotReady     = errors.New("data export is not ready yet")
+	ErrInvalidDataExportToken = errors.New("invalid or expired download token")
+)
+
+func GetDataExportService() *DataExportServiceImpl {
+	dataExportOnce.Do(func() {
+		if dataExportInst == nil {
+			dataExportInst = &DataExportServiceImpl{
+				userDao:          dao.GetUserDao(),
+				userAddressDao:   dao.GetUserAddressDao(),
+				sessionDao:       dao.GetUserSessionDao(),
+				identityDao:      dao.GetUserIdentityDao(),
+				apiKeyDao:        dao.GetApiKeyDao(),
+				userMfaDao:       dao.GetUserMfaDao(),
+				statusHistoryDao: dao.GetUserStatusHistoryDao(),
+				impersonationDao: dao.GetImpersonationDao(),
+				exportDao:        dao.GetUserDataExportDao(),
+				emailService:     proxy.GetEmailInstance(),
+				downloadTTL:      defaultDataExportDownloadTTL,
+				async:            func(task func()) { go task() },
+			}
+			if cfg := config.Config.DataExportConfig; cfg != nil {
+				if cfg.DownloadTTLHours > 0 {
+					dataExportInst.downloadTTL = time.Duration(cfg.DownloadTTLHours) * time.Hour
+				}
+				dataExportInst.linkBaseURL = cfg.LinkBaseURL
+			}
+		}
+	})
+	return dataExportInst
+}
+
+func (des *DataExportServiceImpl) RequestExport(ctx context.Context, userID int) (*model.UserDataExport, string, error) {
+	latest, err := des.exportDao.GetLatestByUserId(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	if latest != nil && latest.Status == model.DataExportStatusPending && latest.CreatedAt.Add(dataExportGenerationTimeout).After(now) {
+		return nil, "", ErrDataExportInProgress
+	}
+	token, err := generateOpaqueToken()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate download token: %v", err)
+		return nil, "", err
+	}
+	export := &model.UserDataExport{
+		UserID:    userID,
+		Status:    model.DataExportStatusPending,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(dataExportGenerationTimeout + des.downloadTTL),
+	}
+	if err := des.exportDao.Create(ctx, export); err != nil {
+		return nil, "", err
+	}
+	log.Logger.Infof("Data export %d requested by user %d", export.ID, userID)
+	des.async(func() {
+		des.generate(context.Background(), export, token)
+	})
+	return export, token, nil
+}
+
+func (des *DataExportServiceImpl) GetLatest(ctx context.Context, userID int) (*model.UserDataExport, error) {
+	export, err := des.exportDao.GetLatestByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, ErrDataExportNotFound
+	}
+	return export, nil
+}
+
+// Download returns the export the token belongs to, with its archive. The
+// token can be used any number of times until the export expires.
+func (des *DataExportServiceImpl) Download(ctx context.Context, token string) (*model.UserDataExport, error) {
+	if token == "" {
+		return nil, ErrInvalidDataExportToken
+	}
+	export, err := des.exportDao.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if export == nil || !export.ExpiresAt.After(time.Now()) || export.Status == model.DataExportStatusFailed {
+		log.Logger.Warn("Download token is unknown, expired or of a failed export")
+		return nil, ErrInvalidDataExportToken
+	}
+	if export.Status != model.DataExportStatusReady {
+		return nil, ErrDataExportNotReady
+	}
+	return export, nil
+}
+
+func (des *DataExportServiceImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return des.exportDao.DeleteExpired(ctx, now)
+}
+
+// generate builds and stores the archive of an export, then tells the user
+// it is ready.
+func (des *DataExportServiceImpl) generate(ctx context.Context, export *model.UserDataExport, token string) {
+	document, err := des.collect(ctx, export.UserID)
+	var archive []byte
+	if err == nil {
+		archive, err = json.MarshalIndent(document, "", "  ")
+	}
+	now := time.Now()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate data export %d: %v", export.ID, err)
+		if err := des.exportDao.MarkFailed(ctx, export.ID, now); err != nil {
+			log.Logger.Errorf("Failed to mark data export %d failed: %v", export.ID, err)
+		}
+		return
+	}
+	expiresAt := now.Add(des.downloadTTL)
+	if err := des.exportDao.Complete(ctx, export.ID, archive, now, expiresAt); err != nil {
+		log.Logger.Errorf("Failed to store data export %d: %v", export.ID, err)
+		return
+	}
+	log.Logger.Infof("Data export %d of user %d is ready, %d bytes", export.ID, export.UserID, len(archive))
+
+	body := "The export of your CermiCraft data is ready."
+	if des.linkBaseURL != "" {
+		client := clientForRole(document.Profile.Role)
+		body += fmt.Sprintf("<br>Download it <a href=\"%s\">as JSON</a> or <a href=\"%s\">as ZIP</a> until %s.",
+			DataExportDownloadURL(des.linkBaseURL, client, token, "json"),
+			DataExportDownloadURL(des.linkBaseURL, client, token, "zip"),
+			expiresAt.Format("2006-01-02 15:04 MST"))
+	}
+	if err := des.emailService.Send(body, document.Profile.Email, "CermiCraft Data Export"); err != nil {
+		log.Logger.Errorf("Failed to send data export email: %v", err)
+	}
+}
+
+// DataExportDocument is the content of an export. Credentials are left out,
+// only the facts about them are included.
+type DataExportDocument struct {
+	GeneratedAt      time.Time                 `json:"generated_at"`
+	Profile          DataExportProfile         `json:"profile"`
+	Addresses        []DataExportAddress       `json:"addresses"`
+	Sessions         []DataExportSession       `json:"sessions"`
+	SocialLogins     []DataExportIdentity      `json:"social_logins"`
+	ApiKeys          []DataExportApiKey        `json:"api_keys"`
+	TwoFactorEnabled bool                      `json:"two_factor_enabled"`
+	StatusHistory    []DataExportStatusChange  `json:"status_history"`
+	Impersonations   []DataExportImpersonation `json:"impersonations"`
+}
+
+type DataExportProfile struct {
+	ID           int        `json:"id"`
+	Email        string     `json:"email"`
+	Name         string     `json:"name"`
+	AvatarId     string     `json:"avatar_id"`
+	Role         string     `json:"role"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ActivateTime *time.Time `json:"activate_time,omitempty"`
+}
+
+type DataExportAddress struct {
+	ID           int        `json:"id"`
+	FirstName    string     `json:"first_name"`
+	LastName     string     `json:"last_name"`
+	ContactPhone string     `json:"contact_phone"`
+	Country      string     `json:"country"`
+	Province     string     `json:"province"`
+	City         string     `json:"city"`
+	Detail       string     `json:"detail"`
+	ZipCode      string     `json:"zip_code"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+// DataExportSession is one login, kept until its refresh token expires.
+type DataExportSession struct {
+	Client     string     `json:"client"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type DataExportIdentity struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DataExportApiKey struct {
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// DataExportStatusChange is a change of the account status. ByStaff tells
+// whether support staff made it; who did is left out.
+type DataExportStatusChange struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason"`
+	ByStaff    bool      `json:"by_staff"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// DataExportImpersonation is a time support staff acted as the user, with
+// the requests made meanwhile. The staff member is left out.
+type DataExportImpersonation struct {
+	Client    string                          `json:"client"`
+	Reason    string                          `json:"reason"`
+	StartedAt time.Time                       `json:"started_at"`
+	ExpiresAt time.Time                       `json:"expires_at"`
+	Requests  []DataExportImpersonatedRequest `json:"requests"`
+}
+
+type DataExportImpersonatedRequest struct {
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	StatusCode int       `json:"status_code"`
+	At         time.Time `json:"at"`
+}
+
+func (des *DataExportServiceImpl) collect(ctx context.Context, userID int) (*DataExportDocument, error) {
+	user, err := des.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	document := &DataExportDocument{
+		GeneratedAt: time.Now(),
+		Profile: DataExportProfile{
+			ID:           user.ID,
+			Email:        user.Email,
+			Name:         user.Name,
+			AvatarId:     user.AvatarId,
+			Role:         user.Role,
+			Status:       model.UserStatusNames[user.Status],
+			CreatedAt:    user.CreatedAt,
+			ActivateTime: user.ActivateTime,
+		},
+		Addresses:      []DataExportAddress{},
+		Sessions:       []DataExportSession{},
+		SocialLogins:   []DataExportIdentity{},
+		ApiKeys:        []DataExportApiKey{},
+		StatusHistory:  []DataExportStatusChange{},
+		Impersonations: []DataExportImpersonation{},
+	}
+	addresses, err := des.userAddressDao.ListAllByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range addresses {
+		item := DataExportAddress{
+			ID: address.ID, FirstName: address.FirstName, LastName: address.LastName, ContactPhone: address.ContactPhone,
+			Country: address.Country, Province: address.Province, City: address.City, Detail: address.Detail,
+			ZipCode: address.ZipCode, CreatedAt: address.CreatedAt,
+		}
+		if address.DeletedAt.Valid {
+			item.DeletedAt = &address.DeletedAt.Time
+		}
+		document.Addresses = append(document.Addresses, item)
+	}
+	sessions, err := des.sessionDao.ListByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		document.Sessions = append(document.Sessions, DataExportSession{
+			Client: session.Audience, UserAgent: session.UserAgent, IP: session.IP,
+			CreatedAt: session.CreatedAt, LastSeenAt: session.LastSeenAt, RevokedAt: session.RevokedAt,
+		})
+	}
+	identities, err := des.identityDao.ListByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		document.SocialLogins = append(document.SocialLogins, DataExportIdentity{
+			Provider: identity.Provider, Email: identity.Email, CreatedAt: identity.CreatedAt,
+		})
+	}
+	apiKeys, err := des.apiKeyDao.ListByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, apiKey := range apiKeys {
+		document.ApiKeys = append(document.ApiKeys, DataExportApiKey{
+			Name: apiKey.Name, Prefix: apiKey.Prefix, Scopes: apiKey.Scopes,
+			CreatedAt: apiKey.CreatedAt, ExpiresAt: apiKey.ExpiresAt, LastUsedAt: apiKey.LastUsedAt,
+		})
+	}
+	mfa, err := des.userMfaDao.GetByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	document.TwoFactorEnabled = mfa != nil && mfa.Enabled
+	history, err := des.statusHistoryDao.ListByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range history {
+		document.StatusHistory = append(document.StatusHistory, DataExportStatusChange{
+			FromStatus: model.UserStatusNames[entry.FromStatus], ToStatus: model.UserStatusNames[entry.ToStatus],
+			Reason: entry.Reason, ByStaff: entry.ActorID != nil, ChangedAt: entry.CreatedAt,
+		})
+	}
+	impersonations, err := des.impersonationDao.ListByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := des.impersonationDao.ListRequestsByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	requestsByToken := make(map[string][]DataExportImpersonatedRequest, len(impersonations))
+	for _, request := range requests {
+		requestsByToken[request.TokenID] = append(requestsByToken[request.TokenID], DataExportImpersonatedRequest{
+			Method: request.Method, Path: request.Path, StatusCode: request.StatusCode, At: request.CreatedAt,
+		})
+	}
+	for _, impersonation := range impersonations {
+		item := DataExportImpersonation{
+			Client: impersonation.Audience, Reason: impersonation.Reason,
+			StartedAt: impersonation.CreatedAt, ExpiresAt: impersonation.ExpiresAt,
+			Requests: requestsByToken[impersonation.TokenID],
+		}
+		if item.Requests == nil {
+			item.Requests = []DataExportImpersonatedRequest{}
+		}
+		document.Impersonations = append(document.Impersonations, item)
+	}
+	return document, nil
+}
+
+// DataExportZip packs the JSON archive of an export into a ZIP file.
+func DataExportZip(export *model.UserDataExport) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := zip.NewWriter(buf)
+	modified := export.CreatedAt
+	if export.CompletedAt != nil {
+		modified = *export.CompletedAt
+	}
+	file, err := writer.CreateHeader(&zip.FileHeader{Name: dataExportFileName, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(export.Archive); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DataExportDownloadURL builds the download link of an export. base is the
+// public service prefix, e.g. https://example.com/user-ms/v1, and format is
+// json or zip.
+func DataExportDownloadURL(base, client, token, format string) string {
+	return fmt.Sprintf("%s/%s/users/data-export/download?token=%s&format=%s", strings.TrimRight(base, "/"), client, url.QueryEscape(token), format)
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	proxy_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy/mocks"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type dataExportMocks struct {
+	userDao          *dao_mock.UserDao
+	userAddressDao   *dao_mock.UserAddressDao
+	sessionDao       *dao_mock.UserSessionDao
+	identityDao      *dao_mock.UserIdentityDao
+	apiKeyDao        *dao_mock.ApiKeyDao
+	userMfaDao       *dao_mock.UserMfaDao
+	statusHistoryDao *dao_mock.UserStatusHistoryDao
+	impersonationDao *dao_mock.ImpersonationDao
+	exportDao        *dao_mock.UserDataExportDao
+	emailSender      *proxy_mock.EmailService
+}
+
+func newDataExportService() (*DataExportServiceImpl, *dataExportMocks) {
+	m := &dataExportMocks{
+		userDao:          new(dao_mock.UserDao),
+		userAddressDao:   new(dao_mock.UserAddressDao),
+		sessionDao:       new(dao_mock.UserSessionDao),
+		identityDao:      new(dao_mock.UserIdentityDao),
+		apiKeyDao:        new(dao_mock.ApiKeyDao),
+		userMfaDao:       new(dao_mock.UserMfaDao),
+		statusHistoryDao: new(dao_mock.UserStatusHistoryDao),
+		impersonationDao: new(dao_mock.ImpersonationDao),
+		exportDao:        new(dao_mock.UserDataExportDao),
+		emailSender:      new(proxy_mock.EmailService),
+	}
+	service := &DataExportServiceImpl{
+		userDao:          m.userDao,
+		userAddressDao:   m.userAddressDao,
+		sessionDao:       m.sessionDao,
+		identityDao:      m.identityDao,
+		apiKeyDao:        m.apiKeyDao,
+		userMfaDao:       m.userMfaDao,
+		statusHistoryDao: m.statusHistoryDao,
+		impersonationDao: m.impersonationDao,
+		exportDao:        m.exportDao,
+		emailService:     m.emailSender,
+		downloadTTL:      24 * time.Hour,
+		linkBaseURL:      "https://shop.example.com/user-ms/v1",
+		// Generate right away so the tests can check the result
+		async: func(task func()) { task() },
+	}
+	return service, m
+}
+
+func TestRequestDataExport(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+
+	t.Run("Collects everything about the user", func(t *testing.T) {
+		service, m := newDataExportService()
+		m.exportDao.On("GetLatestByUserId", mock.Anything, 1).Return(nil, nil)
+		var created *model.UserDataExport
+		m.exportDao.On("Create", mock.Anything, mock.MatchedBy(func(arg *model.UserDataExport) bool {
+			created = arg
+			arg.ID = 9
+			return arg.UserID == 1 && arg.Status == model.DataExportStatusPending
+		})).Return(nil)
+		m.userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{
+			ID: 1, Email: "buyer@example.com", Password: "secret-hash", Name: "Jane", Role: model.UserRoleCustomer, Status: model.UserStatusActive,
+		}, nil)
+		deletedAt := time.Now().Add(-time.Hour)
+		m.userAddressDao.On("ListAllByUserId", mock.Anything, 1).Return([]*model.UserAddress{
+			{ID: 1, UserID: 1, City: "Singapore"},
+			{ID: 2, UserID: 1, City: "Johor Bahru", DeletedAt: sql.NullTime{Time: deletedAt, Valid: true}},
+		}, nil)
+		m.sessionDao.On("ListByUserId", mock.Anything, 1).Return([]*model.UserSession{{ID: 3, UserID: 1, Audience: "customer", IP: "10.0.0.1", FamilyID: "family"}}, nil)
+		m.identityDao.On("ListByUserId", mock.Anything, 1).Return([]*model.UserIdentity{{UserID: 1, Provider: "google", Subject: "sub", Email: "buyer@gmail.com"}}, nil)
+		m.apiKeyDao.On("ListByUserId", mock.Anything, 1).Return([]*model.ApiKey{{UserID: 1, Name: "script", Prefix: "ck_abc", SecretHash: "key-hash", Scopes: []string{"profile:read"}}}, nil)
+		m.userMfaDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserMfa{UserID: 1, Secret: "totp-secret", Enabled: true}, nil)
+		staffID := 42
+		m.statusHistoryDao.On("ListByUserId", mock.Anything, 1).Return([]*model.UserStatusHistory{
+			{UserID: 1, FromStatus: model.UserStatusInactive, ToStatus: model.UserStatusActive, Reason: "activated"},
+			{UserID: 1, FromStatus: model.UserStatusActive, ToStatus: model.UserStatusSuspended, Reason: "chargeback", ActorID: &staffID},
+			{UserID: 1, FromStatus: model.UserStatusSuspended, ToStatus: model.UserStatusActive, Reason: "appeal granted", ActorID: &staffID},
+		}, nil)
+		m.impersonationDao.On("ListByUserId", mock.Anything, 1).Return([]*model.Impersonation{
+			{ImpersonatorID: staffID, UserID: 1, Audience: "customer", Reason: "ticket 123", TokenID: "jti-1"},
+			{ImpersonatorID: staffID, UserID: 1, Audience: "customer", Reason: "ticket 456", TokenID: "jti-2"},
+		}, nil)
+		m.impersonationDao.On("ListRequestsByUserId", mock.Anything, 1).Return([]*model.ImpersonationRequest{
+			{TokenID: "jti-1", ImpersonatorID: staffID, UserID: 1, Method: "GET", Path: "/user-ms/v1/customer/users/self", StatusCode: 200, IP: "192.0.2.7"},
+		}, nil)
+		var archive []byte
+		m.exportDao.On("Complete", mock.Anything, int64(9), mock.MatchedBy(func(arg []byte) bool {
+			archive = arg
+			return true
+		}), mock.Anything, mock.Anything).Return(nil)
+		var emailedToken string
+		m.emailSender.On("Send", mock.MatchedBy(func(body string) bool {
+			match := regexp.MustCompile(`<a href="https://shop\.example\.com/user-ms/v1/customer/users/data-export/download\?token=([^&"]+)&format=zip"`).FindStringSubmatch(body)
+			if match == nil {
+				return false
+			}
+			emailedToken, _ = url.QueryUnescape(match[1])
+			return true
+		}), "buyer@example.com", mock.Anything).Return(nil)
+
+		export, token, err := service.RequestExport(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, created, export)
+		assert.Equal(t, hashToken(token), export.TokenHash)
+		assert.Equal(t, token, emailedToken)
+
+		var document DataExportDocument
+		assert.NoError(t, json.Unmarshal(archive, &document))
+		assert.Equal(t, "buyer@example.com", document.Profile.Email)
+		assert.Equal(t, "active", document.Profile.Status)
+		assert.Len(t, document.Addresses, 2)
+		assert.Nil(t, document.Addresses[0].DeletedAt)
+		assert.WithinDuration(t, deletedAt, *document.Addresses[1].DeletedAt, time.Second)
+		assert.Equal(t, "10.0.0.1", document.Sessions[0].IP)
+		assert.Equal(t, "google", document.SocialLogins[0].Provider)
+		assert.Equal(t, "ck_abc", document.ApiKeys[0].Prefix)
+		assert.True(t, document.TwoFactorEnabled)
+		assert.Len(t, document.StatusHistory, 3)
+		assert.Equal(t, "suspended", document.StatusHistory[1].ToStatus)
+		assert.False(t, document.StatusHistory[0].ByStaff)
+		assert.True(t, document.StatusHistory[1].ByStaff)
+		assert.Len(t, document.Impersonations, 2)
+		assert.Equal(t, "ticket 123", document.Impersonations[0].Reason)
+		assert.Equal(t, "/user-ms/v1/customer/users/self", document.Impersonations[0].Requests[0].Path)
+		assert.Empty(t, document.Impersonations[1].Requests)
+		// Credentials and the staff members never leave the service
+		for _, secret := range []string{"secret-hash", "key-hash", "totp-secret", "jti-1", "192.0.2.7"} {
+			assert.NotContains(t, string(archive), secret)
+		}
+		m.emailSender.AssertExpectations(t)
+	})
+
+	t.Run("One export at a time", func(t *testing.T) {
+		service, m := newDataExportService()
+		m.exportDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserDataExport{
+			ID: 9, UserID: 1, Status: model.DataExportStatusPending, CreatedAt: time.Now().Add(-time.Minute),
+		}, nil)
+
+		_, _, err := service.RequestExport(ctx, 1)
+		assert.ErrorIs(t, err, ErrDataExportInProgress)
+		m.exportDao.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed generation is recorded", func(t *testing.T) {
+		service, m := newDataExportService()
+		// A pending export that was lost does not block a new one
+		m.exportDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserDataExport{
+			ID: 8, UserID: 1, Status: model.DataExportStatusPending, CreatedAt: time.Now().Add(-2 * dataExportGenerationTimeout),
+		}, nil)
+		m.exportDao.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.UserDataExport).ID = 9
+		}).Return(nil)
+		m.userDao.On("GetUserById", mock.Anything, 1).Return(nil, nil)
+		m.exportDao.On("MarkFailed", mock.Anything, int64(9), mock.Anything).Return(nil).Once()
+
+		_, _, err := service.RequestExport(ctx, 1)
+		assert.NoError(t, err)
+		m.exportDao.AssertExpectations(t)
+		m.emailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDownloadDataExport(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	completedAt := time.Now()
+	ready := &model.UserDataExport{ID: 1, UserID: 1, Status: model.DataExportStatusReady, Archive: []byte(`{"profile":{}}`), CompletedAt: &completedAt, ExpiresAt: time.Now().Add(time.Hour)}
+	service, m := newDataExportService()
+	m.exportDao.On("GetByTokenHash", mock.Anything, hashToken("ready")).Return(ready, nil)
+	m.exportDao.On("GetByTokenHash", mock.Anything, hashToken("pending")).Return(&model.UserDataExport{Status: model.DataExportStatusPending, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	m.exportDao.On("GetByTokenHash", mock.Anything, hashToken("expired")).Return(&model.UserDataExport{Status: model.DataExportStatusReady, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+	m.exportDao.On("GetByTokenHash", mock.Anything, hashToken("unknown")).Return(nil, nil)
+
+	export, err := service.Download(ctx, "ready")
+	assert.NoError(t, err)
+	assert.Equal(t, ready, export)
+	_, err = service.Download(ctx, "pending")
+	assert.ErrorIs(t, err, ErrDataExportNotReady)
+	for _, token := range []string{"", "expired", "unknown"} {
+		_, err = service.Download(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidDataExportToken, token)
+	}
+
+	t.Run("ZIP holds the JSON document", func(t *testing.T) {
+		archive, err := DataExportZip(ready)
+		assert.NoError(t, err)
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		assert.NoError(t, err)
+		assert.Len(t, reader.File, 1)
+		assert.Equal(t, dataExportFileName, reader.File[0].Name)
+		file, err := reader.File[0].Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(file)
+		assert.NoError(t, err)
+		assert.Equal(t, ready.Archive, content)
+	})
+}
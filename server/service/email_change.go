@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+// EmailChangeService changes the login email of a user. The new address is
+// confirmed with a code, and the old address is told about the change with a
+// link that undoes it.
+type EmailChangeService interface {
+	RequestChange(ctx context.Context, userID int, password, newEmail string) error
+	ConfirmChange(ctx context.Context, userID int, code string) error
+	RevertChange(ctx context.Context, token string) error
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type EmailChangeServiceImpl struct {
+	userDao        dao.UserDao
+	emailChangeDao dao.UserEmailChangeDao
+	emailService   proxy.EmailService
+	txBeginner     repository.TxBeginner
+	kafkaProducer  mq.KafkaProducer
+	// linkBaseURL is the service prefix revert links point to, the notice to
+	// the old address goes without a link while it is empty
+	linkBaseURL string
+}
+
+var (
+	emailChangeOnce sync.Once
+	emailChangeInst *EmailChangeServiceImpl
+)
+
+const (
+	EmailChangeCodeTTL     = 15 * time.Minute
+	EmailChangeRevertTTL   = 7 * 24 * time.Hour
+	emailChangeMaxAttempts = 5
+)
+
+var (
+	ErrSameEmail              = errors.New("new email must differ from the current email")
+	ErrEmailTaken             = errors.New("email is already in use")
+	ErrInvalidEmailChangeCode = errors.New("invalid or expired email change code")
+	ErrInvalidRevertLink      = errors.New("invalid or expired revert link")
+)
+
+func GetEmailChangeService() *EmailChangeServiceImpl {
+	emailChangeOnce.Do(func() {
+		if emailChangeInst == nil {
+			emailChangeInst = &EmailChangeServiceImpl{
+				userDao:        dao.GetUserDao(),
+				emailChangeDao: dao.GetUserEmailChangeDao(),
+				emailService:   proxy.GetEmailInstance(),
+				txBeginner:     repository.DB,
+				kafkaProducer:  mq.GetKafkaProducer(),
+			}
+			if cfg := config.Config.EmailChangeConfig; cfg != nil {
+				emailChangeInst.linkBaseURL = cfg.LinkBaseURL
+			}
+		}
+	})
+	return emailChangeInst
+}
+
+// RequestChange checks the current password and emails a code to newEmail.
+// A new request replaces the pending one.
+func (es *EmailChangeServiceImpl) RequestChange(ctx context.Context, userID int, password, newEmail string) error {
+	user, err := es.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if VerifyPassword(user.Password, password) != nil {
+		log.Logger.Warnf("Incorrect current password for email change of user %d", userID)
+		return ErrIncorrectPassword
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return ErrSameEmail
+	}
+	if err := es.checkEmailFree(ctx, newEmail, userID); err != nil {
+		return err
+	}
+	code, err := generateVerificationCode()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate email change code: %v", err)
+		return err
+	}
+	codeHash, err := HashPassword(code)
+	if err != nil {
+		log.Logger.Errorf("Failed to hash email change code: %v", err)
+		return err
+	}
+	now := time.Now()
+	err = es.emailChangeDao.Replace(ctx, &model.UserEmailChange{
+		UserID:    userID,
+		OldEmail:  user.Email,
+		NewEmail:  newEmail,
+		CodeHash:  codeHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(EmailChangeCodeTTL),
+	})
+	if err != nil {
+		return err
+	}
+	err = es.emailService.Send("Your code to confirm your new CermiCraft email is: "+code, newEmail, "CermiCraft Email Change Code")
+	if err != nil {
+		log.Logger.Errorf("Failed to send email change code: %v", err)
+		return err
+	}
+	log.Logger.Infof("Email change code sent for user: %d", userID)
+	return nil
+}
+
+// ConfirmChange switches the user to the new email once the code matches,
+// and sends the old address a link to undo the change.
+func (es *EmailChangeServiceImpl) ConfirmChange(ctx context.Context, userID int, code string) error {
+	change, err := es.emailChangeDao.GetPendingByUserId(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if change == nil || !change.ExpiresAt.After(time.Now()) || change.Attempts >= emailChangeMaxAttempts {
+		log.Logger.Warnf("No valid email change for user: %d", userID)
+		return ErrInvalidEmailChangeCode
+	}
+	if VerifyPassword(change.CodeHash, code) != nil {
+		if err := es.emailChangeDao.IncrementAttempts(ctx, change.ID); err != nil {
+			log.Logger.Errorf("Failed to record email change attempt: %v", err)
+		}
+		log.Logger.Warnf("Wrong email change code for user: %d, attempts=%d", userID, change.Attempts+1)
+		return ErrInvalidEmailChangeCode
+	}
+	// The address may have been registered since the code was sent
+	if err := es.checkEmailFree(ctx, change.NewEmail, userID); err != nil {
+		return err
+	}
+	revertToken, err := generateOpaqueToken()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate revert link: %v", err)
+		return err
+	}
+	now := time.Now()
+	err = es.txBeginner.Transaction(func(tx *gorm.DB) error {
+		confirmed, err := es.emailChangeDao.ConfirmInTransaction(ctx, change.ID, hashToken(revertToken), now, now.Add(EmailChangeRevertTTL), tx)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return ErrInvalidEmailChangeCode
+		}
+		changed, err := es.userDao.ChangeEmailInTransaction(ctx, userID, change.OldEmail, change.NewEmail, false, tx)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			// The email was changed another way since the request
+			return ErrInvalidEmailChangeCode
+		}
+		return es.produceEvent(ctx, &mq.UserEmailChangedEvent{
+			UserID: userID, OldEmail: change.OldEmail, NewEmail: change.NewEmail, ChangeTime: now.Unix(),
+		})
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to change email of user %d: %v", userID, err)
+		return err
+	}
+	log.Logger.Infof("Email of user %d changed", userID)
+
+	user, err := es.userDao.GetUserById(ctx, userID)
+	if err != nil || user == nil {
+		log.Logger.Errorf("Failed to get user %d for the email change notice: %v", userID, err)
+		return nil
+	}
+	body := fmt.Sprintf("The email of your CermiCraft account was changed to %s.", change.NewEmail)
+	if es.linkBaseURL != "" {
+		body += fmt.Sprintf("<br>If this was not you, <a href=\"%s\">restore this email and sign out everywhere</a> within %d days.",
+			EmailRevertLinkURL(es.linkBaseURL, clientForRole(user.Role), revertToken), int(EmailChangeRevertTTL.Hours()/24))
+	}
+	// The change is done, a lost notice must not turn it into an error
+	if err := es.emailService.Send(body, change.OldEmail, "CermiCraft Email Changed"); err != nil {
+		log.Logger.Errorf("Failed to send email change notice: %v", err)
+	}
+	return nil
+}
+
+// RevertChange restores the old email with the link sent to it. Since the
+// change may have been made by someone who took over the account, every
+// session of the user is signed out as well.
+func (es *EmailChangeServiceImpl) RevertChange(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidRevertLink
+	}
+	change, err := es.emailChangeDao.GetByRevertHash(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if change == nil || change.ConfirmedAt == nil || !change.ExpiresAt.After(time.Now()) {
+		log.Logger.Warn("Revert link is unknown, used or expired")
+		return ErrInvalidRevertLink
+	}
+	if err := es.checkEmailFree(ctx, change.OldEmail, change.UserID); err != nil {
+		return err
+	}
+	now := time.Now()
+	err = es.txBeginner.Transaction(func(tx *gorm.DB) error {
+		// The delete decides between concurrent uses of the link
+		consumed, err := es.emailChangeDao.DeleteInTransaction(ctx, change.ID, tx)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return ErrInvalidRevertLink
+		}
+		changed, err := es.userDao.ChangeEmailInTransaction(ctx, change.UserID, change.NewEmail, change.OldEmail, true, tx)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			log.Logger.Warnf("Email of user %d changed again, change %d cannot be reverted", change.UserID, change.ID)
+			return ErrInvalidRevertLink
+		}
+		return es.produceEvent(ctx, &mq.UserEmailChangedEvent{
+			UserID: change.UserID, OldEmail: change.NewEmail, NewEmail: change.OldEmail, Reverted: true, ChangeTime: now.Unix(),
+		})
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to revert email change %d: %v", change.ID, err)
+		return err
+	}
+	log.Logger.Infof("Email change of user %d reverted, all sessions signed out", change.UserID)
+	return nil
+}
+
+func (es *EmailChangeServiceImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return es.emailChangeDao.DeleteExpired(ctx, now)
+}
+
+// checkEmailFree fails with ErrEmailTaken if an account other than userID,
+// pending ones included, uses email.
+func (es *EmailChangeServiceImpl) checkEmailFree(ctx context.Context, email string, userID int) error {
+	existing, err := es.userDao.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by email: %v", err)
+		return err
+	}
+	if existing != nil && existing.ID != userID {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+func (es *EmailChangeServiceImpl) produceEvent(ctx context.Context, event *mq.UserEmailChangedEvent) error {
+	err := es.kafkaProducer.Produce(ctx, config.Config.KafkaConfig.UserEmailChangedTopic, fmt.Sprintf("%d", event.UserID), event.ToBytes())
+	if err != nil {
+		log.Logger.Errorf("Failed to produce email changed event: %v", err)
+	}
+	return err
+}
+
+// EmailRevertLinkURL builds the link sent to the old address. base is the
+// public service prefix, e.g. https://example.com/user-ms/v1
+func EmailRevertLinkURL(base, client, token string) string {
+	return fmt.Sprintf("%s/%s/users/email/revert?token=%s", strings.TrimRight(base, "/"), client, url.QueryEscape(token))
+}
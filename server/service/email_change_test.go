@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	mq_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq/mocks"
+	proxy_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy/mocks"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newEmailChangeService(t *testing.T) (*EmailChangeServiceImpl, *dao_mock.UserDao, *dao_mock.UserEmailChangeDao, *proxy_mock.EmailService, *mq_mock.KafkaProducer) {
+	userDao := new(dao_mock.UserDao)
+	emailChangeDao := new(dao_mock.UserEmailChangeDao)
+	emailSender := new(proxy_mock.EmailService)
+	kafkaProducer := new(mq_mock.KafkaProducer)
+	service := &EmailChangeServiceImpl{
+		userDao:        userDao,
+		emailChangeDao: emailChangeDao,
+		emailService:   emailSender,
+		txBeginner:     &fakeTx{DB: initMemDb(t)},
+		kafkaProducer:  kafkaProducer,
+		linkBaseURL:    "https://shop.example.com/user-ms/v1",
+	}
+	return service, userDao, emailChangeDao, emailSender, kafkaProducer
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	hashedPwd, _ := HashPassword("password1")
+	user := &model.User{ID: 1, Email: "old@example.com", Password: hashedPwd, Role: model.UserRoleCustomer}
+
+	t.Run("Wrong password", func(t *testing.T) {
+		service, userDao, emailChangeDao, _, _ := newEmailChangeService(t)
+		userDao.On("GetUserById", mock.Anything, 1).Return(user, nil)
+
+		err := service.RequestChange(ctx, 1, "wrong", "new@example.com")
+		assert.ErrorIs(t, err, ErrIncorrectPassword)
+		emailChangeDao.AssertNotCalled(t, "Replace", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Same email", func(t *testing.T) {
+		service, userDao, _, _, _ := newEmailChangeService(t)
+		userDao.On("GetUserById", mock.Anything, 1).Return(user, nil)
+
+		assert.ErrorIs(t, service.RequestChange(ctx, 1, "password1", "OLD@example.com"), ErrSameEmail)
+	})
+
+	t.Run("Email taken", func(t *testing.T) {
+		service, userDao, _, _, _ := newEmailChangeService(t)
+		userDao.On("GetUserById", mock.Anything, 1).Return(user, nil)
+		userDao.On("GetUserByEmail", mock.Anything, "new@example.com").Return(&model.User{ID: 2}, nil)
+
+		assert.ErrorIs(t, service.RequestChange(ctx, 1, "password1", "new@example.com"), ErrEmailTaken)
+	})
+
+	t.Run("Emails a code to the new address", func(t *testing.T) {
+		service, userDao, emailChangeDao, emailSender, _ := newEmailChangeService(t)
+		userDao.On("GetUserById", mock.Anything, 1).Return(user, nil)
+		userDao.On("GetUserByEmail", mock.Anything, "new@example.com").Return(nil, nil)
+		var stored *model.UserEmailChange
+		emailChangeDao.On("Replace", mock.Anything, mock.MatchedBy(func(arg *model.UserEmailChange) bool {
+			stored = arg
+			return arg.UserID == 1 && arg.OldEmail == "old@example.com" && arg.NewEmail == "new@example.com" && arg.ExpiresAt.After(time.Now())
+		})).Return(nil)
+		var code string
+		emailSender.On("Send", mock.MatchedBy(func(body string) bool {
+			match := regexp.MustCompile(`code to confirm your new CermiCraft email is: (\d{6})`).FindStringSubmatch(body)
+			if match == nil {
+				return false
+			}
+			code = match[1]
+			return true
+		}), "new@example.com", mock.Anything).Return(nil)
+
+		assert.NoError(t, service.RequestChange(ctx, 1, "password1", "new@example.com"))
+		// Only a hash of the code is stored
+		assert.NotEqual(t, code, stored.CodeHash)
+		assert.NoError(t, VerifyPassword(stored.CodeHash, code))
+	})
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	initEnv()
+	config.Config.KafkaConfig.UserEmailChangedTopic = "user_email_changed"
+	ctx := context.Background()
+	codeHash, _ := HashPassword("123456")
+	pending := func() *model.UserEmailChange {
+		return &model.UserEmailChange{ID: 7, UserID: 1, OldEmail: "old@example.com", NewEmail: "new@example.com", CodeHash: codeHash, ExpiresAt: time.Now().Add(time.Minute)}
+	}
+
+	t.Run("Wrong code counts an attempt", func(t *testing.T) {
+		service, userDao, emailChangeDao, _, _ := newEmailChangeService(t)
+		emailChangeDao.On("GetPendingByUserId", mock.Anything, 1).Return(pending(), nil)
+		emailChangeDao.On("IncrementAttempts", mock.Anything, int64(7)).Return(nil).Once()
+
+		assert.ErrorIs(t, service.ConfirmChange(ctx, 1, "654321"), ErrInvalidEmailChangeCode)
+		emailChangeDao.AssertExpectations(t)
+		userDao.AssertNotCalled(t, "ChangeEmailInTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Too many attempts", func(t *testing.T) {
+		service, _, emailChangeDao, _, _ := newEmailChangeService(t)
+		exhausted := pending()
+		exhausted.Attempts = emailChangeMaxAttempts
+		emailChangeDao.On("GetPendingByUserId", mock.Anything, 1).Return(exhausted, nil)
+
+		assert.ErrorIs(t, service.ConfirmChange(ctx, 1, "123456"), ErrInvalidEmailChangeCode)
+	})
+
+	t.Run("Changes the email and notifies the old address", func(t *testing.T) {
+		service, userDao, emailChangeDao, emailSender, kafkaProducer := newEmailChangeService(t)
+		emailChangeDao.On("GetPendingByUserId", mock.Anything, 1).Return(pending(), nil)
+		userDao.On("GetUserByEmail", mock.Anything, "new@example.com").Return(nil, nil)
+		var revertHash string
+		emailChangeDao.On("ConfirmInTransaction", mock.Anything, int64(7), mock.MatchedBy(func(hash string) bool {
+			revertHash = hash
+			return hash != ""
+		}), mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		// Sessions stay valid, the user asked for the change
+		userDao.On("ChangeEmailInTransaction", mock.Anything, 1, "old@example.com", "new@example.com", false, mock.Anything).Return(true, nil)
+		kafkaProducer.On("Produce", mock.Anything, "user_email_changed", "1", mock.Anything).Return(nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Email: "new@example.com", Role: model.UserRoleCustomer}, nil)
+		var token string
+		emailSender.On("Send", mock.MatchedBy(func(body string) bool {
+			match := regexp.MustCompile(`<a href="https://shop\.example\.com/user-ms/v1/customer/users/email/revert\?token=([^"]+)"`).FindStringSubmatch(body)
+			if match == nil {
+				return false
+			}
+			token, _ = url.QueryUnescape(match[1])
+			return true
+		}), "old@example.com", mock.Anything).Return(nil)
+
+		assert.NoError(t, service.ConfirmChange(ctx, 1, "123456"))
+		assert.Equal(t, hashToken(token), revertHash)
+		userDao.AssertExpectations(t)
+		kafkaProducer.AssertExpectations(t)
+		emailSender.AssertExpectations(t)
+	})
+}
+
+func TestRevertEmailChange(t *testing.T) {
+	initEnv()
+	config.Config.KafkaConfig.UserEmailChangedTopic = "user_email_changed"
+	ctx := context.Background()
+	confirmedAt := time.Now().Add(-time.Hour)
+	confirmed := &model.UserEmailChange{ID: 7, UserID: 1, OldEmail: "old@example.com", NewEmail: "new@example.com", ConfirmedAt: &confirmedAt, ExpiresAt: time.Now().Add(time.Hour)}
+
+	t.Run("Restores the old email and signs out everywhere", func(t *testing.T) {
+		service, userDao, emailChangeDao, _, kafkaProducer := newEmailChangeService(t)
+		emailChangeDao.On("GetByRevertHash", mock.Anything, hashToken("revert-token")).Return(confirmed, nil)
+		userDao.On("GetUserByEmail", mock.Anything, "old@example.com").Return(nil, nil)
+		emailChangeDao.On("DeleteInTransaction", mock.Anything, int64(7), mock.Anything).Return(true, nil)
+		userDao.On("ChangeEmailInTransaction", mock.Anything, 1, "new@example.com", "old@example.com", true, mock.Anything).Return(true, nil)
+		kafkaProducer.On("Produce", mock.Anything, "user_email_changed", "1", mock.Anything).Return(nil)
+
+		assert.NoError(t, service.RevertChange(ctx, "revert-token"))
+		userDao.AssertExpectations(t)
+		emailChangeDao.AssertExpectations(t)
+		kafkaProducer.AssertExpectations(t)
+	})
+
+	t.Run("Unknown, unconfirmed or expired links", func(t *testing.T) {
+		service, _, emailChangeDao, _, _ := newEmailChangeService(t)
+		expired := *confirmed
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		unconfirmed := *confirmed
+		unconfirmed.ConfirmedAt = nil
+		emailChangeDao.On("GetByRevertHash", mock.Anything, hashToken("unknown")).Return(nil, nil)
+		emailChangeDao.On("GetByRevertHash", mock.Anything, hashToken("expired")).Return(&expired, nil)
+		emailChangeDao.On("GetByRevertHash", mock.Anything, hashToken("unconfirmed")).Return(&unconfirmed, nil)
+
+		for _, token := range []string{"", "unknown", "expired", "unconfirmed"} {
+			assert.ErrorIs(t, service.RevertChange(ctx, token), ErrInvalidRevertLink, token)
+		}
+		emailChangeDao.AssertNotCalled(t, "DeleteInTransaction", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
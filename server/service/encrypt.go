@@ -1,17 +1,34 @@
 package service
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 )
 
+// VerifyPassword checks password against a hash of any supported algorithm.
 func VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return GetPasswordHasher().Verify(hashedPassword, password)
 }
 
+// HashPassword hashes password with the configured algorithm.
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	return GetPasswordHasher().Hash(password)
+}
+
+// generateOpaqueToken returns a random URL-safe token with 256 bits of entropy
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
 		return "", err
 	}
-	return string(hashedPassword), nil
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken digests a high-entropy token for storage. A fast hash is enough
+// here because, unlike passwords, the input cannot be guessed.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
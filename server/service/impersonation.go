@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/bo"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// ImpersonationService lets an admin act as a user, e.g. to see what a
+// customer sees. Every grant and every request made with it is recorded.
+type ImpersonationService interface {
+	// Start issues an access token for userID that records adminID as the
+	// impersonator. No refresh token is issued, so the impersonation ends
+	// when the access token expires.
+	Start(ctx context.Context, adminID, userID int, reason string) (*ImpersonationGrant, error)
+	// RecordRequest adds a request made with an impersonation token to the
+	// audit trail. The source IP is taken from the ClientInfo of ctx.
+	RecordRequest(ctx context.Context, claims *utils.Claims, method, path string, statusCode int) error
+}
+
+// ImpersonationGrant is a started impersonation.
+type ImpersonationGrant struct {
+	ID          int64
+	UserID      int
+	Audience    string
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+type ImpersonationServiceImpl struct {
+	userDao           dao.UserDao
+	rolePermissionDao dao.RolePermissionDao
+	impersonationDao  dao.ImpersonationDao
+}
+
+var (
+	impersonationServiceOnce sync.Once
+	impersonationServiceInst *ImpersonationServiceImpl
+)
+
+// impersonableRoles are the roles admins may impersonate. Admins are left
+// out so impersonation cannot be used to gain another admin's standing.
+var impersonableRoles = []string{model.UserRoleCustomer, model.UserRoleMerchant}
+
+var (
+	ErrCannotImpersonate = errors.New("this account cannot be impersonated")
+	// ErrImpersonationEnded rejects impersonation tokens of an admin who was
+	// suspended or lost the admin role since
+	ErrImpersonationEnded = errors.New("impersonation is no longer allowed")
+)
+
+func GetImpersonationService() *ImpersonationServiceImpl {
+	impersonationServiceOnce.Do(func() {
+		if impersonationServiceInst == nil {
+			impersonationServiceInst = &ImpersonationServiceImpl{
+				userDao:           dao.GetUserDao(),
+				rolePermissionDao: dao.GetRolePermissionDao(),
+				impersonationDao:  dao.GetImpersonationDao(),
+			}
+		}
+	})
+	return impersonationServiceInst
+}
+
+// Start only impersonates active customers and merchants, never the admin
+// themselves.
+func (is *ImpersonationServiceImpl) Start(ctx context.Context, adminID, userID int, reason string) (*ImpersonationGrant, error) {
+	if adminID == userID {
+		return nil, ErrCannotManageSelf
+	}
+	user, err := is.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !slices.Contains(impersonableRoles, user.Role) {
+		log.Logger.Warnf("Admin %d tried to impersonate user %d with role %s", adminID, userID, user.Role)
+		return nil, ErrCannotImpersonate
+	}
+	if err := CheckAccountStatus(user.Status); err != nil {
+		return nil, err
+	}
+	permissions, err := is.rolePermissionDao.GetPermissionsByRole(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	audience := clientForRole(user.Role)
+	accessToken, err := utils.GenerateJWTToken(&bo.UserBO{
+		ID:                user.ID,
+		Email:             user.Email,
+		Role:              user.Role,
+		Permissions:       permissions,
+		CredentialVersion: user.CredentialVersion,
+		ImpersonatorID:    adminID,
+	}, audience)
+	if err != nil {
+		log.Logger.Errorf("Failed to generate impersonation token: %v", err)
+		return nil, err
+	}
+	// The jti and expiry are set by the token generator
+	claims, err := utils.ParseJWTToken(accessToken)
+	if err != nil {
+		log.Logger.Errorf("Failed to parse impersonation token: %v", err)
+		return nil, err
+	}
+	impersonation := &model.Impersonation{
+		ImpersonatorID: adminID,
+		UserID:         userID,
+		Audience:       audience,
+		Reason:         reason,
+		TokenID:        claims.RegisteredClaims.ID,
+		ExpiresAt:      claims.ExpiresAt.Time,
+		CreatedAt:      time.Now(),
+	}
+	if err := is.impersonationDao.Create(ctx, impersonation); err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("Admin %d started impersonation %d of user %d: %s", adminID, impersonation.ID, userID, reason)
+	return &ImpersonationGrant{
+		ID:          impersonation.ID,
+		UserID:      userID,
+		Audience:    audience,
+		AccessToken: accessToken,
+		ExpiresAt:   impersonation.ExpiresAt,
+	}, nil
+}
+
+func (is *ImpersonationServiceImpl) RecordRequest(ctx context.Context, claims *utils.Claims, method, path string, statusCode int) error {
+	return is.impersonationDao.CreateRequest(ctx, &model.ImpersonationRequest{
+		TokenID:        claims.RegisteredClaims.ID,
+		ImpersonatorID: claims.ImpersonatorID,
+		UserID:         claims.ID,
+		Method:         truncate(method, 8),
+		Path:           truncate(path, 255),
+		StatusCode:     statusCode,
+		IP:             truncate(clientInfoFromContext(ctx).IP, 64),
+		CreatedAt:      time.Now(),
+	})
+}
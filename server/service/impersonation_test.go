@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImpersonation(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	adminID := 7
+	admin := &model.User{ID: adminID, Role: model.UserRoleAdmin, Status: model.UserStatusActive}
+	customer := &model.User{ID: 1, Email: "buyer@example.com", Role: model.UserRoleCustomer, Status: model.UserStatusActive, CredentialVersion: 2}
+	newService := func() (*ImpersonationServiceImpl, *dao_mock.UserDao, *dao_mock.ImpersonationDao) {
+		userDao := new(dao_mock.UserDao)
+		impersonationDao := new(dao_mock.ImpersonationDao)
+		return &ImpersonationServiceImpl{
+			userDao:           userDao,
+			rolePermissionDao: rolePermissionDaoWithDefaults(),
+			impersonationDao:  impersonationDao,
+		}, userDao, impersonationDao
+	}
+
+	t.Run("Token records the impersonator", func(t *testing.T) {
+		service, userDao, impersonationDao := newService()
+		userDao.On("GetUserById", mock.Anything, 1).Return(customer, nil)
+		var recorded *model.Impersonation
+		impersonationDao.On("Create", mock.Anything, mock.MatchedBy(func(arg *model.Impersonation) bool {
+			recorded = arg
+			return true
+		})).Return(nil)
+
+		grant, err := service.Start(ctx, adminID, 1, "address not saved")
+		assert.NoError(t, err)
+		assert.Equal(t, utils.AudienceCustomer, grant.Audience)
+		claims, err := utils.ParseJWTToken(grant.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, claims.ID)
+		assert.Equal(t, adminID, claims.ImpersonatorID)
+		assert.True(t, claims.IsImpersonated())
+		assert.True(t, claims.HasAudience(utils.AudienceCustomer))
+		assert.Equal(t, 2, claims.CredentialVersion)
+		assert.Contains(t, claims.Permissions, utils.PermAddressWrite)
+		// The grant is tied to the token and expires with it
+		assert.Equal(t, claims.RegisteredClaims.ID, recorded.TokenID)
+		assert.Equal(t, "address not saved", recorded.Reason)
+		assert.Equal(t, adminID, recorded.ImpersonatorID)
+		assert.WithinDuration(t, claims.ExpiresAt.Time, grant.ExpiresAt, time.Second)
+	})
+
+	t.Run("Refused targets", func(t *testing.T) {
+		service, userDao, impersonationDao := newService()
+		userDao.On("GetUserById", mock.Anything, 2).Return(&model.User{ID: 2, Role: model.UserRoleAdmin, Status: model.UserStatusActive}, nil)
+		userDao.On("GetUserById", mock.Anything, 3).Return(&model.User{ID: 3, Role: model.UserRoleCustomer, Status: model.UserStatusSuspended}, nil)
+		userDao.On("GetUserById", mock.Anything, 4).Return(nil, nil)
+
+		_, err := service.Start(ctx, adminID, adminID, "test")
+		assert.ErrorIs(t, err, ErrCannotManageSelf)
+		_, err = service.Start(ctx, adminID, 2, "test")
+		assert.ErrorIs(t, err, ErrCannotImpersonate)
+		_, err = service.Start(ctx, adminID, 3, "test")
+		assert.ErrorIs(t, err, ErrAccountSuspended)
+		_, err = service.Start(ctx, adminID, 4, "test")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		impersonationDao.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Token stops working once the admin is suspended", func(t *testing.T) {
+		service, userDao, impersonationDao := newService()
+		userDao.On("GetUserById", mock.Anything, 1).Return(customer, nil)
+		impersonationDao.On("Create", mock.Anything, mock.Anything).Return(nil)
+		grant, err := service.Start(ctx, adminID, 1, "address not saved")
+		assert.NoError(t, err)
+		claims, err := utils.ParseJWTToken(grant.AccessToken)
+		assert.NoError(t, err)
+		loginService := &LoginServiceImpl{userDao: userDao, revocationStore: NewMemoryTokenRevocationStore()}
+
+		userDao.On("GetUserById", mock.Anything, adminID).Return(admin, nil).Once()
+		assert.NoError(t, loginService.CheckClaims(claims))
+		suspended := *admin
+		suspended.Status = model.UserStatusSuspended
+		userDao.On("GetUserById", mock.Anything, adminID).Return(&suspended, nil)
+		assert.ErrorIs(t, loginService.CheckClaims(claims), ErrImpersonationEnded)
+	})
+
+	t.Run("Requests are recorded with the source IP", func(t *testing.T) {
+		service, _, impersonationDao := newService()
+		claims := &utils.Claims{ID: 1, ImpersonatorID: adminID}
+		claims.RegisteredClaims.ID = "jti-1"
+		impersonationDao.On("CreateRequest", mock.Anything, mock.MatchedBy(func(arg *model.ImpersonationRequest) bool {
+			return arg.TokenID == "jti-1" && arg.ImpersonatorID == adminID && arg.UserID == 1 && arg.Method == "PUT" &&
+				len(arg.Path) == 255 && arg.StatusCode == 403 && arg.IP == "10.0.0.1"
+		})).Return(nil)
+
+		ctx := WithClientInfo(ctx, ClientInfo{IP: "10.0.0.1"})
+		path := "/user-ms/v1/customer/users/self/addresses/" + strings.Repeat("1", 300)
+		assert.NoError(t, service.RecordRequest(ctx, claims, "PUT", path, 403))
+		impersonationDao.AssertExpectations(t)
+	})
+}
@@ -3,22 +3,36 @@ package service
 import (
 	"context"
 	"errors"
-	"fmt"
+	"slices"
 	"sync"
+	"time"
 
-	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/bo"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
 )
 
+// LoginResult holds either the issued tokens or, for users with 2FA enabled,
+// the challenge to complete with MfaService.VerifyChallenge.
+type LoginResult struct {
+	Tokens       *AuthTokens
+	MfaChallenge string
+}
+
 type LoginService interface {
-	Login(ctx context.Context, email, password string) (string, error)
-	Logout(ctx context.Context) error
+	Login(ctx context.Context, client, email, password, clientIP string) (*LoginResult, error)
+	Logout(ctx context.Context, claims *utils.Claims, refreshToken string) error
+	CheckClaims(claims *utils.Claims) error
 }
 
 type LoginServiceImpl struct {
-	userDao dao.UserDao
+	userDao         dao.UserDao
+	revocationStore TokenRevocationStore
+	tokenService    TokenService
+	mfaService      MfaService
+	loginGuard      LoginGuard
+	sessionDao      dao.UserSessionDao
 }
 
 var (
@@ -26,31 +40,258 @@ var (
 	loginServiceInst *LoginServiceImpl
 )
 
+var ErrClientNotAllowed = errors.New("this account cannot sign in to this client")
+
+// ErrInvalidCredentials answers an unknown email and a wrong password alike,
+// so login reveals nothing about which emails have accounts.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+var (
+	unknownUserHashOnce sync.Once
+	unknownUserHash     string
+)
+
+// clientRoles lists the roles allowed to sign in to each client.
+var clientRoles = map[string][]string{
+	utils.AudienceCustomer: {model.UserRoleCustomer},
+	utils.AudienceMerchant: {model.UserRoleMerchant},
+	utils.AudienceAdmin:    {model.UserRoleAdmin},
+}
+
+// CanUseClient reports whether a user with the given role may hold tokens for
+// the client.
+func CanUseClient(role, client string) bool {
+	return slices.Contains(clientRoles[client], role)
+}
+
 func GetLoginService() *LoginServiceImpl {
 	loginServiceOnce.Do(func() {
-		loginServiceInst = &LoginServiceImpl{userDao: dao.GetUserDao()}
+		loginServiceInst = &LoginServiceImpl{
+			userDao:         dao.GetUserDao(),
+			revocationStore: GetTokenRevocationStore(),
+			tokenService:    GetTokenService(),
+			mfaService:      GetMfaService(),
+			loginGuard:      GetLoginGuard(),
+			sessionDao:      dao.GetUserSessionDao(),
+		}
 	})
 	return loginServiceInst
 }
 
-func (ls *LoginServiceImpl) Login(ctx context.Context, email, password string) (string, error) {
+// Login checks the password of the user. Failed attempts are throttled per
+// account and per clientIP by the LoginGuard.
+func (ls *LoginServiceImpl) Login(ctx context.Context, client, email, password, clientIP string) (*LoginResult, error) {
+	if err := ls.loginGuard.Check(ctx, email, clientIP); err != nil {
+		log.Logger.Warnf("Login for %s from %s throttled: %v", email, clientIP, err)
+		return nil, err
+	}
 	user, err := ls.userDao.GetUserByEmail(ctx, email)
 	if err != nil {
 		log.Logger.Errorf("Failed to get user by email: %v", err)
-		return "", err
+		return nil, err
 	}
 	if user == nil {
-		return "", errors.New("user not found")
+		verifyUnknownUserPassword(password)
+		ls.recordFailure(ctx, email, clientIP, nil)
+		return nil, ErrInvalidCredentials
 	}
 	if VerifyPassword(user.Password, password) != nil {
-		log.Logger.Errorf("Failed to verify password")
-		return "", fmt.Errorf("invalid password")
+		log.Logger.Warnf("Wrong password for user %d", user.ID)
+		ls.recordFailure(ctx, email, clientIP, user)
+		return nil, ErrInvalidCredentials
+	}
+	if err := ls.loginGuard.RecordSuccess(ctx, email); err != nil {
+		log.Logger.Errorf("Failed to clear login failures: %v", err)
+	}
+	ls.rehashPassword(ctx, user, password)
+	return completeLogin(ctx, ls.mfaService, ls.tokenService, user, client)
+}
+
+// verifyUnknownUserPassword checks password against a throwaway hash, so a
+// login with an unknown email takes as long as one with a wrong password.
+func verifyUnknownUserPassword(password string) {
+	unknownUserHashOnce.Do(func() {
+		hash, err := HashPassword("unknown user")
+		if err != nil {
+			log.Logger.Errorf("Failed to hash placeholder password: %v", err)
+			return
+		}
+		unknownUserHash = hash
+	})
+	if unknownUserHash != "" {
+		_ = VerifyPassword(unknownUserHash, password)
+	}
+}
+
+// completeLogin finishes a login whose first factor was verified. It is
+// shared by every way of signing in so they apply the same policy: the role
+// must be allowed on the client and the account must be active. Users with
+// 2FA enabled then get a challenge, everyone else gets tokens.
+func completeLogin(ctx context.Context, mfaService MfaService, tokenService TokenService, user *model.User, client string) (*LoginResult, error) {
+	if !CanUseClient(user.Role, client) {
+		log.Logger.Warnf("User %d with role %s tried to sign in to the %s client", user.ID, user.Role, client)
+		return nil, ErrClientNotAllowed
+	}
+	if err := CheckAccountStatus(user.Status); err != nil {
+		log.Logger.Warnf("User %d cannot sign in: %v", user.ID, err)
+		return nil, err
+	}
+	mfaEnabled, err := mfaService.IsEnabled(ctx, user.ID)
+	if err != nil {
+		log.Logger.Errorf("Failed to check 2FA status: %v", err)
+		return nil, err
+	}
+	if mfaEnabled {
+		challenge, err := mfaService.CreateChallenge(ctx, user.ID, client)
+		if err != nil {
+			return nil, err
+		}
+		log.Logger.Infof("First factor accepted for user %d, waiting for second factor", user.ID)
+		return &LoginResult{MfaChallenge: challenge}, nil
+	}
+
+	tokens, err := tokenService.IssueTokens(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// rehashPassword upgrades a hash made with an outdated algorithm or cost
+// while the plaintext is at hand. A failure only delays the upgrade to the
+// next login.
+func (ls *LoginServiceImpl) rehashPassword(ctx context.Context, user *model.User, password string) {
+	hasher := GetPasswordHasher()
+	if !hasher.NeedsRehash(user.Password) {
+		return
+	}
+	newHash, err := hasher.Hash(password)
+	if err != nil {
+		log.Logger.Errorf("Failed to rehash password of user %d: %v", user.ID, err)
+		return
 	}
+	updated, err := ls.userDao.RehashPassword(ctx, user.ID, user.Password, newHash)
+	if err != nil {
+		log.Logger.Errorf("Failed to store rehashed password of user %d: %v", user.ID, err)
+		return
+	}
+	if updated {
+		user.Password = newHash
+		log.Logger.Infof("Password hash of user %d upgraded", user.ID)
+	}
+}
+
+func (ls *LoginServiceImpl) recordFailure(ctx context.Context, email, clientIP string, user *model.User) {
+	if err := ls.loginGuard.RecordFailure(ctx, email, clientIP, user); err != nil {
+		log.Logger.Errorf("Failed to record login failure: %v", err)
+	}
+}
+
+// Logout revokes the given access token and refresh token family so they can
+// no longer be used, even by someone who copied them before the cookies were
+// cleared. claims is nil when the access token has already expired; the
+// refresh token alone is enough to end the session.
+func (ls *LoginServiceImpl) Logout(ctx context.Context, claims *utils.Claims, refreshToken string) error {
+	if refreshToken != "" {
+		if err := ls.tokenService.RevokeRefreshToken(ctx, refreshToken); err != nil {
+			log.Logger.Errorf("Failed to revoke refresh token: %v", err)
+			return err
+		}
+	}
+	if claims == nil {
+		return nil
+	}
+	if claims.RegisteredClaims.ID == "" || claims.ExpiresAt == nil {
+		log.Logger.Warnf("Token of user %d has no jti or expiry, nothing to revoke", claims.ID)
+		return nil
+	}
+	err := ls.revocationStore.Revoke(ctx, claims.RegisteredClaims.ID, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		log.Logger.Errorf("Failed to revoke token: %v", err)
+		return err
+	}
+	log.Logger.Infof("Token %s of user %d revoked", claims.RegisteredClaims.ID, claims.ID)
+	return nil
+}
+
+// CheckClaims rejects revoked tokens, tokens of revoked sessions, tokens of
+// accounts that are not active, tokens issued before the user's latest
+// password change and tokens whose role no longer matches the user. It is registered with utils.RegisterClaimsChecker
+// at startup. Impersonation tokens also need their admin to still be one.
+func (ls *LoginServiceImpl) CheckClaims(claims *utils.Claims) error {
+	ctx := context.Background()
+	if claims.RegisteredClaims.ID != "" {
+		revoked, err := ls.revocationStore.IsRevoked(ctx, claims.RegisteredClaims.ID)
+		if err != nil {
+			log.Logger.Errorf("Failed to check token revocation: %v", err)
+			return err
+		}
+		if revoked {
+			return errors.New("token has been revoked")
+		}
+	}
+	if claims.SessionID != 0 {
+		if err := ls.checkSession(ctx, claims); err != nil {
+			return err
+		}
+	}
+	user, err := ls.userDao.GetUserById(ctx, claims.ID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	// Checked first so a suspended user learns why the token stopped working
+	if err := CheckAccountStatus(user.Status); err != nil {
+		return err
+	}
+	if user.CredentialVersion != claims.CredentialVersion {
+		log.Logger.Warnf("Stale credential version for user %d: token=%d, current=%d", user.ID, claims.CredentialVersion, user.CredentialVersion)
+		return errors.New("token has been invalidated")
+	}
+	if user.Role != claims.Role {
+		log.Logger.Warnf("Stale role for user %d: token=%s, current=%s", user.ID, claims.Role, user.Role)
+		return errors.New("token has been invalidated")
+	}
+	if claims.IsImpersonated() {
+		return ls.checkImpersonator(ctx, claims.ImpersonatorID)
+	}
+	return nil
+}
 
-	token, err := utils.GenerateJWTToken(&bo.UserBO{ID: user.ID, Email: user.Email})
+// checkImpersonator ends the impersonations of an admin who is no longer an
+// active admin.
+func (ls *LoginServiceImpl) checkImpersonator(ctx context.Context, adminID int) error {
+	admin, err := ls.userDao.GetUserById(ctx, adminID)
 	if err != nil {
-		return "", err
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return err
 	}
+	if admin == nil || admin.Role != model.UserRoleAdmin || admin.Status != model.UserStatusActive {
+		log.Logger.Warnf("Impersonation token rejected, admin %d can no longer impersonate", adminID)
+		return ErrImpersonationEnded
+	}
+	return nil
+}
 
-	return token, nil
+// checkSession rejects tokens of revoked or expired sessions and keeps the
+// last-seen time of the session roughly current.
+func (ls *LoginServiceImpl) checkSession(ctx context.Context, claims *utils.Claims) error {
+	session, err := ls.sessionDao.GetById(ctx, claims.SessionID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if session == nil || session.UserID != claims.ID || session.RevokedAt != nil || session.ExpiresAt.Before(now) {
+		log.Logger.Warnf("Token of user %d belongs to ended session %d", claims.ID, claims.SessionID)
+		return errors.New("session has ended")
+	}
+	if now.Sub(session.LastSeenAt) >= sessionLastSeenStep {
+		if err := ls.sessionDao.UpdateLastSeen(ctx, session.ID, now); err != nil {
+			log.Logger.Errorf("Failed to update session last seen: %v", err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// LoginGuard throttles password guessing per account and per source IP. The
+// IP is the one gin resolves with its trusted proxies, so clients cannot set
+// it with a header.
+// Accounts are tracked by email, so unknown emails are throttled exactly like
+// registered ones and the lockout reveals nothing about which exist.
+type LoginGuard interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, email, ip string, user *model.User) error
+	RecordSuccess(ctx context.Context, email string) error
+	UnlockUser(ctx context.Context, userID int) error
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type LoginGuardImpl struct {
+	userDao         dao.UserDao
+	loginFailureDao dao.LoginFailureDao
+	emailService    proxy.EmailService
+}
+
+var (
+	loginGuardInst *LoginGuardImpl
+	loginGuardOnce sync.Once
+)
+
+var (
+	ErrLoginThrottled = errors.New("too many failed login attempts")
+	ErrUserNotFound   = errors.New("user not found")
+)
+
+// LoginThrottledError tells the client how long to wait before trying again.
+// It matches ErrLoginThrottled with errors.Is.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts, the account is temporarily locked"
+	}
+	return fmt.Sprintf("too many failed login attempts, retry in %d seconds", int(e.RetryAfter.Round(time.Second).Seconds()))
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrLoginThrottled
+}
+
+var defaultLockoutConfig = &config.LockoutConfig{
+	FailureWindowMinutes:    60,
+	DelayAfterFailures:      3,
+	BaseDelaySeconds:        1,
+	MaxDelaySeconds:         300,
+	AccountLockoutThreshold: 10,
+	AccountLockoutMinutes:   30,
+	IPLockoutThreshold:      50,
+	IPLockoutMinutes:        60,
+}
+
+func GetLoginGuard() *LoginGuardImpl {
+	loginGuardOnce.Do(func() {
+		if loginGuardInst == nil {
+			loginGuardInst = &LoginGuardImpl{
+				userDao:         dao.GetUserDao(),
+				loginFailureDao: dao.GetLoginFailureDao(),
+				emailService:    proxy.GetEmailInstance(),
+			}
+		}
+	})
+	return loginGuardInst
+}
+
+func lockoutConfig() *config.LockoutConfig {
+	if config.Config.LockoutConfig == nil {
+		return defaultLockoutConfig
+	}
+	return config.Config.LockoutConfig
+}
+
+func accountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// Check fails with a *LoginThrottledError while the account or the IP is
+// delayed or locked.
+func (g *LoginGuardImpl) Check(ctx context.Context, email, ip string) error {
+	failures, err := g.loginFailureDao.GetBySubjects(ctx, []string{accountSubject(email), ipSubject(ip)})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var throttled *LoginThrottledError
+	for _, failure := range failures {
+		if !failure.BlockedUntil.After(now) {
+			continue
+		}
+		wait := failure.BlockedUntil.Sub(now)
+		if throttled == nil || wait > throttled.RetryAfter {
+			throttled = &LoginThrottledError{RetryAfter: wait, Locked: failure.Locked}
+		}
+	}
+	if throttled != nil {
+		return throttled
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login against the account and the IP and
+// blocks either of them once their thresholds are reached. The owner of a
+// registered account is emailed when it gets locked.
+func (g *LoginGuardImpl) RecordFailure(ctx context.Context, email, ip string, user *model.User) error {
+	cfg := lockoutConfig()
+	locked, err := g.recordFailure(ctx, accountSubject(email), cfg.AccountLockoutThreshold, cfg.AccountLockoutMinutes)
+	if err != nil {
+		return err
+	}
+	if locked {
+		log.Logger.Warnf("Account %s locked after repeated failed logins", email)
+		if user != nil {
+			g.notifyLockout(user, cfg.AccountLockoutMinutes)
+		}
+	}
+	locked, err = g.recordFailure(ctx, ipSubject(ip), cfg.IPLockoutThreshold, cfg.IPLockoutMinutes)
+	if err != nil {
+		return err
+	}
+	if locked {
+		log.Logger.Warnf("IP %s locked after repeated failed logins", ip)
+	}
+	return nil
+}
+
+// recordFailure returns true when this failure locked the subject.
+func (g *LoginGuardImpl) recordFailure(ctx context.Context, subject string, lockoutThreshold, lockoutMinutes int) (bool, error) {
+	cfg := lockoutConfig()
+	now := time.Now()
+	windowStart := now.Add(-time.Duration(cfg.FailureWindowMinutes) * time.Minute)
+	failure, err := g.loginFailureDao.RecordFailure(ctx, subject, now, windowStart)
+	if err != nil {
+		return false, err
+	}
+	if lockoutThreshold > 0 && failure.Failures >= lockoutThreshold {
+		if failure.Locked && failure.BlockedUntil.After(now) {
+			return false, nil
+		}
+		until := now.Add(time.Duration(lockoutMinutes) * time.Minute)
+		return true, g.loginFailureDao.Block(ctx, subject, until, true)
+	}
+	if delay := backoffDelay(cfg, failure.Failures); delay > 0 {
+		return false, g.loginFailureDao.Block(ctx, subject, now.Add(delay), false)
+	}
+	return false, nil
+}
+
+// backoffDelay doubles the wait with every failure past DelayAfterFailures.
+func backoffDelay(cfg *config.LockoutConfig, failures int) time.Duration {
+	excess := failures - cfg.DelayAfterFailures
+	if excess <= 0 || cfg.BaseDelaySeconds <= 0 {
+		return 0
+	}
+	maxDelay := time.Duration(cfg.MaxDelaySeconds) * time.Second
+	delay := time.Duration(cfg.BaseDelaySeconds) * time.Second
+	for i := 1; i < excess && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+func (g *LoginGuardImpl) notifyLockout(user *model.User, lockoutMinutes int) {
+	body := fmt.Sprintf("Your account was locked for %d minutes after repeated failed sign-in attempts. "+
+		"If this was not you, reset your password; a successful reset also unlocks the account.", lockoutMinutes)
+	if err := g.emailService.Send(body, user.Email, "CermiCraft Account Locked"); err != nil {
+		log.Logger.Errorf("Failed to send lockout email to user %d: %v", user.ID, err)
+	}
+}
+
+// RecordSuccess clears the account's failures. The IP's are left to expire,
+// otherwise one valid account would let an attacker reset the IP counter.
+func (g *LoginGuardImpl) RecordSuccess(ctx context.Context, email string) error {
+	return g.loginFailureDao.DeleteBySubject(ctx, accountSubject(email))
+}
+
+// UnlockUser lifts the lockout of the user's account, e.g. by an admin or
+// after a successful password reset.
+func (g *LoginGuardImpl) UnlockUser(ctx context.Context, userID int) error {
+	user, err := g.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if err := g.loginFailureDao.DeleteBySubject(ctx, accountSubject(user.Email)); err != nil {
+		return err
+	}
+	log.Logger.Infof("Login lockout of user %d lifted", userID)
+	return nil
+}
+
+func (g *LoginGuardImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	windowStart := now.Add(-time.Duration(lockoutConfig().FailureWindowMinutes) * time.Minute)
+	return g.loginFailureDao.DeleteExpired(ctx, windowStart, now)
+}
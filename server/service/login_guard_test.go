@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	proxy_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy/mocks"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// permissiveLoginGuard never throttles and records nothing.
+func permissiveLoginGuard() *LoginGuardImpl {
+	failureDao := new(dao_mock.LoginFailureDao)
+	failureDao.On("GetBySubjects", mock.Anything, mock.Anything).Return(nil, nil)
+	failureDao.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&model.LoginFailure{Failures: 1}, nil)
+	failureDao.On("DeleteBySubject", mock.Anything, mock.Anything).Return(nil)
+	return &LoginGuardImpl{loginFailureDao: failureDao}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	cfg := &config.LockoutConfig{DelayAfterFailures: 3, BaseDelaySeconds: 1, MaxDelaySeconds: 10}
+	assert.Equal(t, time.Duration(0), backoffDelay(cfg, 3))
+	assert.Equal(t, time.Second, backoffDelay(cfg, 4))
+	assert.Equal(t, 2*time.Second, backoffDelay(cfg, 5))
+	assert.Equal(t, 8*time.Second, backoffDelay(cfg, 7))
+	assert.Equal(t, 10*time.Second, backoffDelay(cfg, 8))
+	assert.Equal(t, 10*time.Second, backoffDelay(cfg, 100))
+}
+
+func TestLoginGuardCheck(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+
+	t.Run("Blocked IP is throttled", func(t *testing.T) {
+		failureDao := new(dao_mock.LoginFailureDao)
+		guard := &LoginGuardImpl{loginFailureDao: failureDao}
+		failureDao.On("GetBySubjects", mock.Anything, []string{"account:test@example.com", "ip:10.0.0.1"}).Return([]*model.LoginFailure{
+			{Subject: "account:test@example.com", BlockedUntil: time.Now().Add(-time.Minute)},
+			{Subject: "ip:10.0.0.1", BlockedUntil: time.Now().Add(time.Minute), Locked: true},
+		}, nil)
+
+		err := guard.Check(ctx, "Test@Example.com", "10.0.0.1")
+		assert.True(t, errors.Is(err, ErrLoginThrottled))
+		var throttled *LoginThrottledError
+		assert.True(t, errors.As(err, &throttled))
+		assert.True(t, throttled.Locked)
+		assert.InDelta(t, time.Minute.Seconds(), throttled.RetryAfter.Seconds(), 5)
+	})
+
+	t.Run("Expired blocks are ignored", func(t *testing.T) {
+		failureDao := new(dao_mock.LoginFailureDao)
+		guard := &LoginGuardImpl{loginFailureDao: failureDao}
+		failureDao.On("GetBySubjects", mock.Anything, mock.Anything).Return([]*model.LoginFailure{
+			{Subject: "account:test@example.com", Failures: 9, BlockedUntil: time.Now().Add(-time.Second)},
+		}, nil)
+
+		assert.NoError(t, guard.Check(ctx, "test@example.com", "10.0.0.1"))
+	})
+}
+
+func TestLoginGuardRecordFailure(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	user := &model.User{ID: 1, Email: "test@example.com"}
+
+	t.Run("Delays after the free attempts", func(t *testing.T) {
+		failureDao := new(dao_mock.LoginFailureDao)
+		guard := &LoginGuardImpl{loginFailureDao: failureDao}
+		failureDao.On("RecordFailure", mock.Anything, "account:test@example.com", mock.Anything, mock.Anything).Return(&model.LoginFailure{Failures: 5}, nil)
+		failureDao.On("RecordFailure", mock.Anything, "ip:10.0.0.1", mock.Anything, mock.Anything).Return(&model.LoginFailure{Failures: 2}, nil)
+		failureDao.On("Block", mock.Anything, "account:test@example.com", mock.MatchedBy(func(until time.Time) bool {
+			// Two failures past the default threshold of three
+			return until.After(time.Now().Add(time.Second)) && until.Before(time.Now().Add(3*time.Second))
+		}), false).Return(nil)
+
+		assert.NoError(t, guard.RecordFailure(ctx, user.Email, "10.0.0.1", user))
+		failureDao.AssertExpectations(t)
+	})
+
+	t.Run("Locks the account and emails the owner", func(t *testing.T) {
+		failureDao := new(dao_mock.LoginFailureDao)
+		emailSender := new(proxy_mock.EmailService)
+		guard := &LoginGuardImpl{loginFailureDao: failureDao, emailService: emailSender}
+		failureDao.On("RecordFailure", mock.Anything, "account:test@example.com", mock.Anything, mock.Anything).Return(&model.LoginFailure{Failures: 10}, nil)
+		failureDao.On("RecordFailure", mock.Anything, "ip:10.0.0.1", mock.Anything, mock.Anything).Return(&model.LoginFailure{Failures: 1}, nil)
+		failureDao.On("Block", mock.Anything, "account:test@example.com", mock.MatchedBy(func(until time.Time) bool {
+			return until.After(time.Now().Add(29 * time.Minute))
+		}), true).Return(nil)
+		emailSender.On("Send", mock.Anything, user.Email, mock.Anything).Return(nil)
+
+		assert.NoError(t, guard.RecordFailure(ctx, user.Email, "10.0.0.1", user))
+		failureDao.AssertExpectations(t)
+		emailSender.AssertExpectations(t)
+	})
+
+	t.Run("Unknown account is locked without email", func(t *testing.T) {
+		failureDao := new(dao_mock.LoginFailureDao)
+		emailSender := new(proxy_mock.EmailService)
+		guard := &LoginGuardImpl{loginFailureDao: failureDao, emailService: emailSender}
+		failureDao.On("RecordFailure", mock.Anything, "account:ghost@example.com", mock.Anything, mock.Anything).Return(&model.LoginFailure{Failures: 10}, nil)
+		failureDao.On("RecordFailure", mock.Anything, "ip:10.0.0.1", mock.Anything, mock.Anything).Return(&model.LoginFailure{Failures: 1}, nil)
+		failureDao.On("Block", mock.Anything, "account:ghost@example.com", mock.Anything, true).Return(nil)
+
+		assert.NoError(t, guard.RecordFailure(ctx, "ghost@example.com", "10.0.0.1", nil))
+		emailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already locked account is not emailed again", func(t *testing.T) {
+		failureDao := new(dao_mock.LoginFailureDao)
+		emailSender := new(proxy_mock.EmailService)
+		guard := &LoginGuardImpl{loginFailureDao: failureDao, emailService: emailSender}
+		failureDao.On("RecordFailure", mock.Anything, "account:test@example.com", mock.Anything, mock.Anything).Return(&model.LoginFailure{
+			Failures: 11, Locked: true, BlockedUntil: time.Now().Add(time.Minute),
+		}, nil)
+		failureDao.On("RecordFailure", mock.Anything, "ip:10.0.0.1", mock.Anything, mock.Anything).Return(&model.LoginFailure{Failures: 1}, nil)
+
+		assert.NoError(t, guard.RecordFailure(ctx, user.Email, "10.0.0.1", user))
+		failureDao.AssertNotCalled(t, "Block", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		emailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLoginThrottled(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	userDao := new(dao_mock.UserDao)
+	failureDao := new(dao_mock.LoginFailureDao)
+	loginService := &LoginServiceImpl{
+		userDao:    userDao,
+		loginGuard: &LoginGuardImpl{loginFailureDao: failureDao},
+	}
+	failureDao.On("GetBySubjects", mock.Anything, mock.Anything).Return([]*model.LoginFailure{
+		{Subject: "account:test@example.com", BlockedUntil: time.Now().Add(time.Minute), Locked: true},
+	}, nil)
+
+	_, err := loginService.Login(ctx, "customer", "test@example.com", "correctpassword", "10.0.0.1")
+	assert.True(t, errors.Is(err, ErrLoginThrottled))
+	// The password is not even checked while locked
+	userDao.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+func TestUnlockUser(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	userDao := new(dao_mock.UserDao)
+	failureDao := new(dao_mock.LoginFailureDao)
+	guard := &LoginGuardImpl{userDao: userDao, loginFailureDao: failureDao}
+	userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Email: "Test@example.com"}, nil)
+	userDao.On("GetUserById", mock.Anything, 2).Return(nil, nil)
+	failureDao.On("DeleteBySubject", mock.Anything, "account:test@example.com").Return(nil)
+
+	assert.NoError(t, guard.UnlockUser(ctx, 1))
+	assert.Equal(t, ErrUserNotFound, guard.UnlockUser(ctx, 2))
+	failureDao.AssertExpectations(t)
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -21,7 +24,12 @@ func initEnv() {
 		},
 		EmailConfig: &config.EmailConfig{},
 		KafkaConfig: &config.KafkaConfig{
-			UserActivatedTopic: "user_activated",
+			UserActivatedTopic:     "user_activated",
+			UserStatusChangedTopic: "user_status_changed",
+		},
+		AuthConfig: &config.AuthConfig{
+			AccessTokenTTLMinutes: 15,
+			RefreshTokenTTLHours:  720,
 		},
 	}
 	log.InitLogger()
@@ -36,38 +44,98 @@ func TestLogin(t *testing.T) {
 	initEnv()
 	ctx := context.Background()
 	mockDao := new(mocks.UserDao)
+	refreshTokenDao := new(mocks.RefreshTokenDao)
+	userMfaDao := new(mocks.UserMfaDao)
+	challengeDao := new(mocks.MfaChallengeDao)
 	loginService := &LoginServiceImpl{
-		userDao: mockDao,
+		userDao:      mockDao,
+		tokenService: &TokenServiceImpl{userDao: mockDao, refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDaoWithDefaults()},
+		mfaService:   &MfaServiceImpl{userMfaDao: userMfaDao, challengeDao: challengeDao},
+		loginGuard:   permissiveLoginGuard(),
 	}
+	refreshTokenDao.On("Create", mock.Anything, mock.Anything).Return(nil)
+	userMfaDao.On("GetByUserId", mock.Anything, mock.Anything).Return(nil, nil)
 	hashedPwd, _ := HashPassword("correctpassword")
-	existUser := &model.User{ID: 1, Email: "test@example.com", Password: hashedPwd}
+	existUser := &model.User{ID: 1, Email: "test@example.com", Password: hashedPwd, Status: model.UserStatusActive, Role: model.UserRoleCustomer}
+	merchant := &model.User{ID: 2, Email: "merchant@example.com", Password: hashedPwd, Status: model.UserStatusActive, Role: model.UserRoleMerchant}
 	nonExistEmail := "nonexistent@example.com"
 	mockDao.On("GetUserByEmail", mock.Anything, existUser.Email).Return(existUser, nil)
+	mockDao.On("GetUserByEmail", mock.Anything, merchant.Email).Return(merchant, nil)
 	mockDao.On("GetUserByEmail", mock.Anything, nonExistEmail).Return(nil, nil)
 
 	tests := []struct {
+		name     string
+		client   string
 		email    string
 		password string
 		expected string
 		hasError bool
 	}{
-		{existUser.Email, "correctpassword", "token", false},
-		{nonExistEmail, "anyPassword", "", true},
-		{existUser.Email, "wrongpassword", "", true},
+		{"customer login", utils.AudienceCustomer, existUser.Email, "correctpassword", "token", false},
+		{"merchant login", utils.AudienceMerchant, merchant.Email, "correctpassword", "token", false},
+		{"unknown email", utils.AudienceCustomer, nonExistEmail, "anyPassword", "", true},
+		{"wrong password", utils.AudienceCustomer, existUser.Email, "wrongpassword", "", true},
+		{"customer on merchant client", utils.AudienceMerchant, existUser.Email, "correctpassword", "", true},
+		{"merchant on customer client", utils.AudienceCustomer, merchant.Email, "correctpassword", "", true},
 	}
 
 	for _, test := range tests {
-		t.Run(test.email, func(t *testing.T) {
-			token, err := loginService.Login(ctx, test.email, test.password)
+		t.Run(test.name, func(t *testing.T) {
+			result, err := loginService.Login(ctx, test.client, test.email, test.password, "127.0.0.1")
 			if (err != nil) != test.hasError {
 				t.Errorf("expected error: %v, got: %v", test.hasError, err)
 			}
-			if test.expected == "token" && token == "" {
-				t.Errorf("expected token: %s, got: %s", test.expected, token)
+			if test.expected == "token" && (result == nil || result.Tokens == nil || result.Tokens.AccessToken == "" || result.Tokens.RefreshToken == "") {
+				t.Errorf("expected tokens, got: %v", result)
 			}
 		})
 	}
 }
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	mockDao := new(mocks.UserDao)
+	loginService := &LoginServiceImpl{userDao: mockDao, loginGuard: permissiveLoginGuard()}
+	hashedPwd, _ := HashPassword("correctpassword")
+	mockDao.On("GetUserByEmail", mock.Anything, "test@example.com").Return(&model.User{
+		ID: 1, Email: "test@example.com", Password: hashedPwd, Status: model.UserStatusActive, Role: model.UserRoleCustomer,
+	}, nil)
+	mockDao.On("GetUserByEmail", mock.Anything, "nonexistent@example.com").Return(nil, nil)
+
+	// Whether the email has an account must not show in the answer
+	_, unknownErr := loginService.Login(ctx, utils.AudienceCustomer, "nonexistent@example.com", "correctpassword", "127.0.0.1")
+	_, wrongErr := loginService.Login(ctx, utils.AudienceCustomer, "test@example.com", "wrongpassword", "127.0.0.1")
+	assert.ErrorIs(t, unknownErr, ErrInvalidCredentials)
+	assert.ErrorIs(t, wrongErr, ErrInvalidCredentials)
+	assert.Equal(t, unknownErr.Error(), wrongErr.Error())
+}
+
+func TestLoginWithMfa(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	mockDao := new(mocks.UserDao)
+	userMfaDao := new(mocks.UserMfaDao)
+	challengeDao := new(mocks.MfaChallengeDao)
+	loginService := &LoginServiceImpl{
+		userDao:    mockDao,
+		mfaService: &MfaServiceImpl{userMfaDao: userMfaDao, challengeDao: challengeDao},
+		loginGuard: permissiveLoginGuard(),
+	}
+	hashedPwd, _ := HashPassword("correctpassword")
+	merchant := &model.User{ID: 2, Email: "merchant@example.com", Password: hashedPwd, Status: model.UserStatusActive, Role: model.UserRoleMerchant}
+	mockDao.On("GetUserByEmail", mock.Anything, merchant.Email).Return(merchant, nil)
+	userMfaDao.On("GetByUserId", mock.Anything, 2).Return(&model.UserMfa{UserID: 2, Enabled: true}, nil)
+	challengeDao.On("Create", mock.Anything, mock.MatchedBy(func(arg *model.MfaChallenge) bool {
+		return arg.UserID == 2 && arg.Audience == utils.AudienceMerchant && arg.ExpiresAt.After(time.Now())
+	})).Return(nil)
+
+	result, err := loginService.Login(ctx, utils.AudienceMerchant, merchant.Email, "correctpassword", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Nil(t, result.Tokens)
+	assert.NotEmpty(t, result.MfaChallenge)
+	challengeDao.AssertExpectations(t)
+}
 func TestGetLoginService(t *testing.T) {
 	initEnv()
 
@@ -84,3 +152,57 @@ func TestGetLoginService(t *testing.T) {
 		t.Error("Expected userDao to be initialized, got nil")
 	}
 }
+
+func TestCheckClaims(t *testing.T) {
+	initEnv()
+	mockDao := new(mocks.UserDao)
+	loginService := &LoginServiceImpl{
+		userDao: mockDao,
+	}
+	mockDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleCustomer, CredentialVersion: 2}, nil)
+	mockDao.On("GetUserById", mock.Anything, 2).Return(nil, nil)
+
+	assert.NoError(t, loginService.CheckClaims(&utils.Claims{ID: 1, Role: model.UserRoleCustomer, CredentialVersion: 2}))
+	assert.Error(t, loginService.CheckClaims(&utils.Claims{ID: 1, Role: model.UserRoleCustomer, CredentialVersion: 1}))
+	assert.Error(t, loginService.CheckClaims(&utils.Claims{ID: 1, Role: model.UserRoleMerchant, CredentialVersion: 2}))
+	assert.Error(t, loginService.CheckClaims(&utils.Claims{ID: 2}))
+}
+
+func TestLogout(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	mockDao := new(mocks.UserDao)
+	refreshTokenDao := new(mocks.RefreshTokenDao)
+	store := NewMemoryTokenRevocationStore()
+	loginService := &LoginServiceImpl{
+		userDao:         mockDao,
+		revocationStore: store,
+		tokenService:    &TokenServiceImpl{userDao: mockDao, refreshTokenDao: refreshTokenDao, sessionDao: sessionDaoWithDefaults()},
+	}
+	mockDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleCustomer}, nil)
+	refreshTokenDao.On("GetByTokenHash", mock.Anything, hashToken("refresh-1")).Return(&model.RefreshToken{ID: 1, FamilyID: "family-1"}, nil)
+	refreshTokenDao.On("RevokeFamily", mock.Anything, "family-1", mock.Anything).Return(nil)
+	claims := &utils.Claims{
+		ID:   1,
+		Role: model.UserRoleCustomer,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	assert.NoError(t, loginService.CheckClaims(claims))
+	assert.NoError(t, loginService.Logout(ctx, claims, "refresh-1"))
+	refreshTokenDao.AssertExpectations(t)
+	assert.Error(t, loginService.CheckClaims(claims))
+
+	// Other tokens of the same user stay valid
+	other := &utils.Claims{ID: 1, Role: model.UserRoleCustomer, RegisteredClaims: jwt.RegisteredClaims{ID: "jti-2"}}
+	assert.NoError(t, loginService.CheckClaims(other))
+
+	// With an expired access token the refresh token still ends the session
+	refreshTokenDao.On("GetByTokenHash", mock.Anything, hashToken("refresh-2")).Return(&model.RefreshToken{ID: 2, FamilyID: "family-2"}, nil)
+	refreshTokenDao.On("RevokeFamily", mock.Anything, "family-2", mock.Anything).Return(nil)
+	assert.NoError(t, loginService.Logout(ctx, nil, "refresh-2"))
+	refreshTokenDao.AssertExpectations(t)
+}
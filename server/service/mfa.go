@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+// MfaEnrollment is what the user needs to add the account to an
+// authenticator app. URI is the payload of the QR code.
+type MfaEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type MfaService interface {
+	BeginEnrollment(ctx context.Context, userID int) (*MfaEnrollment, error)
+	ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error)
+	Disable(ctx context.Context, userID int, password, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error)
+	IsEnabled(ctx context.Context, userID int) (bool, error)
+	VerifyCode(ctx context.Context, userID int, code string) error
+	CreateChallenge(ctx context.Context, userID int, audience string) (string, error)
+	VerifyChallenge(ctx context.Context, audience, challenge, code string) (*AuthTokens, error)
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type MfaServiceImpl struct {
+	userDao         dao.UserDao
+	userMfaDao      dao.UserMfaDao
+	recoveryCodeDao dao.UserRecoveryCodeDao
+	challengeDao    dao.MfaChallengeDao
+	tokenService    TokenService
+	txBeginner      repository.TxBeginner
+}
+
+var (
+	mfaServiceInst *MfaServiceImpl
+	mfaServiceOnce sync.Once
+)
+
+const (
+	mfaIssuer = "CeramiCraft"
+	// MfaChallengeTTL is how long a user has to enter the second factor after
+	// the password was accepted.
+	MfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+	// A pending enrollment is spent after this many codes and has to be
+	// started again, with a new secret
+	mfaEnrollmentMaxAttempts = 5
+	recoveryCodeCount        = 10
+	// Unambiguous lowercase letters and digits, 10 of them give ~49 bits
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength   = 10
+)
+
+var (
+	ErrMfaAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMfaNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrMfaNotEnrolled      = errors.New("two-factor enrollment has not been started")
+	ErrMfaEnrollmentSpent  = errors.New("too many wrong codes, start the two-factor enrollment again")
+	ErrInvalidMfaCode      = errors.New("invalid authentication code")
+	ErrInvalidMfaChallenge = errors.New("invalid or expired login challenge")
+)
+
+func GetMfaService() *MfaServiceImpl {
+	mfaServiceOnce.Do(func() {
+		if mfaServiceInst == nil {
+			mfaServiceInst = &MfaServiceImpl{
+				userDao:         dao.GetUserDao(),
+				userMfaDao:      dao.GetUserMfaDao(),
+				recoveryCodeDao: dao.GetUserRecoveryCodeDao(),
+				challengeDao:    dao.GetMfaChallengeDao(),
+				tokenService:    GetTokenService(),
+				txBeginner:      repository.DB,
+			}
+		}
+	})
+	return mfaServiceInst
+}
+
+// BeginEnrollment generates a new TOTP secret. 2FA is only enforced once the
+// user confirms it with a code, so an abandoned enrollment has no effect.
+func (ms *MfaServiceImpl) BeginEnrollment(ctx context.Context, userID int) (*MfaEnrollment, error) {
+	user, err := ms.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	mfa, err := ms.userMfaDao.GetByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.Enabled {
+		return nil, ErrMfaAlreadyEnabled
+	}
+	secret, err := generateTotpSecret()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate totp secret: %v", err)
+		return nil, err
+	}
+	err = ms.userMfaDao.Upsert(ctx, &model.UserMfa{UserID: userID, Secret: secret, CreatedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("2FA enrollment started for user %d", userID)
+	return &MfaEnrollment{Secret: secret, URI: totpURI(mfaIssuer, user.Email, secret)}, nil
+}
+
+// ConfirmEnrollment enables 2FA once the user proves the authenticator works,
+// and returns the recovery codes. They are shown only this once.
+func (ms *MfaServiceImpl) ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	mfa, err := ms.userMfaDao.GetByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMfaNotEnrolled
+	}
+	if mfa.Enabled {
+		return nil, ErrMfaAlreadyEnabled
+	}
+	// The attempt is used up before the code is checked, so concurrent
+	// guesses cannot get past the limit
+	left, err := ms.userMfaDao.IncrementEnrollmentAttempts(ctx, userID, mfaEnrollmentMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !left {
+		log.Logger.Warnf("No 2FA enrollment attempts left for user %d", userID)
+		return nil, ErrMfaEnrollmentSpent
+	}
+	step, ok := matchTotp(mfa.Secret, code, time.Now())
+	if !ok {
+		log.Logger.Warnf("Wrong 2FA enrollment code for user %d", userID)
+		return nil, ErrInvalidMfaCode
+	}
+	codes, records, err := generateRecoveryCodes(userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to generate recovery codes: %v", err)
+		return nil, err
+	}
+	err = ms.txBeginner.Transaction(func(tx *gorm.DB) error {
+		if err := ms.userMfaDao.EnableInTransaction(ctx, userID, step, time.Now(), tx); err != nil {
+			log.Logger.Errorf("Failed to enable 2FA: %v", err)
+			return err
+		}
+		return ms.replaceRecoveryCodes(ctx, userID, records, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("2FA enabled for user %d", userID)
+	return codes, nil
+}
+
+// Disable turns 2FA off. It asks for both the password and a current code so
+// a stolen session alone cannot remove the second factor.
+func (ms *MfaServiceImpl) Disable(ctx context.Context, userID int, password, code string) error {
+	user, err := ms.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if VerifyPassword(user.Password, password) != nil {
+		log.Logger.Warnf("Incorrect password when disabling 2FA for user %d", userID)
+		return ErrIncorrectPassword
+	}
+	mfa, err := ms.enabledMfa(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := ms.verifySecondFactor(ctx, mfa, code); err != nil {
+		return err
+	}
+	err = ms.txBeginner.Transaction(func(tx *gorm.DB) error {
+		if err := ms.userMfaDao.DeleteByUserId(ctx, userID, tx); err != nil {
+			log.Logger.Errorf("Failed to delete 2FA secret: %v", err)
+			return err
+		}
+		if err := ms.recoveryCodeDao.DeleteByUserId(ctx, userID, tx); err != nil {
+			log.Logger.Errorf("Failed to delete recovery codes: %v", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Logger.Infof("2FA disabled for user %d", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user.
+func (ms *MfaServiceImpl) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	mfa, err := ms.enabledMfa(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ms.verifySecondFactor(ctx, mfa, code); err != nil {
+		return nil, err
+	}
+	codes, records, err := generateRecoveryCodes(userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to generate recovery codes: %v", err)
+		return nil, err
+	}
+	err = ms.txBeginner.Transaction(func(tx *gorm.DB) error {
+		return ms.replaceRecoveryCodes(ctx, userID, records, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("Recovery codes regenerated for user %d", userID)
+	return codes, nil
+}
+
+func (ms *MfaServiceImpl) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	mfa, err := ms.userMfaDao.GetByUserId(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return mfa != nil && mfa.Enabled, nil
+}
+
+// VerifyCode checks a TOTP or recovery code of a user with 2FA enabled, for
+// actions that make the user confirm who they are.
+func (ms *MfaServiceImpl) VerifyCode(ctx context.Context, userID int, code string) error {
+	mfa, err := ms.enabledMfa(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return ms.verifySecondFactor(ctx, mfa, code)
+}
+
+// CreateChallenge records that the user passed the password check and returns
+// the opaque challenge the client presents together with the second factor.
+func (ms *MfaServiceImpl) CreateChallenge(ctx context.Context, userID int, audience string) (string, error) {
+	challenge, err := generateOpaqueToken()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate mfa challenge: %v", err)
+		return "", err
+	}
+	err = ms.challengeDao.Create(ctx, &model.MfaChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(challenge),
+		Audience:  audience,
+		ExpiresAt: time.Now().Add(MfaChallengeTTL),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// VerifyChallenge completes a login that is waiting for the second factor.
+// The code is either a TOTP code or an unused recovery code.
+func (ms *MfaServiceImpl) VerifyChallenge(ctx context.Context, audience, challenge, code string) (*AuthTokens, error) {
+	stored, err := ms.challengeDao.GetByTokenHash(ctx, hashToken(challenge))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.ExpiresAt.Before(time.Now()) || stored.Audience != audience || stored.Attempts >= mfaChallengeMaxAttempts {
+		return nil, ErrInvalidMfaChallenge
+	}
+	mfa, err := ms.enabledMfa(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, ErrMfaNotEnabled) {
+			return nil, ErrInvalidMfaChallenge
+		}
+		return nil, err
+	}
+	if err := ms.verifySecondFactor(ctx, mfa, code); err != nil {
+		if errors.Is(err, ErrInvalidMfaCode) {
+			if err := ms.challengeDao.IncrementAttempts(ctx, stored.ID); err != nil {
+				log.Logger.Errorf("Failed to record mfa challenge attempt: %v", err)
+			}
+		}
+		return nil, err
+	}
+	consumed, err := ms.challengeDao.Delete(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidMfaChallenge
+	}
+	user, err := ms.userDao.GetUserById(ctx, stored.UserID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil || !CanUseClient(user.Role, audience) {
+		return nil, ErrInvalidMfaChallenge
+	}
+	// The account may have been suspended since the first factor
+	if err := CheckAccountStatus(user.Status); err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("2FA login completed for user %d", user.ID)
+	return ms.tokenService.IssueTokens(ctx, user, audience)
+}
+
+func (ms *MfaServiceImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return ms.challengeDao.DeleteExpired(ctx, now)
+}
+
+func (ms *MfaServiceImpl) enabledMfa(ctx context.Context, userID int) (*model.UserMfa, error) {
+	mfa, err := ms.userMfaDao.GetByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return nil, ErrMfaNotEnabled
+	}
+	return mfa, nil
+}
+
+// verifySecondFactor accepts a TOTP code that has not been used yet, or
+// consumes a recovery code.
+func (ms *MfaServiceImpl) verifySecondFactor(ctx context.Context, mfa *model.UserMfa, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := matchTotp(mfa.Secret, code, time.Now()); ok {
+		fresh, err := ms.userMfaDao.UpdateLastUsedStep(ctx, mfa.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			log.Logger.Warnf("Replayed TOTP code for user %d", mfa.UserID)
+			return ErrInvalidMfaCode
+		}
+		return nil
+	}
+	used, err := ms.recoveryCodeDao.MarkUsed(ctx, mfa.UserID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		log.Logger.Warnf("Wrong 2FA code for user %d", mfa.UserID)
+		return ErrInvalidMfaCode
+	}
+	log.Logger.Infof("Recovery code used by user %d", mfa.UserID)
+	return nil
+}
+
+func (ms *MfaServiceImpl) replaceRecoveryCodes(ctx context.Context, userID int, records []*model.UserRecoveryCode, tx *gorm.DB) error {
+	if err := ms.recoveryCodeDao.DeleteByUserId(ctx, userID, tx); err != nil {
+		log.Logger.Errorf("Failed to delete recovery codes: %v", err)
+		return err
+	}
+	if err := ms.recoveryCodeDao.CreateBatch(ctx, records, tx); err != nil {
+		log.Logger.Errorf("Failed to create recovery codes: %v", err)
+		return err
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns the codes to show the user, formatted as
+// xxxxx-xxxxx, and the hashed records to store.
+func generateRecoveryCodes(userID int) ([]string, []*model.UserRecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]*model.UserRecoveryCode, 0, recoveryCodeCount)
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for range recoveryCodeCount {
+		buf := make([]byte, recoveryCodeLength)
+		for i := range buf {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, nil, err
+			}
+			buf[i] = recoveryCodeAlphabet[n.Int64()]
+		}
+		code := string(buf)
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		records = append(records, &model.UserRecoveryCode{UserID: userID, CodeHash: hashToken(code), CreatedAt: time.Now()})
+	}
+	return codes, records, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
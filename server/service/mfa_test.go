@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Secret "12345678901234567890" of the RFC 6238 test vectors
+const rfcTotpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTotpCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		code, err := totpCode(rfcTotpSecret, totpStep(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+}
+
+func TestMatchTotp(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step, ok := matchTotp(rfcTotpSecret, "081804", now)
+	assert.True(t, ok)
+	assert.Equal(t, totpStep(now), step)
+
+	// Codes of the previous step are still accepted
+	_, ok = matchTotp(rfcTotpSecret, "081804", now.Add(totpPeriod*time.Second))
+	assert.True(t, ok)
+	_, ok = matchTotp(rfcTotpSecret, "081804", now.Add(3*totpPeriod*time.Second))
+	assert.False(t, ok)
+	_, ok = matchTotp(rfcTotpSecret, "000000", now)
+	assert.False(t, ok)
+}
+
+func TestTotpURI(t *testing.T) {
+	uri := totpURI("CeramiCraft", "test@example.com", rfcTotpSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/CeramiCraft:test@example.com?"))
+	assert.Contains(t, uri, "secret="+rfcTotpSecret)
+	assert.Contains(t, uri, "issuer=CeramiCraft")
+}
+
+func currentTotp(t *testing.T, secret string) string {
+	code, err := totpCode(secret, totpStep(time.Now()))
+	assert.NoError(t, err)
+	return code
+}
+
+func TestBeginEnrollment(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+
+	t.Run("New enrollment", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		userMfaDao := new(dao_mock.UserMfaDao)
+		service := &MfaServiceImpl{userDao: userDao, userMfaDao: userMfaDao}
+		userDao.On("GetUserById", ctx, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Email: "test@example.com"}, nil)
+		userMfaDao.On("GetByUserId", ctx, 1).Return(nil, nil)
+		userMfaDao.On("Upsert", ctx, mock.MatchedBy(func(arg *model.UserMfa) bool {
+			return arg.UserID == 1 && !arg.Enabled && arg.Secret != ""
+		})).Return(nil)
+
+		enrollment, err := service.BeginEnrollment(ctx, 1)
+		assert.NoError(t, err)
+		assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+		userMfaDao.AssertExpectations(t)
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		userMfaDao := new(dao_mock.UserMfaDao)
+		service := &MfaServiceImpl{userDao: userDao, userMfaDao: userMfaDao}
+		userDao.On("GetUserById", ctx, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive}, nil)
+		userMfaDao.On("GetByUserId", ctx, 1).Return(&model.UserMfa{UserID: 1, Enabled: true}, nil)
+
+		_, err := service.BeginEnrollment(ctx, 1)
+		assert.ErrorIs(t, err, ErrMfaAlreadyEnabled)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		service := &MfaServiceImpl{userDao: userDao}
+		userDao.On("GetUserById", ctx, 1).Return(nil, nil)
+
+		_, err := service.BeginEnrollment(ctx, 1)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestConfirmEnrollment(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	secret, _ := generateTotpSecret()
+
+	t.Run("Valid code enables 2FA", func(t *testing.T) {
+		userMfaDao := new(dao_mock.UserMfaDao)
+		recoveryCodeDao := new(dao_mock.UserRecoveryCodeDao)
+		service := &MfaServiceImpl{
+			userMfaDao:      userMfaDao,
+			recoveryCodeDao: recoveryCodeDao,
+			txBeginner:      &fakeTx{DB: initMemDb(t)},
+		}
+		userMfaDao.On("GetByUserId", ctx, 1).Return(&model.UserMfa{UserID: 1, Secret: secret}, nil)
+		userMfaDao.On("IncrementEnrollmentAttempts", ctx, 1, mfaEnrollmentMaxAttempts).Return(true, nil)
+		userMfaDao.On("EnableInTransaction", ctx, 1, mock.AnythingOfType("int64"), mock.Anything, mock.Anything).Return(nil)
+		recoveryCodeDao.On("DeleteByUserId", ctx, 1, mock.Anything).Return(nil)
+		var stored []*model.UserRecoveryCode
+		recoveryCodeDao.On("CreateBatch", ctx, mock.MatchedBy(func(arg []*model.UserRecoveryCode) bool {
+			stored = arg
+			return true
+		}), mock.Anything).Return(nil)
+
+		codes, err := service.ConfirmEnrollment(ctx, 1, currentTotp(t, secret))
+		assert.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+		assert.Len(t, stored, recoveryCodeCount)
+		// Only hashes are stored
+		assert.Equal(t, hashToken(normalizeRecoveryCode(codes[0])), stored[0].CodeHash)
+		userMfaDao.AssertExpectations(t)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		userMfaDao := new(dao_mock.UserMfaDao)
+		service := &MfaServiceImpl{userMfaDao: userMfaDao}
+		userMfaDao.On("GetByUserId", ctx, 1).Return(&model.UserMfa{UserID: 1, Secret: rfcTotpSecret}, nil)
+		userMfaDao.On("IncrementEnrollmentAttempts", ctx, 1, mfaEnrollmentMaxAttempts).Return(true, nil)
+
+		_, err := service.ConfirmEnrollment(ctx, 1, "000000")
+		assert.ErrorIs(t, err, ErrInvalidMfaCode)
+		userMfaDao.AssertExpectations(t)
+	})
+
+	t.Run("Attempts used up", func(t *testing.T) {
+		userMfaDao := new(dao_mock.UserMfaDao)
+		service := &MfaServiceImpl{userMfaDao: userMfaDao}
+		userMfaDao.On("GetByUserId", ctx, 1).Return(&model.UserMfa{UserID: 1, Secret: secret, EnrollmentAttempts: mfaEnrollmentMaxAttempts}, nil)
+		userMfaDao.On("IncrementEnrollmentAttempts", ctx, 1, mfaEnrollmentMaxAttempts).Return(false, nil)
+
+		// Even the right code is refused until the enrollment is started again
+		_, err := service.ConfirmEnrollment(ctx, 1, currentTotp(t, secret))
+		assert.ErrorIs(t, err, ErrMfaEnrollmentSpent)
+		userMfaDao.AssertNotCalled(t, "EnableInTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		userMfaDao := new(dao_mock.UserMfaDao)
+		service := &MfaServiceImpl{userMfaDao: userMfaDao}
+		userMfaDao.On("GetByUserId", ctx, 1).Return(nil, nil)
+
+		_, err := service.ConfirmEnrollment(ctx, 1, "123456")
+		assert.ErrorIs(t, err, ErrMfaNotEnrolled)
+	})
+}
+
+func TestDisableMfa(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	secret, _ := generateTotpSecret()
+	hashedPwd, _ := HashPassword("password1")
+
+	t.Run("Password and code disable 2FA", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		userMfaDao := new(dao_mock.UserMfaDao)
+		recoveryCodeDao := new(dao_mock.UserRecoveryCodeDao)
+		service := &MfaServiceImpl{
+			userDao:         userDao,
+			userMfaDao:      userMfaDao,
+			recoveryCodeDao: recoveryCodeDao,
+			txBeginner:      &fakeTx{DB: initMemDb(t)},
+		}
+		userDao.On("GetUserById", ctx, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Password: hashedPwd}, nil)
+		userMfaDao.On("GetByUserId", ctx, 1).Return(&model.UserMfa{UserID: 1, Secret: secret, Enabled: true}, nil)
+		userMfaDao.On("UpdateLastUsedStep", ctx, 1, mock.Anything).Return(true, nil)
+		userMfaDao.On("DeleteByUserId", ctx, 1, mock.Anything).Return(nil)
+		recoveryCodeDao.On("DeleteByUserId", ctx, 1, mock.Anything).Return(nil)
+
+		err := service.Disable(ctx, 1, "password1", currentTotp(t, secret))
+		assert.NoError(t, err)
+		userMfaDao.AssertExpectations(t)
+		recoveryCodeDao.AssertExpectations(t)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		service := &MfaServiceImpl{userDao: userDao}
+		userDao.On("GetUserById", ctx, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Password: hashedPwd}, nil)
+
+		err := service.Disable(ctx, 1, "wrong1", "123456")
+		assert.ErrorIs(t, err, ErrIncorrectPassword)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		service := &MfaServiceImpl{userDao: userDao}
+		userDao.On("GetUserById", ctx, 1).Return(nil, nil)
+
+		err := service.Disable(ctx, 1, "password1", "123456")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	secret, _ := generateTotpSecret()
+	user := &model.User{ID: 1, Status: model.UserStatusActive, Email: "merchant@example.com", Role: model.UserRoleMerchant}
+	challenge := func() *model.MfaChallenge {
+		return &model.MfaChallenge{ID: 7, UserID: 1, Audience: utils.AudienceMerchant, ExpiresAt: time.Now().Add(time.Minute)}
+	}
+	newService := func() (*MfaServiceImpl, *dao_mock.UserMfaDao, *dao_mock.UserRecoveryCodeDao, *dao_mock.MfaChallengeDao) {
+		userDao := new(dao_mock.UserDao)
+		userMfaDao := new(dao_mock.UserMfaDao)
+		recoveryCodeDao := new(dao_mock.UserRecoveryCodeDao)
+		challengeDao := new(dao_mock.MfaChallengeDao)
+		refreshTokenDao := new(dao_mock.RefreshTokenDao)
+		userDao.On("GetUserById", ctx, 1).Return(user, nil)
+		userMfaDao.On("GetByUserId", ctx, 1).Return(&model.UserMfa{UserID: 1, Secret: secret, Enabled: true}, nil)
+		refreshTokenDao.On("Create", ctx, mock.Anything).Return(nil)
+		return &MfaServiceImpl{
+			userDao:         userDao,
+			userMfaDao:      userMfaDao,
+			recoveryCodeDao: recoveryCodeDao,
+			challengeDao:    challengeDao,
+			tokenService:    &TokenServiceImpl{userDao: userDao, refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDaoWithDefaults()},
+		}, userMfaDao, recoveryCodeDao, challengeDao
+	}
+
+	t.Run("TOTP code completes login", func(t *testing.T) {
+		service, userMfaDao, _, challengeDao := newService()
+		challengeDao.On("GetByTokenHash", ctx, hashToken("challenge")).Return(challenge(), nil)
+		userMfaDao.On("UpdateLastUsedStep", ctx, 1, mock.AnythingOfType("int64")).Return(true, nil)
+		challengeDao.On("Delete", ctx, int64(7)).Return(true, nil)
+
+		tokens, err := service.VerifyChallenge(ctx, utils.AudienceMerchant, "challenge", currentTotp(t, secret))
+		assert.NoError(t, err)
+		claims, err := utils.ParseJWTToken(tokens.AccessToken)
+		assert.NoError(t, err)
+		assert.True(t, claims.HasAudience(utils.AudienceMerchant))
+	})
+
+	t.Run("Recovery code completes login", func(t *testing.T) {
+		service, _, recoveryCodeDao, challengeDao := newService()
+		challengeDao.On("GetByTokenHash", ctx, hashToken("challenge")).Return(challenge(), nil)
+		recoveryCodeDao.On("MarkUsed", ctx, 1, hashToken("abcdefghjk"), mock.Anything).Return(true, nil)
+		challengeDao.On("Delete", ctx, int64(7)).Return(true, nil)
+
+		_, err := service.VerifyChallenge(ctx, utils.AudienceMerchant, "challenge", "ABCDE-FGHJK")
+		assert.NoError(t, err)
+		recoveryCodeDao.AssertExpectations(t)
+	})
+
+	t.Run("Replayed TOTP code", func(t *testing.T) {
+		service, userMfaDao, recoveryCodeDao, challengeDao := newService()
+		challengeDao.On("GetByTokenHash", ctx, hashToken("challenge")).Return(challenge(), nil)
+		userMfaDao.On("UpdateLastUsedStep", ctx, 1, mock.Anything).Return(false, nil)
+		challengeDao.On("IncrementAttempts", ctx, int64(7)).Return(nil)
+
+		_, err := service.VerifyChallenge(ctx, utils.AudienceMerchant, "challenge", currentTotp(t, secret))
+		assert.ErrorIs(t, err, ErrInvalidMfaCode)
+		recoveryCodeDao.AssertNotCalled(t, "MarkUsed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		challengeDao.AssertExpectations(t)
+	})
+
+	t.Run("Wrong code counts an attempt", func(t *testing.T) {
+		service, _, recoveryCodeDao, challengeDao := newService()
+		challengeDao.On("GetByTokenHash", ctx, hashToken("challenge")).Return(challenge(), nil)
+		recoveryCodeDao.On("MarkUsed", ctx, 1, mock.Anything, mock.Anything).Return(false, nil)
+		challengeDao.On("IncrementAttempts", ctx, int64(7)).Return(nil)
+
+		_, err := service.VerifyChallenge(ctx, utils.AudienceMerchant, "challenge", "wrong")
+		assert.ErrorIs(t, err, ErrInvalidMfaCode)
+		challengeDao.AssertExpectations(t)
+	})
+
+	t.Run("Too many attempts", func(t *testing.T) {
+		service, _, _, challengeDao := newService()
+		exhausted := challenge()
+		exhausted.Attempts = mfaChallengeMaxAttempts
+		challengeDao.On("GetByTokenHash", ctx, hashToken("challenge")).Return(exhausted, nil)
+
+		_, err := service.VerifyChallenge(ctx, utils.AudienceMerchant, "challenge", currentTotp(t, secret))
+		assert.ErrorIs(t, err, ErrInvalidMfaChallenge)
+	})
+
+	t.Run("Challenge of another client", func(t *testing.T) {
+		service, _, _, challengeDao := newService()
+		challengeDao.On("GetByTokenHash", ctx, hashToken("challenge")).Return(challenge(), nil)
+
+		_, err := service.VerifyChallenge(ctx, utils.AudienceCustomer, "challenge", currentTotp(t, secret))
+		assert.ErrorIs(t, err, ErrInvalidMfaChallenge)
+	})
+
+	t.Run("Expired challenge", func(t *testing.T) {
+		service, _, _, challengeDao := newService()
+		expired := challenge()
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+		challengeDao.On("GetByTokenHash", ctx, hashToken("challenge")).Return(expired, nil)
+
+		_, err := service.VerifyChallenge(ctx, utils.AudienceMerchant, "challenge", currentTotp(t, secret))
+		assert.ErrorIs(t, err, ErrInvalidMfaChallenge)
+	})
+}
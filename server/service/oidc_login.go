@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+// OidcAuthorization is where to send the browser to sign in at the provider.
+// State must also be bound to the browser, e.g. in a cookie, and compared on
+// the callback.
+type OidcAuthorization struct {
+	URL      string
+	State    string
+	FormPost bool
+}
+
+type OidcLoginService interface {
+	BeginLogin(ctx context.Context, provider string) (*OidcAuthorization, error)
+	CompleteLogin(ctx context.Context, provider, state, code string) (*LoginResult, error)
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type OidcLoginServiceImpl struct {
+	providers       map[string]proxy.OidcProvider
+	userDao         dao.UserDao
+	identityDao     dao.UserIdentityDao
+	loginStateDao   dao.OidcLoginStateDao
+	mfaService      MfaService
+	tokenService    TokenService
+	txBeginner      repository.TxBeginner
+	kafkaProducer   mq.KafkaProducer
+	lifecycle       UserLifecycleService
+	redirectBaseURL string
+}
+
+var (
+	oidcLoginServiceInst *OidcLoginServiceImpl
+	oidcLoginServiceOnce sync.Once
+)
+
+// OidcLoginStateTTL is how long the user has to sign in at the provider.
+const OidcLoginStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownOidcProvider  = errors.New("unknown identity provider")
+	ErrInvalidOidcState     = errors.New("invalid or expired login state")
+	ErrOidcEmailNotVerified = errors.New("the identity provider has not verified the email address")
+)
+
+func GetOidcLoginService() *OidcLoginServiceImpl {
+	oidcLoginServiceOnce.Do(func() {
+		if oidcLoginServiceInst == nil {
+			redirectBaseURL := ""
+			if config.Config.OidcConfig != nil {
+				redirectBaseURL = config.Config.OidcConfig.RedirectBaseURL
+			}
+			oidcLoginServiceInst = &OidcLoginServiceImpl{
+				providers:       proxy.GetOidcProviders(),
+				userDao:         dao.GetUserDao(),
+				identityDao:     dao.GetUserIdentityDao(),
+				loginStateDao:   dao.GetOidcLoginStateDao(),
+				mfaService:      GetMfaService(),
+				tokenService:    GetTokenService(),
+				txBeginner:      repository.DB,
+				kafkaProducer:   mq.GetKafkaProducer(),
+				lifecycle:       GetUserLifecycleService(),
+				redirectBaseURL: redirectBaseURL,
+			}
+		}
+	})
+	return oidcLoginServiceInst
+}
+
+// OidcCallbackURL is the redirect URI registered at the provider.
+func OidcCallbackURL(redirectBaseURL, provider string) string {
+	return strings.TrimSuffix(redirectBaseURL, "/") + "/customer/oidc/" + provider + "/callback"
+}
+
+// BeginLogin stores a fresh state, nonce and PKCE verifier and returns the
+// provider's authorization URL.
+func (ols *OidcLoginServiceImpl) BeginLogin(ctx context.Context, provider string) (*OidcAuthorization, error) {
+	idp, ok := ols.providers[provider]
+	if !ok {
+		return nil, ErrUnknownOidcProvider
+	}
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	err = ols.loginStateDao.Create(ctx, &model.OidcLoginState{
+		StateHash:    hashToken(state),
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OidcLoginStateTTL),
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	authURL, err := idp.AuthCodeURL(ctx, OidcCallbackURL(ols.redirectBaseURL, provider), state, nonce,
+		base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		log.Logger.Errorf("Failed to build %s authorization url: %v", provider, err)
+		return nil, err
+	}
+	return &OidcAuthorization{URL: authURL, State: state, FormPost: idp.UsesFormPost()}, nil
+}
+
+// CompleteLogin redeems the authorization code and signs the user in to the
+// customer client. Users are matched by the provider's subject first and by
+// verified email second; unknown emails get a new, already active account.
+func (ols *OidcLoginServiceImpl) CompleteLogin(ctx context.Context, provider, state, code string) (*LoginResult, error) {
+	idp, ok := ols.providers[provider]
+	if !ok {
+		return nil, ErrUnknownOidcProvider
+	}
+	stored, err := ols.loginStateDao.GetByStateHash(ctx, hashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.Provider != provider || stored.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidOidcState
+	}
+	consumed, err := ols.loginStateDao.Delete(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidOidcState
+	}
+	rawIDToken, err := idp.Exchange(ctx, OidcCallbackURL(ols.redirectBaseURL, provider), code, stored.CodeVerifier)
+	if err != nil {
+		log.Logger.Errorf("Failed to redeem %s authorization code: %v", provider, err)
+		return nil, err
+	}
+	identity, err := idp.VerifyIDToken(ctx, rawIDToken, stored.Nonce)
+	if err != nil {
+		log.Logger.Warnf("Rejected %s id token: %v", provider, err)
+		return nil, err
+	}
+	user, err := ols.resolveUser(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("User %d authenticated with %s", user.ID, provider)
+	return completeLogin(ctx, ols.mfaService, ols.tokenService, user, utils.AudienceCustomer)
+}
+
+func (ols *OidcLoginServiceImpl) resolveUser(ctx context.Context, provider string, identity *proxy.OidcIdentity) (*model.User, error) {
+	linked, err := ols.identityDao.GetByProviderSubject(ctx, provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		user, err := ols.userDao.GetUserById(ctx, linked.UserID)
+		if err != nil {
+			log.Logger.Errorf("Failed to get user by id: %v", err)
+			return nil, err
+		}
+		if user == nil {
+			return nil, errors.New("user not found")
+		}
+		if err := CheckAccountStatus(user.Status); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	// Linking by email is only safe when the provider vouches for it
+	if !identity.EmailVerified || identity.Email == "" {
+		return nil, ErrOidcEmailNotVerified
+	}
+	email := strings.ToLower(identity.Email)
+	user, err := ols.userDao.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by email: %v", err)
+		return nil, err
+	}
+	err = ols.txBeginner.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		activated := false
+		switch {
+		case user == nil:
+			password, err := unusablePassword()
+			if err != nil {
+				return err
+			}
+			user = &model.User{
+				Email:        email,
+				Password:     password,
+				Status:       model.UserStatusActive,
+				Role:         model.UserRoleCustomer,
+				Name:         identity.Name,
+				ActivateTime: &now,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			}
+			if err := ols.userDao.CreateUserInTransaction(ctx, user, tx); err != nil {
+				return err
+			}
+			activated = true
+		case user.Status == model.UserStatusInactive:
+			// The pending password was chosen by whoever registered the
+			// unverified address, so it must not survive the activation
+			password, err := unusablePassword()
+			if err != nil {
+				return err
+			}
+			err = ols.lifecycle.TransitionInTransaction(ctx, user.ID, model.UserStatusInactive, model.UserStatusActive, StatusReasonSocialLogin, nil, tx)
+			if err != nil {
+				return err
+			}
+			user.Status = model.UserStatusActive
+			user.Password = password
+			user.ActivateTime = &now
+			user.UpdatedAt = now
+			err = ols.userDao.UpdateUserInTransaction(ctx, &model.User{
+				ID: user.ID, Password: password, ActivateTime: &now, UpdatedAt: now,
+			}, tx)
+			if err != nil {
+				return err
+			}
+			activated = true
+		case user.Status != model.UserStatusActive:
+			// Locked or suspended accounts are not linked either
+			return CheckAccountStatus(user.Status)
+		}
+		err := ols.identityDao.CreateInTransaction(ctx, &model.UserIdentity{
+			UserID:    user.ID,
+			Provider:  provider,
+			Subject:   identity.Subject,
+			Email:     email,
+			CreatedAt: now,
+		}, tx)
+		if err != nil {
+			return err
+		}
+		if !activated {
+			return nil
+		}
+		log.Logger.Infof("User %d activated through %s", user.ID, provider)
+		eventMsg := &mq.UserActivatedEvent{UserID: user.ID, ActivateTime: now.Unix()}
+		return ols.kafkaProducer.Produce(ctx, config.Config.KafkaConfig.UserActivatedTopic, fmt.Sprintf("%d", user.ID), eventMsg.ToBytes())
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to link %s identity: %v", provider, err)
+		return nil, err
+	}
+	return user, nil
+}
+
+func (ols *OidcLoginServiceImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return ols.loginStateDao.DeleteExpired(ctx, now)
+}
+
+// unusablePassword is the password of accounts created through social login.
+// Nobody knows it; a password can be set later with a password reset.
+func unusablePassword() (string, error) {
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return HashPassword(secret)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	mq_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	proxy_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy/mocks"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const oidcCallback = "https://example.com/user-ms/v1/customer/oidc/google/callback"
+
+func TestOidcBeginLogin(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+
+	t.Run("Stores state and sends PKCE challenge", func(t *testing.T) {
+		idp := new(proxy_mock.OidcProvider)
+		stateDao := new(dao_mock.OidcLoginStateDao)
+		service := &OidcLoginServiceImpl{
+			providers:       map[string]proxy.OidcProvider{"google": idp},
+			loginStateDao:   stateDao,
+			redirectBaseURL: "https://example.com/user-ms/v1/",
+		}
+		var stored *model.OidcLoginState
+		stateDao.On("Create", mock.Anything, mock.MatchedBy(func(arg *model.OidcLoginState) bool {
+			stored = arg
+			return arg.Provider == "google" && arg.ExpiresAt.After(time.Now())
+		})).Return(nil)
+		idp.On("AuthCodeURL", mock.Anything, oidcCallback, mock.Anything, mock.Anything, mock.Anything).Return("https://idp/auth", nil)
+		idp.On("UsesFormPost").Return(false)
+
+		auth, err := service.BeginLogin(ctx, "google")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://idp/auth", auth.URL)
+		assert.Equal(t, hashToken(auth.State), stored.StateHash)
+		challenge := sha256.Sum256([]byte(stored.CodeVerifier))
+		idp.AssertCalled(t, "AuthCodeURL", mock.Anything, oidcCallback, auth.State, stored.Nonce,
+			base64.RawURLEncoding.EncodeToString(challenge[:]))
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		service := &OidcLoginServiceImpl{providers: map[string]proxy.OidcProvider{}}
+		_, err := service.BeginLogin(ctx, "google")
+		assert.Equal(t, ErrUnknownOidcProvider, err)
+	})
+}
+
+func TestOidcCompleteLogin(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	state := "state"
+	storedState := &model.OidcLoginState{
+		ID: 1, StateHash: hashToken(state), Provider: "google", Nonce: "nonce",
+		CodeVerifier: "verifier", ExpiresAt: time.Now().Add(time.Minute),
+	}
+	identity := &proxy.OidcIdentity{Subject: "sub-1", Email: "Test@Example.com", EmailVerified: true, Name: "Test"}
+
+	newService := func(t *testing.T) (*OidcLoginServiceImpl, *proxy_mock.OidcProvider, *dao_mock.UserDao, *dao_mock.UserIdentityDao, *dao_mock.OidcLoginStateDao) {
+		idp := new(proxy_mock.OidcProvider)
+		userDao := new(dao_mock.UserDao)
+		identityDao := new(dao_mock.UserIdentityDao)
+		stateDao := new(dao_mock.OidcLoginStateDao)
+		mfaDao := new(dao_mock.UserMfaDao)
+		mfaDao.On("GetByUserId", mock.Anything, mock.Anything).Return(nil, nil)
+		refreshTokenDao := new(dao_mock.RefreshTokenDao)
+		refreshTokenDao.On("Create", mock.Anything, mock.Anything).Return(nil)
+		service := &OidcLoginServiceImpl{
+			providers:       map[string]proxy.OidcProvider{"google": idp},
+			userDao:         userDao,
+			identityDao:     identityDao,
+			loginStateDao:   stateDao,
+			mfaService:      &MfaServiceImpl{userMfaDao: mfaDao},
+			tokenService:    &TokenServiceImpl{userDao: userDao, refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDaoWithDefaults()},
+			txBeginner:      &fakeTx{DB: initMemDb(t)},
+			redirectBaseURL: "https://example.com/user-ms/v1",
+		}
+		return service, idp, userDao, identityDao, stateDao
+	}
+	expectValidCallback := func(idp *proxy_mock.OidcProvider, stateDao *dao_mock.OidcLoginStateDao) {
+		stateDao.On("GetByStateHash", mock.Anything, hashToken(state)).Return(storedState, nil)
+		stateDao.On("Delete", mock.Anything, int64(1)).Return(true, nil)
+		idp.On("Exchange", mock.Anything, oidcCallback, "code", "verifier").Return("id-token", nil)
+		idp.On("VerifyIDToken", mock.Anything, "id-token", "nonce").Return(identity, nil)
+	}
+
+	t.Run("Linked identity signs in", func(t *testing.T) {
+		service, idp, userDao, identityDao, stateDao := newService(t)
+		expectValidCallback(idp, stateDao)
+		identityDao.On("GetByProviderSubject", mock.Anything, "google", "sub-1").Return(&model.UserIdentity{UserID: 1}, nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleCustomer}, nil)
+
+		result, err := service.CompleteLogin(ctx, "google", state, "code")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, result.Tokens.AccessToken)
+		claims, err := utils.ParseJWTToken(result.Tokens.AccessToken)
+		assert.NoError(t, err)
+		assert.True(t, claims.HasAudience(utils.AudienceCustomer))
+	})
+
+	t.Run("Unknown email creates an active account", func(t *testing.T) {
+		service, idp, userDao, identityDao, stateDao := newService(t)
+		kafkaProducer := new(mq_mock.KafkaProducer)
+		service.kafkaProducer = kafkaProducer
+		expectValidCallback(idp, stateDao)
+		identityDao.On("GetByProviderSubject", mock.Anything, "google", "sub-1").Return(nil, nil)
+		userDao.On("GetUserByEmail", mock.Anything, "test@example.com").Return(nil, nil)
+		userDao.On("CreateUserInTransaction", mock.Anything, mock.MatchedBy(func(arg *model.User) bool {
+			arg.ID = 2
+			return arg.Email == "test@example.com" && arg.Status == model.UserStatusActive &&
+				arg.Role == model.UserRoleCustomer && arg.Name == "Test" && arg.Password != ""
+		}), mock.Anything).Return(nil)
+		identityDao.On("CreateInTransaction", mock.Anything, mock.MatchedBy(func(arg *model.UserIdentity) bool {
+			return arg.UserID == 2 && arg.Provider == "google" && arg.Subject == "sub-1"
+		}), mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, "user_activated", "2", mock.Anything).Return(nil)
+
+		result, err := service.CompleteLogin(ctx, "google", state, "code")
+		assert.NoError(t, err)
+		assert.NotNil(t, result.Tokens)
+		userDao.AssertExpectations(t)
+		identityDao.AssertExpectations(t)
+		kafkaProducer.AssertExpectations(t)
+	})
+
+	t.Run("Pending account is activated and its password replaced", func(t *testing.T) {
+		service, idp, userDao, identityDao, stateDao := newService(t)
+		kafkaProducer := new(mq_mock.KafkaProducer)
+		service.kafkaProducer = kafkaProducer
+		expectValidCallback(idp, stateDao)
+		pending, err := HashPassword("password123")
+		assert.NoError(t, err)
+		identityDao.On("GetByProviderSubject", mock.Anything, "google", "sub-1").Return(nil, nil)
+		userDao.On("GetUserByEmail", mock.Anything, "test@example.com").Return(&model.User{
+			ID: 3, Email: "test@example.com", Password: pending, Status: model.UserStatusInactive, Role: model.UserRoleCustomer,
+		}, nil)
+		service.lifecycle = newUserLifecycle(userDao, kafkaProducer)
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 3, model.UserStatusInactive, model.UserStatusActive, false, mock.Anything).Return(true, nil)
+		userDao.On("UpdateUserInTransaction", mock.Anything, mock.MatchedBy(func(arg *model.User) bool {
+			return arg.ID == 3 && arg.ActivateTime != nil && VerifyPassword(arg.Password, "password123") != nil
+		}), mock.Anything).Return(nil)
+		identityDao.On("CreateInTransaction", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "3", mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, "user_activated", "3", mock.Anything).Return(nil)
+
+		_, err = service.CompleteLogin(ctx, "google", state, "code")
+		assert.NoError(t, err)
+		userDao.AssertExpectations(t)
+		kafkaProducer.AssertExpectations(t)
+	})
+
+	t.Run("Unverified email is rejected", func(t *testing.T) {
+		service, idp, _, identityDao, stateDao := newService(t)
+		stateDao.On("GetByStateHash", mock.Anything, hashToken(state)).Return(storedState, nil)
+		stateDao.On("Delete", mock.Anything, int64(1)).Return(true, nil)
+		idp.On("Exchange", mock.Anything, oidcCallback, "code", "verifier").Return("id-token", nil)
+		idp.On("VerifyIDToken", mock.Anything, "id-token", "nonce").Return(&proxy.OidcIdentity{Subject: "sub-1", Email: "test@example.com"}, nil)
+		identityDao.On("GetByProviderSubject", mock.Anything, "google", "sub-1").Return(nil, nil)
+
+		_, err := service.CompleteLogin(ctx, "google", state, "code")
+		assert.Equal(t, ErrOidcEmailNotVerified, err)
+	})
+
+	t.Run("Consumed state is rejected", func(t *testing.T) {
+		service, idp, _, _, stateDao := newService(t)
+		stateDao.On("GetByStateHash", mock.Anything, hashToken(state)).Return(storedState, nil)
+		stateDao.On("Delete", mock.Anything, int64(1)).Return(false, nil)
+
+		_, err := service.CompleteLogin(ctx, "google", state, "code")
+		assert.Equal(t, ErrInvalidOidcState, err)
+		idp.AssertNotCalled(t, "Exchange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown state is rejected", func(t *testing.T) {
+		service, _, _, _, stateDao := newService(t)
+		stateDao.On("GetByStateHash", mock.Anything, mock.Anything).Return(nil, nil)
+
+		_, err := service.CompleteLogin(ctx, "google", "other", "code")
+		assert.Equal(t, ErrInvalidOidcState, err)
+	})
+
+	t.Run("Merchant accounts cannot use social login", func(t *testing.T) {
+		service, idp, userDao, identityDao, stateDao := newService(t)
+		expectValidCallback(idp, stateDao)
+		identityDao.On("GetByProviderSubject", mock.Anything, "google", "sub-1").Return(&model.UserIdentity{UserID: 1}, nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleMerchant}, nil)
+
+		_, err := service.CompleteLogin(ctx, "google", state, "code")
+		assert.True(t, errors.Is(err, ErrClientNotAllowed))
+	})
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher hashes one algorithm into PHC strings such as
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>. bcrypt keeps its own
+// $2a$<cost>$... format, which PHC adopts as is.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify returns ErrPasswordMismatch if password does not match encoded
+	Verify(encoded, password string) error
+	// NeedsRehash reports whether encoded was made with other parameters
+	NeedsRehash(encoded string) bool
+}
+
+const (
+	passwordAlgorithmArgon2id = "argon2id"
+	passwordAlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+)
+
+// VersionedPasswordHasher hashes with the current algorithm and verifies
+// hashes of every known one, so the algorithm and its parameters can be
+// raised without resetting passwords.
+type VersionedPasswordHasher struct {
+	current        string
+	argon2idHasher *Argon2idHasher
+	bcryptHasher   *BcryptHasher
+}
+
+var (
+	passwordHasherOnce sync.Once
+	passwordHasherInst *VersionedPasswordHasher
+)
+
+// NewPasswordHasher builds a hasher from cfg. Without a configuration new
+// hashes are bcrypt with the default cost.
+func NewPasswordHasher(cfg *config.PasswordHash) (*VersionedPasswordHasher, error) {
+	if cfg == nil {
+		cfg = &config.PasswordHash{Algorithm: passwordAlgorithmBcrypt}
+	}
+	bcryptCost := cfg.BcryptCost
+	if bcryptCost == 0 {
+		bcryptCost = bcrypt.DefaultCost
+	}
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d", bcryptCost)
+	}
+	params := Argon2Params{MemoryKiB: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+	if cfg.Argon2id != nil {
+		params = Argon2Params(*cfg.Argon2id)
+	}
+	if params.MemoryKiB < 8*uint32(params.Parallelism) || params.Iterations == 0 || params.Parallelism == 0 ||
+		params.SaltLength < 8 || params.KeyLength < 16 {
+		return nil, fmt.Errorf("invalid argon2id parameters %+v", params)
+	}
+	if cfg.Algorithm != passwordAlgorithmArgon2id && cfg.Algorithm != passwordAlgorithmBcrypt {
+		return nil, fmt.Errorf("unknown password hash algorithm %q", cfg.Algorithm)
+	}
+	return &VersionedPasswordHasher{
+		current:        cfg.Algorithm,
+		argon2idHasher: &Argon2idHasher{Params: params},
+		bcryptHasher:   &BcryptHasher{Cost: bcryptCost},
+	}, nil
+}
+
+// GetPasswordHasher returns the hasher configured under password_hash.
+func GetPasswordHasher() *VersionedPasswordHasher {
+	passwordHasherOnce.Do(func() {
+		if passwordHasherInst == nil {
+			hasher, err := NewPasswordHasher(config.Config.PasswordHash)
+			if err != nil {
+				panic(err)
+			}
+			passwordHasherInst = hasher
+		}
+	})
+	return passwordHasherInst
+}
+
+func (h *VersionedPasswordHasher) Hash(password string) (string, error) {
+	return h.hasherFor(h.current).Hash(password)
+}
+
+func (h *VersionedPasswordHasher) Verify(encoded, password string) error {
+	hasher := h.hasherFor(passwordAlgorithm(encoded))
+	if hasher == nil {
+		return ErrUnknownPasswordHash
+	}
+	return hasher.Verify(encoded, password)
+}
+
+func (h *VersionedPasswordHasher) NeedsRehash(encoded string) bool {
+	algorithm := passwordAlgorithm(encoded)
+	if algorithm != h.current {
+		return true
+	}
+	return h.hasherFor(algorithm).NeedsRehash(encoded)
+}
+
+func (h *VersionedPasswordHasher) hasherFor(algorithm string) PasswordHasher {
+	switch algorithm {
+	case passwordAlgorithmArgon2id:
+		return h.argon2idHasher
+	case passwordAlgorithmBcrypt:
+		return h.bcryptHasher
+	}
+	return nil
+}
+
+// passwordAlgorithm reads the algorithm from the identifier of a PHC string.
+func passwordAlgorithm(encoded string) string {
+	fields := strings.SplitN(encoded, "$", 3)
+	if len(fields) < 3 || fields[0] != "" {
+		return ""
+	}
+	switch fields[1] {
+	case "argon2id":
+		return passwordAlgorithmArgon2id
+	case "2a", "2b", "2y":
+		return passwordAlgorithmBcrypt
+	}
+	return ""
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) Verify(encoded, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
+
+// Argon2Params are the argon2id parameters, memory in KiB.
+type Argon2Params struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type Argon2idHasher struct {
+	Params Argon2Params
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Params.Iterations, h.Params.MemoryKiB, h.Params.Parallelism, h.Params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		h.Params.MemoryKiB, h.Params.Iterations, h.Params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(encoded, password string) error {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return err
+	}
+	// The parameters of the stored hash apply, not the configured ones
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.MemoryKiB, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	return err != nil || params != h.Params
+}
+
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 6 || fields[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.MemoryKiB, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Small parameters keep the tests fast
+var testArgon2Params = &config.Argon2Params{MemoryKiB: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasher(t *testing.T) {
+	argon2Hasher, err := NewPasswordHasher(&config.PasswordHash{Algorithm: "argon2id", Argon2id: testArgon2Params, BcryptCost: bcrypt.MinCost})
+	assert.NoError(t, err)
+	bcryptHasher, err := NewPasswordHasher(&config.PasswordHash{Algorithm: "bcrypt", Argon2id: testArgon2Params, BcryptCost: bcrypt.MinCost})
+	assert.NoError(t, err)
+
+	t.Run("Argon2id hashes are PHC strings", func(t *testing.T) {
+		encoded, err := argon2Hasher.Hash("correct horse")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"), encoded)
+		assert.NoError(t, argon2Hasher.Verify(encoded, "correct horse"))
+		assert.ErrorIs(t, argon2Hasher.Verify(encoded, "wrong horse"), ErrPasswordMismatch)
+		assert.False(t, argon2Hasher.NeedsRehash(encoded))
+	})
+
+	t.Run("Every algorithm verifies, only the current one is kept", func(t *testing.T) {
+		legacy, err := bcryptHasher.Hash("correct horse")
+		assert.NoError(t, err)
+		assert.NoError(t, argon2Hasher.Verify(legacy, "correct horse"))
+		assert.ErrorIs(t, argon2Hasher.Verify(legacy, "wrong horse"), ErrPasswordMismatch)
+		assert.True(t, argon2Hasher.NeedsRehash(legacy))
+
+		upgraded, err := argon2Hasher.Hash("correct horse")
+		assert.NoError(t, err)
+		assert.NoError(t, bcryptHasher.Verify(upgraded, "correct horse"))
+		assert.True(t, bcryptHasher.NeedsRehash(upgraded))
+	})
+
+	t.Run("Changed parameters need a rehash", func(t *testing.T) {
+		stronger := *testArgon2Params
+		stronger.Iterations = 2
+		strongerHasher, err := NewPasswordHasher(&config.PasswordHash{Algorithm: "argon2id", Argon2id: &stronger})
+		assert.NoError(t, err)
+		encoded, err := argon2Hasher.Hash("correct horse")
+		assert.NoError(t, err)
+		// Old hashes are verified with their own parameters
+		assert.NoError(t, strongerHasher.Verify(encoded, "correct horse"))
+		assert.True(t, strongerHasher.NeedsRehash(encoded))
+
+		cheap, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+		assert.NoError(t, err)
+		costlier, err := NewPasswordHasher(&config.PasswordHash{Algorithm: "bcrypt", BcryptCost: bcrypt.MinCost + 1})
+		assert.NoError(t, err)
+		assert.True(t, costlier.NeedsRehash(string(cheap)))
+	})
+
+	t.Run("Unknown or malformed hashes never match", func(t *testing.T) {
+		for _, encoded := range []string{"", "plaintext", "$md5$abc", "$argon2id$v=19$m=1024,t=1,p=1$bad"} {
+			assert.Error(t, argon2Hasher.Verify(encoded, "plaintext"), encoded)
+			assert.True(t, argon2Hasher.NeedsRehash(encoded), encoded)
+		}
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		_, err := NewPasswordHasher(&config.PasswordHash{Algorithm: "scrypt"})
+		assert.Error(t, err)
+		_, err = NewPasswordHasher(&config.PasswordHash{Algorithm: "bcrypt", BcryptCost: 99})
+		assert.Error(t, err)
+		_, err = NewPasswordHasher(&config.PasswordHash{Algorithm: "argon2id", Argon2id: &config.Argon2Params{MemoryKiB: 1024}})
+		assert.Error(t, err)
+	})
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	// Hashed with a lower cost than the default hasher uses
+	legacyHash, err := (&BcryptHasher{Cost: bcrypt.MinCost}).Hash("correctpassword")
+	assert.NoError(t, err)
+	user := &model.User{ID: 1, Email: "test@example.com", Password: legacyHash, Status: model.UserStatusActive, Role: model.UserRoleCustomer, CredentialVersion: 2}
+	userDao := new(mocks.UserDao)
+	userMfaDao := new(mocks.UserMfaDao)
+	refreshTokenDao := new(mocks.RefreshTokenDao)
+	loginService := &LoginServiceImpl{
+		userDao:      userDao,
+		tokenService: &TokenServiceImpl{userDao: userDao, refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDaoWithDefaults()},
+		mfaService:   &MfaServiceImpl{userMfaDao: userMfaDao},
+		loginGuard:   permissiveLoginGuard(),
+	}
+	userDao.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+	userMfaDao.On("GetByUserId", mock.Anything, 1).Return(nil, nil)
+	refreshTokenDao.On("Create", mock.Anything, mock.Anything).Return(nil)
+	userDao.On("RehashPassword", mock.Anything, 1, legacyHash, mock.MatchedBy(func(newHash string) bool {
+		return !GetPasswordHasher().NeedsRehash(newHash) && VerifyPassword(newHash, "correctpassword") == nil
+	})).Return(true, nil).Once()
+
+	result, err := loginService.Login(ctx, utils.AudienceCustomer, user.Email, "correctpassword", "127.0.0.1")
+	assert.NoError(t, err)
+	// The upgrade is not a password change, so sessions stay valid
+	claims, err := utils.ParseJWTToken(result.Tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, claims.CredentialVersion)
+	userDao.AssertExpectations(t)
+
+	// The next login finds the upgraded hash
+	_, err = loginService.Login(ctx, utils.AudienceCustomer, user.Email, "correctpassword", "127.0.0.1")
+	assert.NoError(t, err)
+	userDao.AssertNumberOfCalls(t, "RehashPassword", 1)
+}
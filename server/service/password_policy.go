@@ -0,0 +1,334 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+// PasswordSubject is the account a new password is meant for. UserID and
+// CurrentHash are zero while registering.
+type PasswordSubject struct {
+	UserID      int
+	Email       string
+	Name        string
+	CurrentHash string
+}
+
+// PasswordRule is one check of the password policy.
+type PasswordRule interface {
+	// Check returns a message for the user if password breaks the rule, or
+	// "" if it passes.
+	Check(ctx context.Context, password string, subject PasswordSubject) (string, error)
+}
+
+type PasswordPolicy interface {
+	// Validate runs every rule and returns a *PasswordPolicyError listing
+	// the rules the password breaks.
+	Validate(ctx context.Context, password string, subject PasswordSubject) error
+	// Remember adds the hash of a password being replaced to the history
+	// of the user.
+	Remember(ctx context.Context, userID int, passwordHash string, tx *gorm.DB) error
+}
+
+type PasswordPolicyImpl struct {
+	rules              []PasswordRule
+	passwordHistoryDao dao.PasswordHistoryDao
+	historySize        int
+}
+
+var (
+	passwordPolicyOnce sync.Once
+	passwordPolicyInst *PasswordPolicyImpl
+)
+
+const (
+	// bcrypt ignores everything past 72 bytes, so longer passwords are
+	// refused while bcrypt hashes may still be in use
+	passwordMaxBytes = 72
+	// personalInfoMinLength keeps short names such as "Al" from blocking
+	// every password containing them
+	personalInfoMinLength = 3
+)
+
+var ErrPasswordPolicy = errors.New("password does not meet the password policy")
+
+// PasswordPolicyError lists the rules a password breaks. It matches
+// ErrPasswordPolicy with errors.Is.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrPasswordPolicy.Error() + ": " + strings.Join(e.Violations, "; ")
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrPasswordPolicy
+}
+
+// NewPasswordPolicy builds a policy from rules. Passwords being replaced are
+// kept for the newest historySize-1 entries, which together with the current
+// password make up the history checked by PasswordHistoryRule.
+func NewPasswordPolicy(passwordHistoryDao dao.PasswordHistoryDao, historySize int, rules ...PasswordRule) *PasswordPolicyImpl {
+	return &PasswordPolicyImpl{rules: rules, passwordHistoryDao: passwordHistoryDao, historySize: historySize}
+}
+
+// GetPasswordPolicy returns the policy configured under password_policy.
+// Without the section passwords need 8 characters with a letter and a digit.
+func GetPasswordPolicy() *PasswordPolicyImpl {
+	passwordPolicyOnce.Do(func() {
+		if passwordPolicyInst == nil {
+			cfg := config.Config.PasswordPolicy
+			if cfg == nil {
+				cfg = &config.PasswordPolicy{MinLength: 8, RequiredClasses: []string{characterClassLetter, characterClassDigit}}
+			}
+			for _, class := range cfg.RequiredClasses {
+				if _, ok := characterClasses[class]; !ok {
+					panic(fmt.Sprintf("unknown character class in password_policy.required_classes: %s", class))
+				}
+			}
+			historyDao := dao.GetPasswordHistoryDao()
+			rules := []PasswordRule{
+				&LengthRule{Min: cfg.MinLength},
+				&CharacterClassRule{Required: cfg.RequiredClasses, MinClasses: cfg.MinClasses},
+			}
+			if cfg.BlockPersonalInfo {
+				rules = append(rules, &PersonalInfoRule{})
+			}
+			if cfg.HistorySize > 0 {
+				rules = append(rules, &PasswordHistoryRule{PasswordHistoryDao: historyDao, Size: cfg.HistorySize})
+			}
+			if cfg.BreachedHashDir != "" {
+				rules = append(rules, &BreachedPasswordRule{Checker: &RangeFileChecker{Dir: cfg.BreachedHashDir}})
+			}
+			passwordPolicyInst = NewPasswordPolicy(historyDao, cfg.HistorySize, rules...)
+		}
+	})
+	return passwordPolicyInst
+}
+
+func (pp *PasswordPolicyImpl) Validate(ctx context.Context, password string, subject PasswordSubject) error {
+	var violations []string
+	for _, rule := range pp.rules {
+		violation, err := rule.Check(ctx, password, subject)
+		if err != nil {
+			return err
+		}
+		if violation != "" {
+			violations = append(violations, violation)
+		}
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (pp *PasswordPolicyImpl) Remember(ctx context.Context, userID int, passwordHash string, tx *gorm.DB) error {
+	// The current password is checked from the user row, only older ones
+	// need to be kept
+	keep := pp.historySize - 1
+	if keep <= 0 || passwordHash == "" {
+		return nil
+	}
+	if err := pp.passwordHistoryDao.Create(ctx, &model.PasswordHistory{UserID: userID, PasswordHash: passwordHash}, tx); err != nil {
+		return err
+	}
+	return pp.passwordHistoryDao.Trim(ctx, userID, keep, tx)
+}
+
+// LengthRule requires at least Min characters. Passwords longer than bcrypt
+// can hash are always refused.
+type LengthRule struct {
+	Min int
+}
+
+func (r *LengthRule) Check(ctx context.Context, password string, subject PasswordSubject) (string, error) {
+	if utf8.RuneCountInString(password) < r.Min {
+		return fmt.Sprintf("must be at least %d characters long", r.Min), nil
+	}
+	if len(password) > passwordMaxBytes {
+		return fmt.Sprintf("must be at most %d bytes long", passwordMaxBytes), nil
+	}
+	return "", nil
+}
+
+const (
+	characterClassLower  = "lower"
+	characterClassUpper  = "upper"
+	characterClassLetter = "letter"
+	characterClassDigit  = "digit"
+	characterClassSymbol = "symbol"
+)
+
+var characterClasses = map[string]func(rune) bool{
+	characterClassLower:  unicode.IsLower,
+	characterClassUpper:  unicode.IsUpper,
+	characterClassLetter: unicode.IsLetter,
+	characterClassDigit:  unicode.IsDigit,
+	characterClassSymbol: func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) },
+}
+
+var characterClassNames = map[string]string{
+	characterClassLower:  "a lowercase letter",
+	characterClassUpper:  "an uppercase letter",
+	characterClassLetter: "a letter",
+	characterClassDigit:  "a digit",
+	characterClassSymbol: "a symbol",
+}
+
+// CharacterClassRule requires every class in Required and at least
+// MinClasses of lowercase letters, uppercase letters, digits and symbols.
+type CharacterClassRule struct {
+	Required   []string
+	MinClasses int
+}
+
+func (r *CharacterClassRule) Check(ctx context.Context, password string, subject PasswordSubject) (string, error) {
+	var missing []string
+	for _, class := range r.Required {
+		if !strings.ContainsFunc(password, characterClasses[class]) {
+			missing = append(missing, characterClassNames[class])
+		}
+	}
+	if len(missing) > 0 {
+		return "must contain " + strings.Join(missing, ", "), nil
+	}
+	present := 0
+	for _, class := range []string{characterClassLower, characterClassUpper, characterClassDigit, characterClassSymbol} {
+		if strings.ContainsFunc(password, characterClasses[class]) {
+			present++
+		}
+	}
+	if present < r.MinClasses {
+		return fmt.Sprintf("must contain at least %d of lowercase letters, uppercase letters, digits and symbols", r.MinClasses), nil
+	}
+	return "", nil
+}
+
+// PersonalInfoRule refuses passwords containing the email, the part of the
+// email before the @ or a part of the name.
+type PersonalInfoRule struct{}
+
+func (r *PersonalInfoRule) Check(ctx context.Context, password string, subject PasswordSubject) (string, error) {
+	lowered := strings.ToLower(password)
+	localPart, _, _ := strings.Cut(strings.ToLower(subject.Email), "@")
+	if len(localPart) >= personalInfoMinLength && strings.Contains(lowered, localPart) {
+		return "must not contain your email address", nil
+	}
+	nameParts := strings.FieldsFunc(strings.ToLower(subject.Name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, part := range nameParts {
+		if utf8.RuneCountInString(part) >= personalInfoMinLength && strings.Contains(lowered, part) {
+			return "must not contain your name", nil
+		}
+	}
+	return "", nil
+}
+
+// PasswordHistoryRule refuses the current password and the passwords it
+// replaced, Size in total.
+type PasswordHistoryRule struct {
+	PasswordHistoryDao dao.PasswordHistoryDao
+	Size               int
+}
+
+func (r *PasswordHistoryRule) Check(ctx context.Context, password string, subject PasswordSubject) (string, error) {
+	if subject.UserID == 0 {
+		return "", nil
+	}
+	violation := fmt.Sprintf("must not be one of your last %d passwords", r.Size)
+	if subject.CurrentHash != "" && VerifyPassword(subject.CurrentHash, password) == nil {
+		return violation, nil
+	}
+	if r.Size <= 1 {
+		return "", nil
+	}
+	entries, err := r.PasswordHistoryDao.ListRecentByUserId(ctx, subject.UserID, r.Size-1)
+	if err != nil {
+		return "", err
+	}
+	if slices.ContainsFunc(entries, func(entry *model.PasswordHistory) bool {
+		return VerifyPassword(entry.PasswordHash, password) == nil
+	}) {
+		return violation, nil
+	}
+	return "", nil
+}
+
+// BreachedPasswordChecker tells whether a password appears in known
+// breaches.
+type BreachedPasswordChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// BreachedPasswordRule refuses passwords the Checker knows from breaches.
+type BreachedPasswordRule struct {
+	Checker BreachedPasswordChecker
+}
+
+func (r *BreachedPasswordRule) Check(ctx context.Context, password string, subject PasswordSubject) (string, error) {
+	breached, err := r.Checker.IsBreached(ctx, password)
+	if err != nil {
+		return "", err
+	}
+	if breached {
+		return "appears in a known data breach, choose another one", nil
+	}
+	return "", nil
+}
+
+// RangeFileChecker looks passwords up in a local copy of the Pwned Passwords
+// range files. Dir holds one file per 5 character SHA-1 prefix, named
+// <PREFIX>.txt, with a "<SUFFIX>:<COUNT>" line per breached hash, as served
+// by the k-anonymity range API.
+type RangeFileChecker struct {
+	Dir string
+}
+
+const rangePrefixLength = 5
+
+func (c *RangeFileChecker) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+	file, err := os.Open(filepath.Join(c.Dir, prefix+".txt"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Logger.Warnf("Breached password range file %s.txt is missing", prefix)
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(lineSuffix, suffix) {
+			continue
+		}
+		// Padded range files list made up hashes with a count of 0
+		n, err := strconv.Atoi(count)
+		return err != nil || n > 0, nil
+	}
+	return false, scanner.Err()
+}
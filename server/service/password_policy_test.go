@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// permissivePasswordPolicy accepts every password and keeps no history.
+func permissivePasswordPolicy() *PasswordPolicyImpl {
+	return NewPasswordPolicy(nil, 0)
+}
+
+func TestPasswordPolicyRules(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	policy := NewPasswordPolicy(nil, 0,
+		&LengthRule{Min: 10},
+		&CharacterClassRule{Required: []string{characterClassLetter, characterClassDigit}, MinClasses: 3},
+		&PersonalInfoRule{},
+	)
+	subject := PasswordSubject{UserID: 1, Email: "Jane.Doe@example.com", Name: "Jane Li"}
+
+	assert.NoError(t, policy.Validate(ctx, "correct-Horse7", subject))
+
+	err := policy.Validate(ctx, "short", subject)
+	assert.ErrorIs(t, err, ErrPasswordPolicy)
+	var policyErr *PasswordPolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	// Every broken rule is reported, not just the first one
+	assert.Equal(t, []string{"must be at least 10 characters long", "must contain a digit"}, policyErr.Violations)
+
+	cases := map[string]string{
+		"lowercase and digits only": "abcdefgh12345",
+		"email":                     "x-jane.doe-2024",
+		"name":                      "Bestjane#2024",
+		"too long for bcrypt":       "Aa1" + strings.Repeat("x", 70),
+	}
+	for name, password := range cases {
+		assert.ErrorIs(t, policy.Validate(ctx, password, subject), ErrPasswordPolicy, name)
+	}
+	// Name parts shorter than 3 letters do not count
+	assert.NoError(t, policy.Validate(ctx, "li-Horse-777", subject))
+}
+
+func TestPasswordHistory(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	current, _ := HashPassword("current1")
+	previous, _ := HashPassword("previous1")
+	historyDao := new(dao_mock.PasswordHistoryDao)
+	historyDao.On("ListRecentByUserId", mock.Anything, 1, 2).Return([]*model.PasswordHistory{{UserID: 1, PasswordHash: previous}}, nil)
+	policy := NewPasswordPolicy(historyDao, 3, &PasswordHistoryRule{PasswordHistoryDao: historyDao, Size: 3})
+	subject := PasswordSubject{UserID: 1, CurrentHash: current}
+
+	assert.ErrorIs(t, policy.Validate(ctx, "current1", subject), ErrPasswordPolicy)
+	assert.ErrorIs(t, policy.Validate(ctx, "previous1", subject), ErrPasswordPolicy)
+	assert.NoError(t, policy.Validate(ctx, "brandnew1", subject))
+	// Registration has no history to check
+	assert.NoError(t, policy.Validate(ctx, "previous1", PasswordSubject{}))
+
+	t.Run("Replaced passwords are remembered and trimmed", func(t *testing.T) {
+		historyDao.On("Create", mock.Anything, mock.MatchedBy(func(arg *model.PasswordHistory) bool {
+			return arg.UserID == 1 && arg.PasswordHash == current
+		}), mock.Anything).Return(nil)
+		historyDao.On("Trim", mock.Anything, 1, 2, mock.Anything).Return(nil)
+
+		assert.NoError(t, policy.Remember(ctx, 1, current, nil))
+		historyDao.AssertExpectations(t)
+	})
+}
+
+func TestBreachedPasswordRule(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	dir := t.TempDir()
+	rangeLine := func(password string, count string) (string, string) {
+		sum := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		return hash[:5], hash[5:] + ":" + count
+	}
+	prefix, line := rangeLine("P@ssw0rd", "52578")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte("0000000000000000000000000000000000A:1\r\n"+line+"\r\n"), 0o600))
+	paddedPrefix, paddedLine := rangeLine("Padded-entry-9", "0")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, paddedPrefix+".txt"), []byte(paddedLine+"\n"), 0o600))
+	policy := NewPasswordPolicy(nil, 0, &BreachedPasswordRule{Checker: &RangeFileChecker{Dir: dir}})
+
+	assert.ErrorIs(t, policy.Validate(ctx, "P@ssw0rd", PasswordSubject{}), ErrPasswordPolicy)
+	assert.NoError(t, policy.Validate(ctx, "Padded-entry-9", PasswordSubject{}))
+	// Prefixes without a range file are treated as not breached
+	assert.NoError(t, policy.Validate(ctx, "unlisted-Password-1", PasswordSubject{}))
+}
+
+func TestChangePasswordPolicy(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	hashedPwd, _ := HashPassword("oldPassword1")
+	userDao := new(dao_mock.UserDao)
+	userDao.On("GetUserById", ctx, 1).Return(&model.User{ID: 1, Email: "jane@example.com", Password: hashedPwd}, nil)
+	service := &UserProfileServiceImpl{
+		userDao:        userDao,
+		passwordPolicy: NewPasswordPolicy(nil, 0, &LengthRule{Min: 8}, &PersonalInfoRule{}),
+	}
+
+	_, err := service.ChangePassword(ctx, 1, "customer", "oldPassword1", "jane1234")
+	var policyErr *PasswordPolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, []string{"must not contain your email address"}, policyErr.Violations)
+	userDao.AssertNotCalled(t, "UpdatePasswordInTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, email string) error
+	ConfirmReset(ctx context.Context, email, code, newPassword string) error
+}
+
+type PasswordResetServiceImpl struct {
+	userDao          dao.UserDao
+	passwordResetDao dao.UserPasswordResetDao
+	emailService     proxy.EmailService
+	txBeginner       repository.TxBeginner
+	kafkaProducer    mq.KafkaProducer
+	loginGuard       LoginGuard
+	passwordPolicy   PasswordPolicy
+	lifecycle        UserLifecycleService
+}
+
+var (
+	passwordResetServiceInst *PasswordResetServiceImpl
+	passwordResetOnce        sync.Once
+)
+
+const (
+	passwordResetExpiryDuration = time.Minute * 15
+	passwordResetMaxAttempts    = 5
+)
+
+var ErrInvalidResetCode = errors.New("invalid or expired reset code")
+
+func GetPasswordResetService() *PasswordResetServiceImpl {
+	passwordResetOnce.Do(func() {
+		if passwordResetServiceInst == nil {
+			passwordResetServiceInst = &PasswordResetServiceImpl{
+				userDao:          dao.GetUserDao(),
+				passwordResetDao: dao.GetUserPasswordResetDao(),
+				emailService:     proxy.GetEmailInstance(),
+				txBeginner:       repository.DB,
+				kafkaProducer:    mq.GetKafkaProducer(),
+				loginGuard:       GetLoginGuard(),
+				passwordPolicy:   GetPasswordPolicy(),
+				lifecycle:        GetUserLifecycleService(),
+			}
+		}
+	})
+	return passwordResetServiceInst
+}
+
+// RequestReset sends a one-time reset code to the given email. Unknown or
+// inactive accounts are silently ignored so the endpoint cannot be used to
+// probe which emails are registered. Locked accounts get a code, resetting
+// the password is how they are unlocked.
+func (ps *PasswordResetServiceImpl) RequestReset(ctx context.Context, email string) error {
+	user, err := ps.userDao.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by email: %v", err)
+		return err
+	}
+	if user == nil || !canResetPassword(user.Status) {
+		log.Logger.Warnf("Password reset requested for unknown or inactive email: %s", email)
+		return nil
+	}
+	code, err := generateVerificationCode()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate reset code: %v", err)
+		return err
+	}
+	codeHash, err := HashPassword(code)
+	if err != nil {
+		log.Logger.Errorf("Failed to hash reset code: %v", err)
+		return err
+	}
+	err = ps.txBeginner.Transaction(func(tx *gorm.DB) error {
+		// Only the most recently issued code stays valid
+		if err := ps.passwordResetDao.DeleteByUserId(ctx, user.ID, tx); err != nil {
+			log.Logger.Errorf("Failed to delete previous password resets: %v", err)
+			return err
+		}
+		return ps.passwordResetDao.Create(ctx, &model.UserPasswordReset{
+			UserID:    user.ID,
+			CodeHash:  codeHash,
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(passwordResetExpiryDuration),
+		}, tx)
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to create password reset: %v", err)
+		return err
+	}
+	err = ps.emailService.Send("Your password reset code is: "+code, email, "CermiCraft Password Reset Code")
+	if err != nil {
+		log.Logger.Errorf("Failed to send password reset email: %v", err)
+		return err
+	}
+	log.Logger.Infof("Password reset email sent for user: %d", user.ID)
+	return nil
+}
+
+func (ps *PasswordResetServiceImpl) ConfirmReset(ctx context.Context, email, code, newPassword string) error {
+	user, err := ps.userDao.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by email: %v", err)
+		return err
+	}
+	if user == nil || !canResetPassword(user.Status) {
+		log.Logger.Warnf("Password reset confirmed for unknown or inactive email: %s", email)
+		return ErrInvalidResetCode
+	}
+	reset, err := ps.passwordResetDao.GetLatestByUserId(ctx, user.ID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get password reset by user id: %v", err)
+		return err
+	}
+	if reset == nil || reset.ExpiresAt.Before(time.Now()) || reset.Attempts >= passwordResetMaxAttempts {
+		log.Logger.Warnf("No valid password reset for user: %d", user.ID)
+		return ErrInvalidResetCode
+	}
+	// Every try uses up an attempt before the code is checked, so concurrent
+	// guesses cannot get past the limit
+	left, err := ps.passwordResetDao.IncrementAttempts(ctx, reset.ID, passwordResetMaxAttempts)
+	if err != nil {
+		log.Logger.Errorf("Failed to record password reset attempt: %v", err)
+		return err
+	}
+	if !left {
+		log.Logger.Warnf("No password reset attempts left for user: %d", user.ID)
+		return ErrInvalidResetCode
+	}
+	if VerifyPassword(reset.CodeHash, code) != nil {
+		log.Logger.Warnf("Wrong password reset code for user: %d, attempts=%d", user.ID, reset.Attempts+1)
+		return ErrInvalidResetCode
+	}
+	// A refused password leaves the code valid for another try while
+	// attempts are left
+	err = ps.passwordPolicy.Validate(ctx, newPassword, PasswordSubject{
+		UserID: user.ID, Email: user.Email, Name: user.Name, CurrentHash: user.Password,
+	})
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		log.Logger.Errorf("Failed to hash password: %v", err)
+		return err
+	}
+	err = ps.txBeginner.Transaction(func(tx *gorm.DB) error {
+		curTime := time.Now()
+		err := ps.userDao.UpdatePasswordInTransaction(ctx, user.ID, hashedPassword, tx)
+		if err != nil {
+			log.Logger.Errorf("Failed to update user password: %v", err)
+			return err
+		}
+		if err := ps.passwordPolicy.Remember(ctx, user.ID, user.Password, tx); err != nil {
+			return err
+		}
+		if user.Status == model.UserStatusLocked {
+			err = ps.lifecycle.TransitionInTransaction(ctx, user.ID, model.UserStatusLocked, model.UserStatusActive, StatusReasonPasswordReset, nil, tx)
+			if err != nil {
+				return err
+			}
+		}
+		err = ps.passwordResetDao.DeleteByUserId(ctx, user.ID, tx)
+		if err != nil {
+			log.Logger.Errorf("Failed to delete password reset after use: %v", err)
+			return err
+		}
+		eventMsg := &mq.PasswordResetEvent{UserID: user.ID, ResetTime: curTime.Unix()}
+		err = ps.kafkaProducer.Produce(ctx, config.Config.KafkaConfig.PasswordResetTopic, fmt.Sprintf("%d", user.ID), eventMsg.ToBytes())
+		if err != nil {
+			log.Logger.Errorf("Failed to produce password reset event: %v", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Logger.Errorf("Failed to reset password: %v", err)
+		return err
+	}
+	log.Logger.Infof("Password reset successfully for user: %d", user.ID)
+	// Proving control of the mailbox is enough to lift a lockout
+	if err := ps.loginGuard.UnlockUser(ctx, user.ID); err != nil {
+		log.Logger.Errorf("Failed to unlock user %d after password reset: %v", user.ID, err)
+	}
+	return nil
+}
+
+// canResetPassword reports whether accounts in status may reset their
+// password. Locked accounts have to, to be unlocked.
+func canResetPassword(status int) bool {
+	return status == model.UserStatusActive || status == model.UserStatusLocked
+}
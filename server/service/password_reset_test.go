@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	mq_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq/mocks"
+	proxy_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy/mocks"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRequestReset(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	email := "test@example.com"
+
+	t.Run("Successful request", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		resetDao := new(dao_mock.UserPasswordResetDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &PasswordResetServiceImpl{
+			userDao:          userDao,
+			passwordResetDao: resetDao,
+			emailService:     emailSender,
+			txBeginner:       &fakeTx{DB: initMemDb(t)},
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(&model.User{ID: 1, Email: email, Status: model.UserStatusActive}, nil)
+		resetDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
+		var stored *model.UserPasswordReset
+		resetDao.On("Create", mock.Anything, mock.MatchedBy(func(arg *model.UserPasswordReset) bool {
+			stored = arg
+			return arg.UserID == 1 && arg.ExpiresAt.After(time.Now())
+		}), mock.Anything).Return(nil)
+		var body string
+		emailSender.On("Send", mock.MatchedBy(func(arg string) bool {
+			body = arg
+			return true
+		}), email, mock.Anything).Return(nil)
+
+		err := service.RequestReset(ctx, email)
+		assert.NoError(t, err)
+		resetDao.AssertExpectations(t)
+		emailSender.AssertExpectations(t)
+		// Only a hash of the emailed code is stored
+		code := regexp.MustCompile(`\d{6}`).FindString(body)
+		assert.NotEqual(t, code, stored.CodeHash)
+		assert.NoError(t, VerifyPassword(stored.CodeHash, code))
+	})
+
+	t.Run("Unknown email is ignored", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &PasswordResetServiceImpl{
+			userDao:      userDao,
+			emailService: emailSender,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(nil, nil)
+
+		err := service.RequestReset(ctx, email)
+		assert.NoError(t, err)
+		emailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Inactive user is ignored", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &PasswordResetServiceImpl{
+			userDao:      userDao,
+			emailService: emailSender,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(&model.User{ID: 1, Status: model.UserStatusInactive}, nil)
+
+		err := service.RequestReset(ctx, email)
+		assert.NoError(t, err)
+		emailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Email sending failure", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		resetDao := new(dao_mock.UserPasswordResetDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &PasswordResetServiceImpl{
+			userDao:          userDao,
+			passwordResetDao: resetDao,
+			emailService:     emailSender,
+			txBeginner:       &fakeTx{DB: initMemDb(t)},
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(&model.User{ID: 1, Status: model.UserStatusActive}, nil)
+		resetDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
+		resetDao.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		emailSender.On("Send", mock.Anything, email, mock.Anything).Return(assert.AnError)
+
+		err := service.RequestReset(ctx, email)
+		assert.True(t, errors.Is(err, assert.AnError))
+	})
+}
+
+func TestConfirmReset(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	email := "test@example.com"
+	activeUser := &model.User{ID: 1, Email: email, Status: model.UserStatusActive}
+	resetCodeHash, err := HashPassword("123456")
+	assert.NoError(t, err)
+
+	t.Run("Successful reset", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		resetDao := new(dao_mock.UserPasswordResetDao)
+		kafkaProducer := new(mq_mock.KafkaProducer)
+		failureDao := new(dao_mock.LoginFailureDao)
+		service := &PasswordResetServiceImpl{
+			userDao:          userDao,
+			passwordResetDao: resetDao,
+			txBeginner:       &fakeTx{DB: initMemDb(t)},
+			kafkaProducer:    kafkaProducer,
+			loginGuard:       &LoginGuardImpl{userDao: userDao, loginFailureDao: failureDao},
+			passwordPolicy:   permissivePasswordPolicy(),
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(activeUser, nil)
+		failureDao.On("DeleteBySubject", mock.Anything, "account:"+email).Return(nil)
+		resetDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserPasswordReset{
+			UserID: 1, CodeHash: resetCodeHash, ExpiresAt: time.Now().Add(time.Minute),
+		}, nil)
+		userDao.On("UpdatePasswordInTransaction", mock.Anything, 1, mock.MatchedBy(func(hashed string) bool {
+			return VerifyPassword(hashed, "newPassword1") == nil
+		}), mock.Anything).Return(nil)
+		resetDao.On("IncrementAttempts", mock.Anything, int64(0), passwordResetMaxAttempts).Return(true, nil)
+		resetDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, mock.Anything, "1", mock.Anything).Return(nil)
+
+		err := service.ConfirmReset(ctx, email, "123456", "newPassword1")
+		assert.NoError(t, err)
+		userDao.AssertExpectations(t)
+		resetDao.AssertExpectations(t)
+		kafkaProducer.AssertExpectations(t)
+		failureDao.AssertExpectations(t)
+	})
+
+	t.Run("Wrong code increments attempts", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		resetDao := new(dao_mock.UserPasswordResetDao)
+		service := &PasswordResetServiceImpl{
+			userDao:          userDao,
+			passwordResetDao: resetDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		resetDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserPasswordReset{
+			ID: 7, UserID: 1, CodeHash: resetCodeHash, ExpiresAt: time.Now().Add(time.Minute),
+		}, nil)
+		resetDao.On("IncrementAttempts", mock.Anything, int64(7), passwordResetMaxAttempts).Return(true, nil)
+
+		err := service.ConfirmReset(ctx, email, "654321", "newPassword1")
+		assert.Equal(t, ErrInvalidResetCode, err)
+		resetDao.AssertExpectations(t)
+	})
+
+	t.Run("Attempts used up by concurrent tries", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		resetDao := new(dao_mock.UserPasswordResetDao)
+		service := &PasswordResetServiceImpl{
+			userDao:          userDao,
+			passwordResetDao: resetDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		// The row still read 4 attempts, but the last one was taken meanwhile
+		resetDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserPasswordReset{
+			ID: 7, UserID: 1, CodeHash: resetCodeHash, Attempts: passwordResetMaxAttempts - 1, ExpiresAt: time.Now().Add(time.Minute),
+		}, nil)
+		resetDao.On("IncrementAttempts", mock.Anything, int64(7), passwordResetMaxAttempts).Return(false, nil)
+
+		err := service.ConfirmReset(ctx, email, "123456", "newPassword1")
+		assert.Equal(t, ErrInvalidResetCode, err)
+		userDao.AssertNotCalled(t, "UpdatePasswordInTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Too many attempts", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		resetDao := new(dao_mock.UserPasswordResetDao)
+		service := &PasswordResetServiceImpl{
+			userDao:          userDao,
+			passwordResetDao: resetDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		resetDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserPasswordReset{
+			UserID: 1, CodeHash: resetCodeHash, Attempts: passwordResetMaxAttempts, ExpiresAt: time.Now().Add(time.Minute),
+		}, nil)
+
+		err := service.ConfirmReset(ctx, email, "123456", "newPassword1")
+		assert.Equal(t, ErrInvalidResetCode, err)
+	})
+
+	t.Run("Expired code", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		resetDao := new(dao_mock.UserPasswordResetDao)
+		service := &PasswordResetServiceImpl{
+			userDao:          userDao,
+			passwordResetDao: resetDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		resetDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserPasswordReset{
+			UserID: 1, CodeHash: resetCodeHash, ExpiresAt: time.Now().Add(-time.Minute),
+		}, nil)
+
+		err := service.ConfirmReset(ctx, email, "123456", "newPassword1")
+		assert.Equal(t, ErrInvalidResetCode, err)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		service := &PasswordResetServiceImpl{
+			userDao: userDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(nil, nil)
+
+		err := service.ConfirmReset(ctx, email, "123456", "newPassword1")
+		assert.Equal(t, ErrInvalidResetCode, err)
+	})
+}
+
+func TestGetPasswordResetService(t *testing.T) {
+	initEnv()
+	service1 := GetPasswordResetService()
+	service2 := GetPasswordResetService()
+	assert.Equal(t, service1, service2)
+	assert.NotNil(t, service1.userDao)
+	assert.NotNil(t, service1.passwordResetDao)
+	assert.NotNil(t, service1.emailService)
+}
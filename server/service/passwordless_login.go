@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// PasswordlessLoginService signs users in with a one-time code or link sent
+// to their email instead of a password.
+type PasswordlessLoginService interface {
+	RequestCode(ctx context.Context, client, email string) error
+	VerifyCode(ctx context.Context, client, email, code, clientIP string) (*LoginResult, error)
+	VerifyLink(ctx context.Context, client, token, clientIP string) (*LoginResult, error)
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type PasswordlessLoginServiceImpl struct {
+	userDao      dao.UserDao
+	loginCodeDao dao.UserLoginCodeDao
+	emailService proxy.EmailService
+	mfaService   MfaService
+	tokenService TokenService
+	loginGuard   LoginGuard
+	// linkBaseURL is the service prefix login links point to, emails only
+	// carry the code while it is empty
+	linkBaseURL string
+}
+
+var (
+	passwordlessLoginOnce sync.Once
+	passwordlessLoginInst *PasswordlessLoginServiceImpl
+)
+
+const (
+	LoginCodeTTL         = 10 * time.Minute
+	loginCodeMaxAttempts = 5
+	loginCodeCooldown    = time.Minute
+)
+
+var ErrInvalidLoginCode = errors.New("invalid or expired login code")
+
+func GetPasswordlessLoginService() *PasswordlessLoginServiceImpl {
+	passwordlessLoginOnce.Do(func() {
+		if passwordlessLoginInst == nil {
+			passwordlessLoginInst = &PasswordlessLoginServiceImpl{
+				userDao:      dao.GetUserDao(),
+				loginCodeDao: dao.GetUserLoginCodeDao(),
+				emailService: proxy.GetEmailInstance(),
+				mfaService:   GetMfaService(),
+				tokenService: GetTokenService(),
+				loginGuard:   GetLoginGuard(),
+			}
+			if cfg := config.Config.PasswordlessConfig; cfg != nil {
+				passwordlessLoginInst.linkBaseURL = cfg.LinkBaseURL
+			}
+		}
+	})
+	return passwordlessLoginInst
+}
+
+// RequestCode emails a login code and link to the user. Unknown, inactive
+// and other-client accounts are silently ignored so the endpoint cannot be
+// used to probe which emails are registered, and so are requests within a
+// minute of the previous code.
+func (ps *PasswordlessLoginServiceImpl) RequestCode(ctx context.Context, client, email string) error {
+	user, err := ps.userDao.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by email: %v", err)
+		return err
+	}
+	if user == nil || user.Status != model.UserStatusActive || !CanUseClient(user.Role, client) {
+		log.Logger.Warnf("Login code requested for unknown or inactive email: %s", email)
+		return nil
+	}
+	previous, err := ps.loginCodeDao.GetLatestByUserId(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if previous != nil && previous.CreatedAt.Add(loginCodeCooldown).After(time.Now()) {
+		log.Logger.Warnf("Login code for user %d requested within the cooldown", user.ID)
+		return nil
+	}
+	code, err := generateVerificationCode()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate login code: %v", err)
+		return err
+	}
+	codeHash, err := HashPassword(code)
+	if err != nil {
+		log.Logger.Errorf("Failed to hash login code: %v", err)
+		return err
+	}
+	linkToken, err := generateOpaqueToken()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate login link: %v", err)
+		return err
+	}
+	now := time.Now()
+	err = ps.loginCodeDao.Replace(ctx, &model.UserLoginCode{
+		UserID:    user.ID,
+		Audience:  client,
+		CodeHash:  codeHash,
+		LinkHash:  hashToken(linkToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(LoginCodeTTL),
+	})
+	if err != nil {
+		return err
+	}
+	body := "Your login code is: " + code
+	if ps.linkBaseURL != "" {
+		body += fmt.Sprintf("<br><a href=\"%s\">Sign in to CermiCraft</a>", LoginLinkURL(ps.linkBaseURL, client, linkToken))
+	}
+	err = ps.emailService.Send(body, user.Email, "CermiCraft Login Code")
+	if err != nil {
+		log.Logger.Errorf("Failed to send login code email: %v", err)
+		return err
+	}
+	log.Logger.Infof("Login code email sent for user: %d", user.ID)
+	return nil
+}
+
+// VerifyCode signs in with the emailed code. Wrong codes count as failed
+// logins for the LoginGuard and each code allows a few attempts.
+func (ps *PasswordlessLoginServiceImpl) VerifyCode(ctx context.Context, client, email, code, clientIP string) (*LoginResult, error) {
+	if err := ps.loginGuard.Check(ctx, email, clientIP); err != nil {
+		log.Logger.Warnf("Passwordless login for %s from %s throttled: %v", email, clientIP, err)
+		return nil, err
+	}
+	user, err := ps.userDao.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by email: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		ps.recordFailure(ctx, email, clientIP, nil)
+		return nil, ErrInvalidLoginCode
+	}
+	loginCode, err := ps.loginCodeDao.GetLatestByUserId(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ps.usable(loginCode, client) {
+		log.Logger.Warnf("No valid login code for user: %d", user.ID)
+		ps.recordFailure(ctx, email, clientIP, user)
+		return nil, ErrInvalidLoginCode
+	}
+	if VerifyPassword(loginCode.CodeHash, code) != nil {
+		if err := ps.loginCodeDao.IncrementAttempts(ctx, loginCode.ID); err != nil {
+			log.Logger.Errorf("Failed to record login code attempt: %v", err)
+		}
+		log.Logger.Warnf("Wrong login code for user: %d, attempts=%d", user.ID, loginCode.Attempts+1)
+		ps.recordFailure(ctx, email, clientIP, user)
+		return nil, ErrInvalidLoginCode
+	}
+	return ps.consume(ctx, loginCode, user, clientIP)
+}
+
+// VerifyLink signs in with the token of an emailed login link.
+func (ps *PasswordlessLoginServiceImpl) VerifyLink(ctx context.Context, client, token, clientIP string) (*LoginResult, error) {
+	if token == "" {
+		return nil, ErrInvalidLoginCode
+	}
+	loginCode, err := ps.loginCodeDao.GetByLinkHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if !ps.usable(loginCode, client) {
+		log.Logger.Warn("Login link is unknown, used or expired")
+		return nil, ErrInvalidLoginCode
+	}
+	user, err := ps.userDao.GetUserById(ctx, loginCode.UserID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidLoginCode
+	}
+	if err := ps.loginGuard.Check(ctx, user.Email, clientIP); err != nil {
+		log.Logger.Warnf("Passwordless login for %s from %s throttled: %v", user.Email, clientIP, err)
+		return nil, err
+	}
+	return ps.consume(ctx, loginCode, user, clientIP)
+}
+
+func (ps *PasswordlessLoginServiceImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return ps.loginCodeDao.DeleteExpired(ctx, now)
+}
+
+func (ps *PasswordlessLoginServiceImpl) usable(loginCode *model.UserLoginCode, client string) bool {
+	return loginCode != nil && loginCode.Audience == client &&
+		loginCode.ExpiresAt.After(time.Now()) && loginCode.Attempts < loginCodeMaxAttempts
+}
+
+// consume deletes the verified login code and completes the login. The
+// delete decides between concurrent uses of the same code.
+func (ps *PasswordlessLoginServiceImpl) consume(ctx context.Context, loginCode *model.UserLoginCode, user *model.User, clientIP string) (*LoginResult, error) {
+	consumed, err := ps.loginCodeDao.Delete(ctx, loginCode.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		log.Logger.Warnf("Login code %d of user %d was already used", loginCode.ID, user.ID)
+		return nil, ErrInvalidLoginCode
+	}
+	if err := ps.loginGuard.RecordSuccess(ctx, user.Email); err != nil {
+		log.Logger.Errorf("Failed to clear login failures: %v", err)
+	}
+	log.Logger.Infof("User %d signed in with a login code from %s", user.ID, clientIP)
+	return completeLogin(ctx, ps.mfaService, ps.tokenService, user, loginCode.Audience)
+}
+
+func (ps *PasswordlessLoginServiceImpl) recordFailure(ctx context.Context, email, clientIP string, user *model.User) {
+	if err := ps.loginGuard.RecordFailure(ctx, email, clientIP, user); err != nil {
+		log.Logger.Errorf("Failed to record login failure: %v", err)
+	}
+}
+
+// LoginLinkURL builds the login link emailed to the user. base is the public
+// service prefix, e.g. https://example.com/user-ms/v1
+func LoginLinkURL(base, client, token string) string {
+	return fmt.Sprintf("%s/%s/login/link?token=%s", strings.TrimRight(base, "/"), client, url.QueryEscape(token))
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	proxy_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/proxy/mocks"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newPasswordlessLoginService() (*PasswordlessLoginServiceImpl, *dao_mock.UserDao, *dao_mock.UserLoginCodeDao, *proxy_mock.EmailService) {
+	userDao := new(dao_mock.UserDao)
+	loginCodeDao := new(dao_mock.UserLoginCodeDao)
+	emailSender := new(proxy_mock.EmailService)
+	mfaDao := new(dao_mock.UserMfaDao)
+	mfaDao.On("GetByUserId", mock.Anything, mock.Anything).Return(nil, nil)
+	refreshTokenDao := new(dao_mock.RefreshTokenDao)
+	refreshTokenDao.On("Create", mock.Anything, mock.Anything).Return(nil)
+	service := &PasswordlessLoginServiceImpl{
+		userDao:      userDao,
+		loginCodeDao: loginCodeDao,
+		emailService: emailSender,
+		mfaService:   &MfaServiceImpl{userMfaDao: mfaDao},
+		tokenService: &TokenServiceImpl{userDao: userDao, refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDaoWithDefaults()},
+		loginGuard:   permissiveLoginGuard(),
+		linkBaseURL:  "https://shop.example.com/user-ms/v1",
+	}
+	return service, userDao, loginCodeDao, emailSender
+}
+
+func TestPasswordlessRequestCode(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	email := "buyer@example.com"
+	activeUser := &model.User{ID: 1, Email: email, Status: model.UserStatusActive, Role: model.UserRoleCustomer}
+
+	t.Run("Emails a code and a link", func(t *testing.T) {
+		service, userDao, loginCodeDao, emailSender := newPasswordlessLoginService()
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		loginCodeDao.On("GetLatestByUserId", mock.Anything, 1).Return(nil, nil)
+		var stored *model.UserLoginCode
+		loginCodeDao.On("Replace", mock.Anything, mock.MatchedBy(func(arg *model.UserLoginCode) bool {
+			stored = arg
+			return arg.UserID == 1 && arg.Audience == utils.AudienceCustomer && arg.ExpiresAt.After(time.Now())
+		})).Return(nil)
+		var code, token string
+		emailSender.On("Send", mock.MatchedBy(func(body string) bool {
+			match := regexp.MustCompile(`code is: (\d{6})<br><a href="https://shop\.example\.com/user-ms/v1/customer/login/link\?token=([^"]+)"`).FindStringSubmatch(body)
+			if match == nil {
+				return false
+			}
+			code = match[1]
+			token, _ = url.QueryUnescape(match[2])
+			return true
+		}), email, mock.Anything).Return(nil)
+
+		assert.NoError(t, service.RequestCode(ctx, utils.AudienceCustomer, email))
+		// Only digests of the secrets are stored
+		assert.NoError(t, VerifyPassword(stored.CodeHash, code))
+		assert.Equal(t, hashToken(token), stored.LinkHash)
+	})
+
+	t.Run("Unknown, inactive and other-client accounts are ignored", func(t *testing.T) {
+		for name, user := range map[string]*model.User{
+			"unknown":  nil,
+			"pending":  {ID: 1, Email: email, Status: model.UserStatusInactive, Role: model.UserRoleCustomer},
+			"merchant": {ID: 1, Email: email, Status: model.UserStatusActive, Role: model.UserRoleMerchant},
+		} {
+			service, userDao, loginCodeDao, emailSender := newPasswordlessLoginService()
+			userDao.On("GetUserByEmail", mock.Anything, email).Return(user, nil)
+			assert.NoError(t, service.RequestCode(ctx, utils.AudienceCustomer, email), name)
+			loginCodeDao.AssertNotCalled(t, "Replace", mock.Anything, mock.Anything)
+			emailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("Nothing is sent within the cooldown", func(t *testing.T) {
+		service, userDao, loginCodeDao, emailSender := newPasswordlessLoginService()
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		loginCodeDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserLoginCode{CreatedAt: time.Now().Add(-10 * time.Second)}, nil)
+		assert.NoError(t, service.RequestCode(ctx, utils.AudienceCustomer, email))
+		emailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPasswordlessVerify(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	email := "buyer@example.com"
+	activeUser := &model.User{ID: 1, Email: email, Status: model.UserStatusActive, Role: model.UserRoleCustomer}
+	codeHash, err := HashPassword("123456")
+	assert.NoError(t, err)
+	loginCode := func() *model.UserLoginCode {
+		return &model.UserLoginCode{
+			ID: 5, UserID: 1, Audience: utils.AudienceCustomer, CodeHash: codeHash,
+			LinkHash: hashToken("link-token"), ExpiresAt: time.Now().Add(time.Minute),
+		}
+	}
+
+	t.Run("Code signs in once", func(t *testing.T) {
+		service, userDao, loginCodeDao, _ := newPasswordlessLoginService()
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		loginCodeDao.On("GetLatestByUserId", mock.Anything, 1).Return(loginCode(), nil)
+		loginCodeDao.On("Delete", mock.Anything, int64(5)).Return(true, nil).Once()
+
+		result, err := service.VerifyCode(ctx, utils.AudienceCustomer, email, "123456", "10.0.0.1")
+		assert.NoError(t, err)
+		claims, err := utils.ParseJWTToken(result.Tokens.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, claims.ID)
+		assert.True(t, claims.HasAudience(utils.AudienceCustomer))
+
+		// A concurrent use of the same code lost the delete
+		loginCodeDao.On("Delete", mock.Anything, int64(5)).Return(false, nil).Once()
+		_, err = service.VerifyCode(ctx, utils.AudienceCustomer, email, "123456", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidLoginCode)
+	})
+
+	t.Run("Wrong code counts as a failed login", func(t *testing.T) {
+		service, userDao, loginCodeDao, _ := newPasswordlessLoginService()
+		failureDao := service.loginGuard.(*LoginGuardImpl).loginFailureDao.(*dao_mock.LoginFailureDao)
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+		loginCodeDao.On("GetLatestByUserId", mock.Anything, 1).Return(loginCode(), nil)
+		loginCodeDao.On("IncrementAttempts", mock.Anything, int64(5)).Return(nil)
+
+		_, err := service.VerifyCode(ctx, utils.AudienceCustomer, email, "654321", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidLoginCode)
+		loginCodeDao.AssertCalled(t, "IncrementAttempts", mock.Anything, int64(5))
+		failureDao.AssertCalled(t, "RecordFailure", mock.Anything, "account:"+email, mock.Anything, mock.Anything)
+		loginCodeDao.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Exhausted, expired or other-client codes are rejected", func(t *testing.T) {
+		exhausted := loginCode()
+		exhausted.Attempts = loginCodeMaxAttempts
+		expired := loginCode()
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+		merchant := loginCode()
+		merchant.Audience = utils.AudienceMerchant
+		for name, stored := range map[string]*model.UserLoginCode{
+			"exhausted": exhausted,
+			"expired":   expired,
+			"merchant":  merchant,
+			"missing":   nil,
+		} {
+			service, userDao, loginCodeDao, _ := newPasswordlessLoginService()
+			userDao.On("GetUserByEmail", mock.Anything, email).Return(activeUser, nil)
+			loginCodeDao.On("GetLatestByUserId", mock.Anything, 1).Return(stored, nil)
+			_, err := service.VerifyCode(ctx, utils.AudienceCustomer, email, "123456", "10.0.0.1")
+			assert.ErrorIs(t, err, ErrInvalidLoginCode, name)
+		}
+	})
+
+	t.Run("Link signs in", func(t *testing.T) {
+		service, userDao, loginCodeDao, _ := newPasswordlessLoginService()
+		loginCodeDao.On("GetByLinkHash", mock.Anything, hashToken("link-token")).Return(loginCode(), nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(activeUser, nil)
+		loginCodeDao.On("Delete", mock.Anything, int64(5)).Return(true, nil)
+
+		result, err := service.VerifyLink(ctx, utils.AudienceCustomer, "link-token", "10.0.0.1")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, result.Tokens.AccessToken)
+	})
+
+	t.Run("Unknown link or link of another client", func(t *testing.T) {
+		service, _, loginCodeDao, _ := newPasswordlessLoginService()
+		loginCodeDao.On("GetByLinkHash", mock.Anything, hashToken("link-token")).Return(loginCode(), nil)
+		loginCodeDao.On("GetByLinkHash", mock.Anything, hashToken("other")).Return(nil, nil)
+
+		_, err := service.VerifyLink(ctx, utils.AudienceMerchant, "link-token", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidLoginCode)
+		_, err = service.VerifyLink(ctx, utils.AudienceCustomer, "other", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidLoginCode)
+		loginCodeDao.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Account suspended after the code was sent", func(t *testing.T) {
+		service, userDao, loginCodeDao, _ := newPasswordlessLoginService()
+		loginCodeDao.On("GetByLinkHash", mock.Anything, hashToken("link-token")).Return(loginCode(), nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Email: email, Status: model.UserStatusSuspended, Role: model.UserRoleCustomer}, nil)
+		loginCodeDao.On("Delete", mock.Anything, int64(5)).Return(true, nil)
+
+		_, err := service.VerifyLink(ctx, utils.AudienceCustomer, "link-token", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrAccountSuspended)
+	})
+}
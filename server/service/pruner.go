@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+)
+
+// ExpiryPruner removes stored entries that are past their expiry.
+type ExpiryPruner interface {
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+const pruneInterval = time.Hour
+
+// StartExpiryPruner periodically runs every pruner. It blocks, so run it in
+// its own goroutine.
+func StartExpiryPruner(pruners ...ExpiryPruner) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, pruner := range pruners {
+			count, err := pruner.PruneExpired(context.Background(), time.Now())
+			if err != nil {
+				log.Logger.Errorf("Failed to prune expired entries with %T: %v", pruner, err)
+				continue
+			}
+			log.Logger.Infof("Pruned %d expired entries with %T", count, pruner)
+		}
+	}
+}
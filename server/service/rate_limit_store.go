@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/middleware"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// RateLimitStore holds the token buckets of the rate limiting middleware.
+type RateLimitStore interface {
+	middleware.RateLimitStore
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+const RateLimitBackendMySQL = "mysql"
+
+var (
+	rateLimitStoreInst RateLimitStore
+	rateLimitStoreOnce sync.Once
+)
+
+// GetRateLimitStore returns the store selected by rate_limit.backend. The
+// default keeps buckets in memory, so each instance enforces the limits on
+// its own.
+func GetRateLimitStore() RateLimitStore {
+	rateLimitStoreOnce.Do(func() {
+		rlConfig := config.Config.RateLimitConfig
+		if rlConfig != nil && rlConfig.Backend == RateLimitBackendMySQL {
+			rateLimitStoreInst = &DBRateLimitStore{rateLimitBucketDao: dao.GetRateLimitBucketDao()}
+			return
+		}
+		rateLimitStoreInst = middleware.NewMemoryRateLimitStore()
+	})
+	return rateLimitStoreInst
+}
+
+// DBRateLimitStore keeps the buckets in MySQL so limits hold across all
+// service instances.
+type DBRateLimitStore struct {
+	rateLimitBucketDao dao.RateLimitBucketDao
+}
+
+func (s *DBRateLimitStore) Take(ctx context.Context, key string, limit middleware.RateLimit, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	err := s.rateLimitBucketDao.Update(ctx, key, func(bucket *model.RateLimitBucket) *model.RateLimitBucket {
+		var tokens float64
+		var refilledAt time.Time
+		if bucket != nil {
+			tokens = bucket.Tokens
+			refilledAt = time.UnixMilli(bucket.RefilledAtMs)
+		}
+		tokens, wait = limit.Take(tokens, refilledAt, now)
+		return &model.RateLimitBucket{
+			Tokens:       tokens,
+			RefilledAtMs: now.UnixMilli(),
+			FullAt:       now.Add(limit.FullAfter(tokens)),
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return wait, nil
+}
+
+func (s *DBRateLimitStore) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return s.rateLimitBucketDao.DeleteExpired(ctx, now)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/middleware"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testRateLimit = middleware.RateLimit{Name: "login", Requests: 2, Period: time.Minute}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	store := middleware.NewMemoryRateLimitStore()
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		wait, err := store.Take(ctx, "ip:1", testRateLimit, now)
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+	wait, _ := store.Take(ctx, "ip:1", testRateLimit, now)
+	assert.Equal(t, 30*time.Second, wait)
+	// Other keys have their own bucket
+	wait, _ = store.Take(ctx, "ip:2", testRateLimit, now)
+	assert.Zero(t, wait)
+	// One token is back after half the period
+	wait, _ = store.Take(ctx, "ip:1", testRateLimit, now.Add(30*time.Second))
+	assert.Zero(t, wait)
+
+	count, err := store.PruneExpired(ctx, now.Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestDBRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("New bucket starts full", func(t *testing.T) {
+		bucketDao := new(dao_mock.RateLimitBucketDao)
+		store := &DBRateLimitStore{rateLimitBucketDao: bucketDao}
+		var saved *model.RateLimitBucket
+		bucketDao.On("Update", mock.Anything, "login:ip:1", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(2).(func(*model.RateLimitBucket) *model.RateLimitBucket)(nil)
+		}).Return(nil)
+
+		wait, err := store.Take(ctx, "login:ip:1", testRateLimit, now)
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+		assert.Equal(t, 1.0, saved.Tokens)
+		assert.Equal(t, now.UnixMilli(), saved.RefilledAtMs)
+		assert.WithinDuration(t, now.Add(30*time.Second), saved.FullAt, time.Millisecond)
+	})
+
+	t.Run("Empty bucket asks to wait", func(t *testing.T) {
+		bucketDao := new(dao_mock.RateLimitBucketDao)
+		store := &DBRateLimitStore{rateLimitBucketDao: bucketDao}
+		bucketDao.On("Update", mock.Anything, "login:ip:1", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(2).(func(*model.RateLimitBucket) *model.RateLimitBucket)(&model.RateLimitBucket{
+				Tokens: 0.5, RefilledAtMs: now.Add(-6 * time.Second).UnixMilli(),
+			})
+		}).Return(nil)
+
+		wait, err := store.Take(ctx, "login:ip:1", testRateLimit, now)
+		assert.NoError(t, err)
+		// 0.5 + 6s * 2/60s = 0.7 tokens, 0.3 missing
+		assert.InDelta(t, 9*time.Second, wait, float64(10*time.Millisecond))
+	})
+
+	t.Run("Store errors are returned", func(t *testing.T) {
+		bucketDao := new(dao_mock.RateLimitBucketDao)
+		store := &DBRateLimitStore{rateLimitBucketDao: bucketDao}
+		bucketDao.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(assert.AnError)
+
+		_, err := store.Take(ctx, "login:ip:1", testRateLimit, now)
+		assert.Equal(t, assert.AnError, err)
+	})
+}
@@ -21,7 +21,12 @@ import (
 
 type RegisterService interface {
 	Register(ctx context.Context, email, password string) error
-	VerifyAndActivate(ctx context.Context, activationCode string) error
+	ResendActivation(ctx context.Context, email string) error
+	VerifyAndActivate(ctx context.Context, email, activationCode string) error
+	ActivateByLink(ctx context.Context, token string) error
+	// ActivateUser activates a pending account without a code on behalf of
+	// the staff member actorID.
+	ActivateUser(ctx context.Context, userID, actorID int) error
 }
 
 type RegisterImpl struct {
@@ -30,6 +35,12 @@ type RegisterImpl struct {
 	emailService   proxy.EmailService
 	txBeginner     repository.TxBeginner
 	kafkaProducer  mq.KafkaProducer
+	passwordPolicy PasswordPolicy
+	lifecycle      UserLifecycleService
+	// linkSecret signs activation links, they are left out of the email
+	// while it is empty
+	linkSecret  []byte
+	linkBaseURL string
 }
 
 var (
@@ -37,7 +48,32 @@ var (
 	registerOnce        sync.Once
 )
 
-const activationExpiryDuration = time.Minute * 5
+const (
+	activationExpiryDuration = time.Minute * 5
+	activationMaxAttempts    = 5
+	activationResendCooldown = time.Minute
+	activationDailySendLimit = 5
+)
+
+var (
+	ErrInvalidActivationCode = errors.New("invalid or expired activation code")
+	ErrActivationThrottled   = errors.New("too many activation emails requested")
+)
+
+// ActivationThrottledError tells the client how long to wait before another
+// activation code can be sent. It matches ErrActivationThrottled with
+// errors.Is.
+type ActivationThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ActivationThrottledError) Error() string {
+	return fmt.Sprintf("too many activation emails requested, retry in %d seconds", int(e.RetryAfter.Round(time.Second).Seconds()))
+}
+
+func (e *ActivationThrottledError) Is(target error) bool {
+	return target == ErrActivationThrottled
+}
 
 func GetRegisterService() *RegisterImpl {
 	registerOnce.Do(func() {
@@ -48,32 +84,56 @@ func GetRegisterService() *RegisterImpl {
 				emailService:   proxy.GetEmailInstance(),
 				txBeginner:     repository.DB,
 				kafkaProducer:  mq.GetKafkaProducer(),
+				passwordPolicy: GetPasswordPolicy(),
+				lifecycle:      GetUserLifecycleService(),
+			}
+			if cfg := config.Config.ActivationConfig; cfg != nil && cfg.LinkSecret != "" {
+				registerServiceInst.linkSecret = []byte(cfg.LinkSecret)
+				registerServiceInst.linkBaseURL = cfg.LinkBaseURL
+			} else {
+				log.Logger.Warn("ACTIVATION_LINK_SECRET is not set, activation emails only carry the code")
 			}
 		}
 	})
 	return registerServiceInst
 }
 
+// Register creates a pending account and emails its activation code.
+// Registering a still pending email again sends a new code that activates
+// the account with the new password; the password stays unchanged unless
+// that code is used.
 func (rs *RegisterImpl) Register(ctx context.Context, email, password string) error {
 	user, err := rs.userDao.GetUserByEmail(ctx, email)
 	if err != nil {
 		log.Logger.Errorf("Failed to get user by email: %v", err)
 		return err
 	}
-	if user != nil && user.Status == model.UserStatusActive {
+	if user != nil && user.Status != model.UserStatusInactive {
 		log.Logger.Errorf("User already exists with email: %s", email)
 		return errors.New("user already exists")
 	}
-	if user == nil {
-		hashedPassword, err := HashPassword(password)
+	if err := rs.passwordPolicy.Validate(ctx, password, PasswordSubject{Email: email}); err != nil {
+		return err
+	}
+	var previous *model.UserActivation
+	if user != nil {
+		// Check the limits first so a throttled request changes nothing
+		previous, err = rs.checkActivationSendLimits(ctx, user.ID)
 		if err != nil {
-			log.Logger.Errorf("Failed to hash password: %v", err)
 			return err
 		}
+	}
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		log.Logger.Errorf("Failed to hash password: %v", err)
+		return err
+	}
+	if user == nil {
 		user = &model.User{
 			Email:     email,
 			Password:  hashedPassword,
 			Status:    model.UserStatusInactive,
+			Role:      model.UserRoleCustomer,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
@@ -82,23 +142,110 @@ func (rs *RegisterImpl) Register(ctx context.Context, email, password string) er
 			log.Logger.Errorf("Failed to create user: %v", err)
 			return err
 		}
+		return rs.sendActivationCode(ctx, user, previous, "")
+	}
+	log.Logger.Infof("User %d registered again, the new password waits for activation", user.ID)
+	return rs.sendActivationCode(ctx, user, previous, hashedPassword)
+}
+
+// ResendActivation sends a fresh activation code to a pending account.
+// Unknown and already active emails are silently ignored so the endpoint
+// cannot be used to probe which emails are registered. The new code does not
+// carry the password of a repeated registration, which anyone could have
+// made.
+func (rs *RegisterImpl) ResendActivation(ctx context.Context, email string) error {
+	user, err := rs.userDao.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by email: %v", err)
+		return err
+	}
+	if user == nil || user.Status != model.UserStatusInactive {
+		log.Logger.Warnf("Activation resend requested for unknown or active email: %s", email)
+		return nil
+	}
+	previous, err := rs.checkActivationSendLimits(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return rs.sendActivationCode(ctx, user, previous, "")
+}
+
+// checkActivationSendLimits enforces the cooldown between codes and the
+// daily cap. It returns the activation the next code replaces, if any.
+func (rs *RegisterImpl) checkActivationSendLimits(ctx context.Context, userID int) (*model.UserActivation, error) {
+	previous, err := rs.userActivation.GetByUserId(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user activation by user id: %v", err)
+		return nil, err
+	}
+	if previous == nil {
+		return nil, nil
+	}
+	now := time.Now()
+	if wait := previous.CreatedAt.Add(activationResendCooldown).Sub(now); wait > 0 {
+		log.Logger.Warnf("Activation code for user %d requested within the cooldown", userID)
+		return nil, &ActivationThrottledError{RetryAfter: wait}
+	}
+	if firstSentAt := activationFirstSentAt(previous); previous.SendCount >= activationDailySendLimit {
+		if wait := firstSentAt.Add(24 * time.Hour).Sub(now); wait > 0 {
+			log.Logger.Warnf("Daily activation email cap reached for user %d", userID)
+			return nil, &ActivationThrottledError{RetryAfter: wait}
+		}
+	}
+	return previous, nil
+}
+
+func activationFirstSentAt(activation *model.UserActivation) time.Time {
+	if activation.FirstSentAt != nil {
+		return *activation.FirstSentAt
+	}
+	return activation.CreatedAt
+}
+
+// sendActivationCode replaces the user's activation with a new code and
+// emails it, counting it against the daily cap of the previous activation.
+// A non-empty pendingPassword becomes the user's password on activation.
+func (rs *RegisterImpl) sendActivationCode(ctx context.Context, user *model.User, previous *model.UserActivation, pendingPassword string) error {
+	now := time.Now()
+	sendCount, firstSentAt := 1, now
+	if previous != nil && activationFirstSentAt(previous).Add(24*time.Hour).After(now) {
+		sendCount, firstSentAt = previous.SendCount+1, activationFirstSentAt(previous)
 	}
 	code, err := generateVerificationCode()
 	if err != nil {
 		log.Logger.Errorf("Failed to generate verification code: %v", err)
 		return err
 	}
-	err = rs.userActivation.Replace(ctx, &model.UserActivation{
-		UserID:    user.ID,
-		Code:      code,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(activationExpiryDuration),
-	})
+	// Six digits are quickly brute forced from a fast hash, so the code is
+	// stored like a password
+	codeHash, err := HashPassword(code)
+	if err != nil {
+		log.Logger.Errorf("Failed to hash verification code: %v", err)
+		return err
+	}
+	activation := &model.UserActivation{
+		UserID:          user.ID,
+		CodeHash:        codeHash,
+		PendingPassword: pendingPassword,
+		SendCount:       sendCount,
+		FirstSentAt:     &firstSentAt,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(activationExpiryDuration),
+	}
+	err = rs.userActivation.Replace(ctx, activation)
 	if err != nil {
 		log.Logger.Errorf("Failed to create user activation: %v", err)
 		return err
 	}
-	err = rs.emailService.Send("Your activation code is: "+code, email, "CermiCraft Activation Code")
+	body := "Your activation code is: " + code
+	if pendingPassword != "" {
+		body += "<br>This email address was registered again. Activating with this code sets the password chosen in that registration; if it was not you, ignore this email."
+	}
+	if len(rs.linkSecret) > 0 {
+		link := ActivationLinkURL(rs.linkBaseURL, signActivationLink(rs.linkSecret, activation.ID, user.ID))
+		body += fmt.Sprintf("<br><a href=\"%s\">Activate your account</a>", link)
+	}
+	err = rs.emailService.Send(body, user.Email, "CermiCraft Activation Code")
 	if err != nil {
 		log.Logger.Errorf("Failed to send activation email: %v", err)
 		return err
@@ -107,32 +254,115 @@ func (rs *RegisterImpl) Register(ctx context.Context, email, password string) er
 	return nil
 }
 
-func (rs *RegisterImpl) VerifyAndActivate(ctx context.Context, activationCode string) error {
-	userActivation, err := rs.userActivation.GetByCode(ctx, activationCode)
+// VerifyAndActivate activates the account of email if the code matches its
+// latest activation. Each activation allows a few attempts before it has to
+// be resent.
+func (rs *RegisterImpl) VerifyAndActivate(ctx context.Context, email, activationCode string) error {
+	user, err := rs.userDao.GetUserByEmail(ctx, email)
 	if err != nil {
-		log.Logger.Errorf("Failed to get user activation by code: %v", err)
+		log.Logger.Errorf("Failed to get user by email: %v", err)
+		return err
+	}
+	if user == nil || user.Status != model.UserStatusInactive {
+		log.Logger.Warnf("Activation attempted for unknown or active email: %s", email)
+		return ErrInvalidActivationCode
+	}
+	userActivation, err := rs.userActivation.GetByUserId(ctx, user.ID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user activation by user id: %v", err)
 		return err
 	}
-	if userActivation == nil || userActivation.ExpiresAt.Before(time.Now()) {
-		log.Logger.Warnf("Invalid or expired activation code: %s", activationCode)
-		return errors.New("invalid or expired activation code")
+	if userActivation == nil || userActivation.ExpiresAt.Before(time.Now()) || userActivation.Attempts >= activationMaxAttempts {
+		log.Logger.Warnf("No valid activation for user: %d", user.ID)
+		return ErrInvalidActivationCode
+	}
+	if VerifyPassword(userActivation.CodeHash, activationCode) != nil {
+		if err := rs.userActivation.IncrementAttempts(ctx, userActivation.ID); err != nil {
+			log.Logger.Errorf("Failed to record activation attempt: %v", err)
+		}
+		log.Logger.Warnf("Wrong activation code for user: %d, attempts=%d", user.ID, userActivation.Attempts+1)
+		return ErrInvalidActivationCode
+	}
+	return rs.activate(ctx, userActivation.UserID, userActivation.PendingPassword, StatusReasonActivated, nil)
+}
+
+// ActivateByLink activates the account an emailed activation link was
+// issued for. The link is single use: it references the activation row,
+// which activation deletes and a newer code replaces.
+func (rs *RegisterImpl) ActivateByLink(ctx context.Context, token string) error {
+	if len(rs.linkSecret) == 0 {
+		return ErrInvalidActivationCode
 	}
-	err = rs.txBeginner.Transaction(func(tx *gorm.DB) error {
+	activationID, userID, ok := parseActivationLink(rs.linkSecret, token)
+	if !ok {
+		log.Logger.Warn("Activation link with an invalid signature")
+		return ErrInvalidActivationCode
+	}
+	userActivation, err := rs.userActivation.GetById(ctx, activationID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user activation by id: %v", err)
+		return err
+	}
+	if userActivation == nil || userActivation.UserID != userID || userActivation.ExpiresAt.Before(time.Now()) || userActivation.Attempts >= activationMaxAttempts {
+		log.Logger.Warnf("Activation link for activation %d is used or expired", activationID)
+		return ErrInvalidActivationCode
+	}
+	user, err := rs.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return err
+	}
+	if user == nil || user.Status != model.UserStatusInactive {
+		log.Logger.Warnf("Activation link followed for unknown or active user: %d", userID)
+		return ErrInvalidActivationCode
+	}
+	return rs.activate(ctx, userActivation.UserID, userActivation.PendingPassword, StatusReasonActivated, nil)
+}
+
+// ActivateUser activates a pending account on behalf of staff, e.g. when
+// the activation emails do not arrive. The password of a repeated
+// registration is not applied, nobody proved they own the email.
+func (rs *RegisterImpl) ActivateUser(ctx context.Context, userID, actorID int) error {
+	err := rs.activate(ctx, userID, "", StatusReasonActivatedByAdmin, &actorID)
+	if errors.Is(err, ErrInvalidActivationCode) {
+		return ErrStatusChanged
+	}
+	return err
+}
+
+// activate marks the user active, sets pendingPassword if not empty, consumes
+// its activation and announces the activation on Kafka.
+func (rs *RegisterImpl) activate(ctx context.Context, userID int, pendingPassword, reason string, actorID *int) error {
+	err := rs.txBeginner.Transaction(func(tx *gorm.DB) error {
 		curTime := time.Now()
-		err = rs.userDao.UpdateUserInTransaction(ctx, &model.User{ID: userActivation.UserID, Status: model.UserStatusActive, ActivateTime: &curTime, UpdatedAt: curTime}, tx)
+		err := rs.lifecycle.TransitionInTransaction(ctx, userID, model.UserStatusInactive, model.UserStatusActive, reason, actorID, tx)
+		if errors.Is(err, ErrStatusChanged) {
+			// Activated or suspended since the code was checked
+			return ErrInvalidActivationCode
+		}
+		if err != nil {
+			return err
+		}
+		err = rs.userDao.UpdateUserInTransaction(ctx, &model.User{ID: userID, ActivateTime: &curTime, UpdatedAt: curTime}, tx)
 		if err != nil {
 			log.Logger.Errorf("Failed to update user status: %v", err)
 			return err
 		}
-		log.Logger.Infof("User %d activated successfully", userActivation.UserID)
-		err = rs.userActivation.DeleteByUserId(ctx, userActivation.UserID, tx)
+		if pendingPassword != "" {
+			if err := rs.userDao.UpdatePasswordInTransaction(ctx, userID, pendingPassword, tx); err != nil {
+				return err
+			}
+			log.Logger.Infof("Password of user %d replaced by its repeated registration", userID)
+		}
+		log.Logger.Infof("User %d activated successfully", userID)
+		err = rs.userActivation.DeleteByUserId(ctx, userID, tx)
 		if err != nil {
 			log.Logger.Warnf("Failed to delete user activation after activation: %v", err)
 			return err
 		}
-		log.Logger.Infof("User activation %d marked as used", userActivation.ID)
-		eventMsg := &mq.UserActivatedEvent{UserID: userActivation.UserID, ActivateTime: curTime.Unix()}
-		err = rs.kafkaProducer.Produce(ctx, config.Config.KafkaConfig.UserActivatedTopic, fmt.Sprintf("%d", userActivation.UserID), eventMsg.ToBytes())
+		log.Logger.Infof("Activation of user %d marked as used", userID)
+		eventMsg := &mq.UserActivatedEvent{UserID: userID, ActivateTime: curTime.Unix()}
+		err = rs.kafkaProducer.Produce(ctx, config.Config.KafkaConfig.UserActivatedTopic, fmt.Sprintf("%d", userID), eventMsg.ToBytes())
 		if err != nil {
 			log.Logger.Errorf("Failed to produce user activated event: %v", err)
 			return err
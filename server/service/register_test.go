@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,6 +29,7 @@ func TestRegister(t *testing.T) {
 		userDao := new(dao_mock.UserDao)
 		emailSender := new(proxy_mock.EmailService)
 		service := &RegisterImpl{
+			passwordPolicy: permissivePasswordPolicy(),
 			userDao:        userDao,
 			userActivation: userActivationDao,
 			emailService:   emailSender,
@@ -38,10 +42,16 @@ func TestRegister(t *testing.T) {
 			arg.ID = userId                                       // Simulate DB assigning ID
 			return arg.Email == email && arg.Password != password // Password should be hashed
 		})).Return(1, nil)
+		var codeHash string
 		userActivationDao.On("Replace", mock.Anything, mock.MatchedBy(func(arg *model.UserActivation) bool {
-			return arg.UserID == userId && len(arg.Code) == 6
+			codeHash = arg.CodeHash
+			return arg.UserID == userId && arg.CodeHash != ""
 		})).Return(nil)
-		emailSender.On("Send", mock.Anything, email, mock.Anything).Return(nil)
+		emailSender.On("Send", mock.MatchedBy(func(body string) bool {
+			// Only the hash is stored, the code itself is in the email
+			code := body[len(body)-6:]
+			return VerifyPassword(codeHash, code) == nil && !strings.Contains(codeHash, code)
+		}), email, mock.Anything).Return(nil)
 		err := service.Register(ctx, email, password)
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
@@ -57,7 +67,8 @@ func TestRegister(t *testing.T) {
 	t.Run("User already exists", func(t *testing.T) {
 		userDao := new(dao_mock.UserDao)
 		service := &RegisterImpl{
-			userDao: userDao,
+			passwordPolicy: permissivePasswordPolicy(),
+			userDao:        userDao,
 		}
 		email := "test@example.com"
 		password := "password123"
@@ -71,7 +82,8 @@ func TestRegister(t *testing.T) {
 	t.Run("Database error on GetUserByEmail", func(t *testing.T) {
 		userDao := new(dao_mock.UserDao)
 		service := &RegisterImpl{
-			userDao: userDao,
+			passwordPolicy: permissivePasswordPolicy(),
+			userDao:        userDao,
 		}
 		email := "test@example.com"
 		password := "password123"
@@ -85,7 +97,8 @@ func TestRegister(t *testing.T) {
 	t.Run("Database error on CreateUser", func(t *testing.T) {
 		userDao := new(dao_mock.UserDao)
 		service := &RegisterImpl{
-			userDao: userDao,
+			passwordPolicy: permissivePasswordPolicy(),
+			userDao:        userDao,
 		}
 		email := "test@example.com"
 		userDao.On("GetUserByEmail", mock.Anything, email).Return(nil, nil)
@@ -100,6 +113,7 @@ func TestRegister(t *testing.T) {
 		userActivationDao := new(dao_mock.UserActivationDao)
 		userDao := new(dao_mock.UserDao)
 		service := &RegisterImpl{
+			passwordPolicy: permissivePasswordPolicy(),
 			userDao:        userDao,
 			userActivation: userActivationDao,
 		}
@@ -118,6 +132,7 @@ func TestRegister(t *testing.T) {
 		userDao := new(dao_mock.UserDao)
 		emailSender := new(proxy_mock.EmailService)
 		service := &RegisterImpl{
+			passwordPolicy: permissivePasswordPolicy(),
 			userDao:        userDao,
 			userActivation: userActivationDao,
 			emailService:   emailSender,
@@ -134,6 +149,209 @@ func TestRegister(t *testing.T) {
 	})
 }
 
+func TestRegisterPendingAccount(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	email := "test@example.com"
+	pendingUser := &model.User{ID: 1, Email: email, Status: model.UserStatusInactive}
+
+	t.Run("Re-registration keeps the new password for activation", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &RegisterImpl{
+			passwordPolicy: permissivePasswordPolicy(),
+			userDao:        userDao,
+			userActivation: userActivationDao,
+			emailService:   emailSender,
+		}
+		firstSentAt := time.Now().Add(-time.Hour)
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			UserID: 1, SendCount: 2, FirstSentAt: &firstSentAt, CreatedAt: time.Now().Add(-10 * time.Minute),
+		}, nil)
+		userActivationDao.On("Replace", mock.Anything, mock.MatchedBy(func(arg *model.UserActivation) bool {
+			return arg.SendCount == 3 && arg.FirstSentAt.Equal(firstSentAt) && VerifyPassword(arg.PendingPassword, "newPassword1") == nil
+		})).Return(nil)
+		emailSender.On("Send", mock.Anything, email, mock.Anything).Return(nil)
+
+		assert.NoError(t, service.Register(ctx, email, "newPassword1"))
+		userDao.AssertExpectations(t)
+		userActivationDao.AssertExpectations(t)
+		// The account keeps its password until the new code is used
+		userDao.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Activation applies the password of the repeated registration", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		kafkaProducer := new(mq_mock.KafkaProducer)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
+			txBeginner:     &fakeTx{DB: initMemDb(t)},
+			kafkaProducer:  kafkaProducer,
+			lifecycle:      newUserLifecycle(userDao, kafkaProducer),
+		}
+		codeHash, _ := HashPassword("123456")
+		pendingPassword, _ := HashPassword("newPassword1")
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			UserID: 1, CodeHash: codeHash, PendingPassword: pendingPassword, ExpiresAt: time.Now().Add(time.Minute),
+		}, nil)
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusInactive, model.UserStatusActive, false, mock.Anything).Return(true, nil)
+		userDao.On("UpdateUserInTransaction", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		userDao.On("UpdatePasswordInTransaction", mock.Anything, 1, pendingPassword, mock.Anything).Return(nil)
+		userActivationDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		assert.NoError(t, service.VerifyAndActivate(ctx, email, "123456"))
+		userDao.AssertExpectations(t)
+	})
+
+	t.Run("Resent codes do not carry the repeated registration", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
+			emailService:   emailSender,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			UserID: 1, PendingPassword: "pending-hash", SendCount: 1, CreatedAt: time.Now().Add(-10 * time.Minute),
+		}, nil)
+		userActivationDao.On("Replace", mock.Anything, mock.MatchedBy(func(arg *model.UserActivation) bool {
+			return arg.PendingPassword == ""
+		})).Return(nil)
+		emailSender.On("Send", mock.Anything, email, mock.Anything).Return(nil)
+
+		assert.NoError(t, service.ResendActivation(ctx, email))
+		userActivationDao.AssertExpectations(t)
+	})
+
+	t.Run("Re-registration within the cooldown changes nothing", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		service := &RegisterImpl{
+			passwordPolicy: permissivePasswordPolicy(),
+			userDao:        userDao,
+			userActivation: userActivationDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			UserID: 1, SendCount: 1, CreatedAt: time.Now().Add(-10 * time.Second),
+		}, nil)
+
+		err := service.Register(ctx, email, "newPassword1")
+		assert.True(t, errors.Is(err, ErrActivationThrottled))
+		userDao.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestResendActivation(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	email := "test@example.com"
+	pendingUser := &model.User{ID: 1, Email: email, Status: model.UserStatusInactive}
+
+	t.Run("Sends a new code", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
+			emailService:   emailSender,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(nil, nil)
+		userActivationDao.On("Replace", mock.Anything, mock.MatchedBy(func(arg *model.UserActivation) bool {
+			return arg.UserID == 1 && arg.SendCount == 1 && arg.CodeHash != ""
+		})).Return(nil)
+		emailSender.On("Send", mock.Anything, email, mock.Anything).Return(nil)
+
+		assert.NoError(t, service.ResendActivation(ctx, email))
+		emailSender.AssertExpectations(t)
+	})
+
+	t.Run("Cooldown", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			UserID: 1, SendCount: 1, CreatedAt: time.Now().Add(-20 * time.Second),
+		}, nil)
+
+		err := service.ResendActivation(ctx, email)
+		var throttled *ActivationThrottledError
+		assert.True(t, errors.As(err, &throttled))
+		assert.InDelta(t, (40 * time.Second).Seconds(), throttled.RetryAfter.Seconds(), 2)
+	})
+
+	t.Run("Daily cap", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
+		}
+		firstSentAt := time.Now().Add(-23 * time.Hour)
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			UserID: 1, SendCount: activationDailySendLimit, FirstSentAt: &firstSentAt, CreatedAt: time.Now().Add(-time.Hour),
+		}, nil)
+
+		err := service.ResendActivation(ctx, email)
+		var throttled *ActivationThrottledError
+		assert.True(t, errors.As(err, &throttled))
+		assert.InDelta(t, time.Hour.Seconds(), throttled.RetryAfter.Seconds(), 2)
+	})
+
+	t.Run("Cap resets after a day", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
+			emailService:   emailSender,
+		}
+		firstSentAt := time.Now().Add(-25 * time.Hour)
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			UserID: 1, SendCount: activationDailySendLimit, FirstSentAt: &firstSentAt, CreatedAt: time.Now().Add(-time.Hour),
+		}, nil)
+		userActivationDao.On("Replace", mock.Anything, mock.MatchedBy(func(arg *model.UserActivation) bool {
+			return arg.SendCount == 1 && arg.FirstSentAt.After(firstSentAt)
+		})).Return(nil)
+		emailSender.On("Send", mock.Anything, email, mock.Anything).Return(nil)
+
+		assert.NoError(t, service.ResendActivation(ctx, email))
+		userActivationDao.AssertExpectations(t)
+	})
+
+	t.Run("Active or unknown emails are ignored", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		emailSender := new(proxy_mock.EmailService)
+		service := &RegisterImpl{
+			userDao:      userDao,
+			emailService: emailSender,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(&model.User{ID: 1, Status: model.UserStatusActive}, nil)
+		userDao.On("GetUserByEmail", mock.Anything, "ghost@example.com").Return(nil, nil)
+
+		assert.NoError(t, service.ResendActivation(ctx, email))
+		assert.NoError(t, service.ResendActivation(ctx, "ghost@example.com"))
+		emailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
 type fakeTx struct{ *gorm.DB }
 
 func (f *fakeTx) Transaction(fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
@@ -150,23 +368,26 @@ func initMemDb(t *testing.T) *gorm.DB {
 func TestVerifyAndActivate(t *testing.T) {
 	initEnv()
 	ctx := context.Background()
+	email := "test@example.com"
+	pendingUser := &model.User{ID: 1, Email: email, Status: model.UserStatusInactive}
+	codeHash, err := HashPassword("123456")
+	assert.NoError(t, err)
 
 	t.Run("Activation code expired", func(t *testing.T) {
 		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
 		service := &RegisterImpl{
+			userDao:        userDao,
 			userActivation: userActivationDao,
 		}
-		code := "expired-code"
-		userActivationDao.On("GetByCode", mock.Anything, code).Return(&model.UserActivation{
-			Code:      code,
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			CodeHash:  codeHash,
 			ExpiresAt: time.Now().Add(-time.Minute),
 			UserID:    1,
 		}, nil)
-		err := service.VerifyAndActivate(ctx, code)
-		if err == nil || err.Error() != "invalid or expired activation code" {
-			t.Fatalf("Expected 'invalid or expired activation code' error, got %v", err)
-		}
-		userActivationDao.AssertCalled(t, "GetByCode", mock.Anything, code)
+		err := service.VerifyAndActivate(ctx, email, "123456")
+		assert.Equal(t, ErrInvalidActivationCode, err)
 		userActivationDao.AssertExpectations(t)
 	})
 
@@ -181,20 +402,22 @@ func TestVerifyAndActivate(t *testing.T) {
 			emailService:   emailSender,
 			txBeginner:     &fakeTx{DB: initMemDb(t)},
 			kafkaProducer:  kafkaProducer,
+			lifecycle:      newUserLifecycle(userDao, kafkaProducer),
 		}
-		validCode := "valid-code"
-		userActivationDao.On("GetByCode", mock.Anything, mock.Anything).Return(&model.UserActivation{
-			Code:      validCode,
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			CodeHash:  codeHash,
 			UserID:    1,
 			ExpiresAt: time.Now().Add(time.Minute * 10),
 		}, nil)
 
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusInactive, model.UserStatusActive, false, mock.Anything).Return(true, nil)
 		userDao.On("UpdateUserInTransaction", mock.Anything, mock.MatchedBy(func(arg *model.User) bool {
-			return arg.Status == model.UserStatusActive
+			return arg.ActivateTime != nil
 		}), mock.Anything).Return(nil)
 		userActivationDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
 		kafkaProducer.On("Produce", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
-		err := service.VerifyAndActivate(ctx, validCode)
+		err := service.VerifyAndActivate(ctx, email, "123456")
 		if err != nil {
 			t.Fatalf("Expected no error, got %v", err)
 		}
@@ -205,19 +428,181 @@ func TestVerifyAndActivate(t *testing.T) {
 		kafkaProducer.AssertCalled(t, "Produce", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	})
 
-	t.Run("Invalid activation code", func(t *testing.T) {
+	t.Run("Wrong code increments attempts", func(t *testing.T) {
 		userActivationDao := new(dao_mock.UserActivationDao)
-		userActivationDao.On("GetByCode", mock.Anything, "invalid-code").Return(nil, nil)
+		userDao := new(dao_mock.UserDao)
 		service := &RegisterImpl{
+			userDao:        userDao,
 			userActivation: userActivationDao,
 		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			ID: 7, CodeHash: codeHash, UserID: 1, ExpiresAt: time.Now().Add(time.Minute),
+		}, nil)
+		userActivationDao.On("IncrementAttempts", mock.Anything, int64(7)).Return(nil)
+
+		err := service.VerifyAndActivate(ctx, email, "654321")
+		assert.Equal(t, ErrInvalidActivationCode, err)
+		userActivationDao.AssertExpectations(t)
+	})
 
-		err := service.VerifyAndActivate(ctx, "invalid-code")
-		if err == nil || err.Error() != "invalid or expired activation code" {
-			t.Fatalf("Expected 'invalid or expired activation code' error, got %v", err)
+	t.Run("Too many attempts invalidate the code", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
 		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(pendingUser, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 1).Return(&model.UserActivation{
+			CodeHash: codeHash, UserID: 1, Attempts: activationMaxAttempts, ExpiresAt: time.Now().Add(time.Minute),
+		}, nil)
+
+		err := service.VerifyAndActivate(ctx, email, "123456")
+		assert.Equal(t, ErrInvalidActivationCode, err)
+	})
+
+	t.Run("Code of another email is rejected", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, "other@example.com").Return(&model.User{ID: 2, Status: model.UserStatusInactive}, nil)
+		userActivationDao.On("GetByUserId", mock.Anything, 2).Return(nil, nil)
+
+		err := service.VerifyAndActivate(ctx, "other@example.com", "123456")
+		assert.Equal(t, ErrInvalidActivationCode, err)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		userDao := new(dao_mock.UserDao)
+		service := &RegisterImpl{
+			userDao: userDao,
+		}
+		userDao.On("GetUserByEmail", mock.Anything, email).Return(nil, nil)
+
+		err := service.VerifyAndActivate(ctx, email, "123456")
+		assert.Equal(t, ErrInvalidActivationCode, err)
 	})
 }
+func TestActivateByLink(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	secret := []byte("activation-link-secret")
+	pendingUser := &model.User{ID: 1, Email: "test@example.com", Status: model.UserStatusInactive}
+	validActivation := func() *model.UserActivation {
+		return &model.UserActivation{ID: 7, UserID: 1, ExpiresAt: time.Now().Add(time.Minute)}
+	}
+
+	t.Run("Emailed link activates the account", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		emailSender := new(proxy_mock.EmailService)
+		kafkaProducer := new(mq_mock.KafkaProducer)
+		service := &RegisterImpl{
+			userDao:        userDao,
+			userActivation: userActivationDao,
+			emailService:   emailSender,
+			txBeginner:     &fakeTx{DB: initMemDb(t)},
+			kafkaProducer:  kafkaProducer,
+			lifecycle:      newUserLifecycle(userDao, kafkaProducer),
+			linkSecret:     secret,
+			linkBaseURL:    "https://shop.example.com/user-ms/v1/",
+		}
+		userActivationDao.On("Replace", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.UserActivation).ID = 7
+		}).Return(nil)
+		var token string
+		emailSender.On("Send", mock.MatchedBy(func(body string) bool {
+			match := regexp.MustCompile(`href="https://shop\.example\.com/user-ms/v1/customer/users/activate\?token=([^"]+)"`).FindStringSubmatch(body)
+			if match == nil {
+				return false
+			}
+			token, _ = url.QueryUnescape(match[1])
+			return true
+		}), pendingUser.Email, mock.Anything).Return(nil)
+		assert.NoError(t, service.sendActivationCode(ctx, pendingUser, nil, ""))
+
+		userActivationDao.On("GetById", mock.Anything, int64(7)).Return(validActivation(), nil).Once()
+		userDao.On("GetUserById", mock.Anything, 1).Return(pendingUser, nil)
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusInactive, model.UserStatusActive, false, mock.Anything).Return(true, nil)
+		userDao.On("UpdateUserInTransaction", mock.Anything, mock.MatchedBy(func(arg *model.User) bool {
+			return arg.ID == 1 && arg.ActivateTime != nil
+		}), mock.Anything).Return(nil)
+		userActivationDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
+		kafkaProducer.On("Produce", mock.Anything, mock.Anything, "1", mock.Anything).Return(nil)
+		assert.NoError(t, service.ActivateByLink(ctx, token))
+
+		// Activation consumed the row, so the link does not work twice
+		userActivationDao.On("GetById", mock.Anything, int64(7)).Return(nil, nil).Once()
+		assert.ErrorIs(t, service.ActivateByLink(ctx, token), ErrInvalidActivationCode)
+		userDao.AssertExpectations(t)
+		userActivationDao.AssertExpectations(t)
+		kafkaProducer.AssertExpectations(t)
+	})
+
+	t.Run("Tampered or foreign tokens are rejected", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		service := &RegisterImpl{userActivation: userActivationDao, linkSecret: secret}
+		token := signActivationLink(secret, 7, 1)
+		for _, bad := range []string{
+			"",
+			"garbage",
+			token + "x",
+			signActivationLink([]byte("other-secret"), 7, 1),
+			strings.Replace(token, token[:4], "AAAA", 1),
+		} {
+			assert.ErrorIs(t, service.ActivateByLink(ctx, bad), ErrInvalidActivationCode, bad)
+		}
+		userActivationDao.AssertNotCalled(t, "GetById", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Expired, replaced or mismatched activations are rejected", func(t *testing.T) {
+		expired := validActivation()
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		exhausted := validActivation()
+		exhausted.Attempts = activationMaxAttempts
+		otherUser := validActivation()
+		otherUser.UserID = 2
+		for name, activation := range map[string]*model.UserActivation{
+			"expired":    expired,
+			"exhausted":  exhausted,
+			"other user": otherUser,
+			"replaced":   nil,
+		} {
+			userActivationDao := new(dao_mock.UserActivationDao)
+			userActivationDao.On("GetById", mock.Anything, int64(7)).Return(activation, nil)
+			service := &RegisterImpl{userActivation: userActivationDao, linkSecret: secret}
+			assert.ErrorIs(t, service.ActivateByLink(ctx, signActivationLink(secret, 7, 1)), ErrInvalidActivationCode, name)
+		}
+	})
+
+	t.Run("Already active user", func(t *testing.T) {
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userDao := new(dao_mock.UserDao)
+		userActivationDao.On("GetById", mock.Anything, int64(7)).Return(validActivation(), nil)
+		userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive}, nil)
+		service := &RegisterImpl{userDao: userDao, userActivation: userActivationDao, linkSecret: secret}
+		assert.ErrorIs(t, service.ActivateByLink(ctx, signActivationLink(secret, 7, 1)), ErrInvalidActivationCode)
+		userDao.AssertNotCalled(t, "UpdateUserInTransaction", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Links are disabled without a secret", func(t *testing.T) {
+		emailSender := new(proxy_mock.EmailService)
+		userActivationDao := new(dao_mock.UserActivationDao)
+		userActivationDao.On("Replace", mock.Anything, mock.Anything).Return(nil)
+		emailSender.On("Send", mock.MatchedBy(func(body string) bool {
+			return !strings.Contains(body, "href")
+		}), pendingUser.Email, mock.Anything).Return(nil)
+		service := &RegisterImpl{userActivation: userActivationDao, emailService: emailSender}
+		assert.NoError(t, service.sendActivationCode(ctx, pendingUser, nil, ""))
+		assert.ErrorIs(t, service.ActivateByLink(ctx, signActivationLink(nil, 7, 1)), ErrInvalidActivationCode)
+		emailSender.AssertExpectations(t)
+	})
+}
+
 func TestGetRegisterService(t *testing.T) {
 	initEnv()
 	t.Run("Singleton instance", func(t *testing.T) {
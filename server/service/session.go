@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// ClientInfo describes the device a request comes from. It is recorded on
+// the session started by a login.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo stores the device of the request in ctx for IssueTokens.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+func clientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+type SessionService interface {
+	List(ctx context.Context, userID int) ([]*model.UserSession, error)
+	Revoke(ctx context.Context, userID int, sessionID int64) error
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type SessionServiceImpl struct {
+	userDao         dao.UserDao
+	sessionDao      dao.UserSessionDao
+	refreshTokenDao dao.RefreshTokenDao
+}
+
+var (
+	sessionServiceOnce sync.Once
+	sessionServiceInst *SessionServiceImpl
+)
+
+// sessionLastSeenStep is how stale last-seen may get before a request with
+// one of the session's access tokens updates it.
+const sessionLastSeenStep = 5 * time.Minute
+
+var ErrSessionNotFound = errors.New("session not found")
+
+func GetSessionService() *SessionServiceImpl {
+	sessionServiceOnce.Do(func() {
+		if sessionServiceInst == nil {
+			sessionServiceInst = &SessionServiceImpl{
+				userDao:         dao.GetUserDao(),
+				sessionDao:      dao.GetUserSessionDao(),
+				refreshTokenDao: dao.GetRefreshTokenDao(),
+			}
+		}
+	})
+	return sessionServiceInst
+}
+
+// List returns the active sessions of the user. Sessions started before the
+// latest password change are left out since their tokens no longer work.
+func (ss *SessionServiceImpl) List(ctx context.Context, userID int) ([]*model.UserSession, error) {
+	user, err := ss.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	sessions, err := ss.sessionDao.ListActiveByUserId(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	active := make([]*model.UserSession, 0, len(sessions))
+	for _, session := range sessions {
+		if session.CredentialVersion == user.CredentialVersion {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// Revoke signs the user out of one session. Its refresh token stops working
+// at once and its access tokens are rejected by CheckClaims.
+func (ss *SessionServiceImpl) Revoke(ctx context.Context, userID int, sessionID int64) error {
+	session, err := ss.sessionDao.GetById(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	now := time.Now()
+	revoked, err := ss.sessionDao.Revoke(ctx, userID, sessionID, now)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	if err := ss.refreshTokenDao.RevokeFamily(ctx, session.FamilyID, now); err != nil {
+		return err
+	}
+	log.Logger.Infof("Session %d of user %d revoked", sessionID, userID)
+	return nil
+}
+
+func (ss *SessionServiceImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return ss.sessionDao.DeleteExpired(ctx, now)
+}
+
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	// Do not leave half a character behind
+	return strings.ToValidUTF8(value[:max], "")
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIssueTokensStartsSession(t *testing.T) {
+	initEnv()
+	ctx := WithClientInfo(context.Background(), ClientInfo{UserAgent: "Mozilla/5.0 (iPhone)", IP: "203.0.113.7"})
+	refreshTokenDao := new(mocks.RefreshTokenDao)
+	sessionDao := new(mocks.UserSessionDao)
+	service := &TokenServiceImpl{refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDao}
+	user := &model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleCustomer, CredentialVersion: 2}
+
+	var session *model.UserSession
+	sessionDao.On("Create", ctx, mock.MatchedBy(func(arg *model.UserSession) bool {
+		session = arg
+		arg.ID = 42
+		return true
+	})).Return(nil)
+	var stored *model.RefreshToken
+	refreshTokenDao.On("Create", ctx, mock.MatchedBy(func(arg *model.RefreshToken) bool {
+		stored = arg
+		return true
+	})).Return(nil)
+
+	tokens, err := service.IssueTokens(ctx, user, utils.AudienceCustomer)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mozilla/5.0 (iPhone)", session.UserAgent)
+	assert.Equal(t, "203.0.113.7", session.IP)
+	assert.Equal(t, utils.AudienceCustomer, session.Audience)
+	assert.Equal(t, 2, session.CredentialVersion)
+	assert.Equal(t, stored.FamilyID, session.FamilyID)
+	claims, err := utils.ParseJWTToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), claims.SessionID)
+}
+
+func TestRefreshKeepsSession(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	user := &model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleCustomer, CredentialVersion: 2}
+	stored := &model.RefreshToken{
+		ID: 10, UserID: 1, FamilyID: "family-1", Audience: utils.AudienceCustomer,
+		CredentialVersion: 2, ExpiresAt: time.Now().Add(time.Hour),
+	}
+	newService := func() (*TokenServiceImpl, *mocks.RefreshTokenDao, *mocks.UserSessionDao) {
+		userDao := new(mocks.UserDao)
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		sessionDao := new(mocks.UserSessionDao)
+		userDao.On("GetUserById", ctx, 1).Return(user, nil)
+		refreshTokenDao.On("GetByTokenHash", ctx, hashToken("old")).Return(stored, nil)
+		refreshTokenDao.On("MarkUsed", ctx, int64(10), mock.Anything).Return(true, nil)
+		refreshTokenDao.On("Create", ctx, mock.Anything).Return(nil)
+		return &TokenServiceImpl{userDao: userDao, refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDao}, refreshTokenDao, sessionDao
+	}
+
+	t.Run("Refreshed tokens stay in the session", func(t *testing.T) {
+		service, _, sessionDao := newService()
+		sessionDao.On("GetByFamilyId", ctx, "family-1").Return(&model.UserSession{ID: 42, UserID: 1, FamilyID: "family-1"}, nil)
+		sessionDao.On("Extend", ctx, int64(42), mock.Anything, mock.Anything).Return(nil)
+
+		tokens, err := service.Refresh(ctx, utils.AudienceCustomer, "old")
+		assert.NoError(t, err)
+		claims, err := utils.ParseJWTToken(tokens.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), claims.SessionID)
+		sessionDao.AssertExpectations(t)
+	})
+
+	t.Run("Revoked session cannot refresh", func(t *testing.T) {
+		service, refreshTokenDao, sessionDao := newService()
+		revokedAt := time.Now().Add(-time.Minute)
+		sessionDao.On("GetByFamilyId", ctx, "family-1").Return(&model.UserSession{ID: 42, UserID: 1, RevokedAt: &revokedAt}, nil)
+
+		_, err := service.Refresh(ctx, utils.AudienceCustomer, "old")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		refreshTokenDao.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSessionService(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+
+	t.Run("Lists sessions of the current credentials", func(t *testing.T) {
+		userDao := new(mocks.UserDao)
+		sessionDao := new(mocks.UserSessionDao)
+		service := &SessionServiceImpl{userDao: userDao, sessionDao: sessionDao}
+		userDao.On("GetUserById", ctx, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, CredentialVersion: 3}, nil)
+		sessionDao.On("ListActiveByUserId", ctx, 1, mock.Anything).Return([]*model.UserSession{
+			{ID: 1, CredentialVersion: 3},
+			{ID: 2, CredentialVersion: 2},
+			{ID: 3, CredentialVersion: 3},
+		}, nil)
+
+		sessions, err := service.List(ctx, 1)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+		assert.Equal(t, int64(1), sessions[0].ID)
+		assert.Equal(t, int64(3), sessions[1].ID)
+	})
+
+	t.Run("Revoke ends the refresh token family", func(t *testing.T) {
+		sessionDao := new(mocks.UserSessionDao)
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &SessionServiceImpl{sessionDao: sessionDao, refreshTokenDao: refreshTokenDao}
+		sessionDao.On("GetById", ctx, int64(42)).Return(&model.UserSession{ID: 42, UserID: 1, FamilyID: "family-1"}, nil)
+		sessionDao.On("Revoke", ctx, 1, int64(42), mock.Anything).Return(true, nil)
+		refreshTokenDao.On("RevokeFamily", ctx, "family-1", mock.Anything).Return(nil)
+
+		assert.NoError(t, service.Revoke(ctx, 1, 42))
+		refreshTokenDao.AssertExpectations(t)
+	})
+
+	t.Run("Sessions of other users cannot be revoked", func(t *testing.T) {
+		sessionDao := new(mocks.UserSessionDao)
+		service := &SessionServiceImpl{sessionDao: sessionDao}
+		sessionDao.On("GetById", ctx, int64(42)).Return(&model.UserSession{ID: 42, UserID: 2, FamilyID: "family-1"}, nil)
+		sessionDao.On("GetById", ctx, int64(43)).Return(nil, nil)
+
+		assert.ErrorIs(t, service.Revoke(ctx, 1, 42), ErrSessionNotFound)
+		assert.ErrorIs(t, service.Revoke(ctx, 1, 43), ErrSessionNotFound)
+		sessionDao.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestCheckClaimsSession(t *testing.T) {
+	initEnv()
+	userDao := new(mocks.UserDao)
+	sessionDao := new(mocks.UserSessionDao)
+	loginService := &LoginServiceImpl{userDao: userDao, sessionDao: sessionDao}
+	userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleCustomer}, nil)
+	revokedAt := time.Now().Add(-time.Minute)
+	sessionDao.On("GetById", mock.Anything, int64(1)).Return(&model.UserSession{ID: 1, UserID: 1, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	sessionDao.On("GetById", mock.Anything, int64(2)).Return(&model.UserSession{ID: 2, UserID: 1, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}, nil)
+	sessionDao.On("GetById", mock.Anything, int64(3)).Return(&model.UserSession{ID: 3, UserID: 1, LastSeenAt: time.Now().Add(-time.Hour), ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	sessionDao.On("GetById", mock.Anything, int64(4)).Return(&model.UserSession{ID: 4, UserID: 2, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	sessionDao.On("UpdateLastSeen", mock.Anything, int64(3), mock.Anything).Return(nil)
+
+	assert.NoError(t, loginService.CheckClaims(&utils.Claims{ID: 1, Role: model.UserRoleCustomer, SessionID: 1}))
+	assert.Error(t, loginService.CheckClaims(&utils.Claims{ID: 1, Role: model.UserRoleCustomer, SessionID: 2}))
+	assert.Error(t, loginService.CheckClaims(&utils.Claims{ID: 1, Role: model.UserRoleCustomer, SessionID: 4}))
+	// A stale last-seen time is brought up to date
+	assert.NoError(t, loginService.CheckClaims(&utils.Claims{ID: 1, Role: model.UserRoleCustomer, SessionID: 3}))
+	sessionDao.AssertCalled(t, "UpdateLastSeen", mock.Anything, int64(3), mock.Anything)
+	sessionDao.AssertNotCalled(t, "UpdateLastSeen", mock.Anything, int64(1), mock.Anything)
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/bo"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// AuthTokens is what a client receives after authenticating: a short-lived
+// JWT access token and a long-lived opaque refresh token.
+type AuthTokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type TokenService interface {
+	IssueTokens(ctx context.Context, user *model.User, audience string) (*AuthTokens, error)
+	Refresh(ctx context.Context, audience, refreshToken string) (*AuthTokens, error)
+	RevokeRefreshToken(ctx context.Context, refreshToken string) error
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type TokenServiceImpl struct {
+	userDao           dao.UserDao
+	refreshTokenDao   dao.RefreshTokenDao
+	rolePermissionDao dao.RolePermissionDao
+	sessionDao        dao.UserSessionDao
+}
+
+var (
+	tokenServiceInst *TokenServiceImpl
+	tokenServiceOnce sync.Once
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+func GetTokenService() *TokenServiceImpl {
+	tokenServiceOnce.Do(func() {
+		if tokenServiceInst == nil {
+			tokenServiceInst = &TokenServiceImpl{
+				userDao:           dao.GetUserDao(),
+				refreshTokenDao:   dao.GetRefreshTokenDao(),
+				rolePermissionDao: dao.GetRolePermissionDao(),
+				sessionDao:        dao.GetUserSessionDao(),
+			}
+		}
+	})
+	return tokenServiceInst
+}
+
+// RefreshTokenTTL returns the configured refresh token lifetime.
+func RefreshTokenTTL() time.Duration {
+	return time.Duration(config.Config.AuthConfig.RefreshTokenTTLHours) * time.Hour
+}
+
+// IssueTokens starts a new session and refresh token family for the user,
+// bound to the client (audience) the user signed in to. The device is taken
+// from the ClientInfo of ctx.
+func (ts *TokenServiceImpl) IssueTokens(ctx context.Context, user *model.User, audience string) (*AuthTokens, error) {
+	familyID, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	client := clientInfoFromContext(ctx)
+	session := &model.UserSession{
+		UserID:            user.ID,
+		FamilyID:          familyID,
+		Audience:          audience,
+		UserAgent:         truncate(client.UserAgent, 255),
+		IP:                truncate(client.IP, 64),
+		CredentialVersion: user.CredentialVersion,
+		CreatedAt:         now,
+		LastSeenAt:        now,
+		ExpiresAt:         now.Add(RefreshTokenTTL()),
+	}
+	if err := ts.sessionDao.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("Session %d started for user %d on the %s client", session.ID, user.ID, audience)
+	return ts.issueInFamily(ctx, user, audience, familyID, session.ID)
+}
+
+func (ts *TokenServiceImpl) issueInFamily(ctx context.Context, user *model.User, audience, familyID string, sessionID int64) (*AuthTokens, error) {
+	// Permissions are resolved on every issue, so changed grants take effect
+	// at the next refresh
+	permissions, err := ts.rolePermissionDao.GetPermissionsByRole(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := utils.GenerateJWTToken(&bo.UserBO{
+		ID:                user.ID,
+		Email:             user.Email,
+		Role:              user.Role,
+		Permissions:       permissions,
+		CredentialVersion: user.CredentialVersion,
+		SessionID:         sessionID,
+	}, audience)
+	if err != nil {
+		log.Logger.Errorf("Failed to generate access token: %v", err)
+		return nil, err
+	}
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		log.Logger.Errorf("Failed to generate refresh token: %v", err)
+		return nil, err
+	}
+	err = ts.refreshTokenDao.Create(ctx, &model.RefreshToken{
+		UserID:            user.ID,
+		FamilyID:          familyID,
+		Audience:          audience,
+		TokenHash:         hashToken(refreshToken),
+		CredentialVersion: user.CredentialVersion,
+		ExpiresAt:         time.Now().Add(RefreshTokenTTL()),
+		CreatedAt:         time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AuthTokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh consumes a refresh token and rotates it. Presenting a token that was
+// already used means it leaked, so the whole family is revoked. A token only
+// refreshes on the client it was issued to.
+func (ts *TokenServiceImpl) Refresh(ctx context.Context, audience, refreshToken string) (*AuthTokens, error) {
+	stored, err := ts.refreshTokenDao.GetByTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.RevokedAt != nil || stored.ExpiresAt.Before(time.Now()) || stored.Audience != audience {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, ts.revokeFamilyOnReuse(ctx, stored)
+	}
+	marked, err := ts.refreshTokenDao.MarkUsed(ctx, stored.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		// Lost a race against another refresh with the same token
+		return nil, ts.revokeFamilyOnReuse(ctx, stored)
+	}
+	user, err := ts.userDao.GetUserById(ctx, stored.UserID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil || user.CredentialVersion != stored.CredentialVersion {
+		log.Logger.Warnf("Refresh token of user %d predates a credential change", stored.UserID)
+		return nil, ErrInvalidRefreshToken
+	}
+	if !CanUseClient(user.Role, audience) {
+		log.Logger.Warnf("User %d with role %s can no longer use the %s client", user.ID, user.Role, audience)
+		return nil, ErrInvalidRefreshToken
+	}
+	if err := CheckAccountStatus(user.Status); err != nil {
+		log.Logger.Warnf("Refresh token of user %d refused: %v", user.ID, err)
+		return nil, err
+	}
+	session, err := ts.sessionDao.GetByFamilyId(ctx, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	// Families started before sessions were recorded have no session and
+	// keep working until they expire
+	var sessionID int64
+	if session != nil {
+		if session.RevokedAt != nil {
+			log.Logger.Warnf("Refresh token of revoked session %d of user %d", session.ID, user.ID)
+			return nil, ErrInvalidRefreshToken
+		}
+		now := time.Now()
+		if err := ts.sessionDao.Extend(ctx, session.ID, now, now.Add(RefreshTokenTTL())); err != nil {
+			return nil, err
+		}
+		sessionID = session.ID
+	}
+	return ts.issueInFamily(ctx, user, audience, stored.FamilyID, sessionID)
+}
+
+func (ts *TokenServiceImpl) revokeFamilyOnReuse(ctx context.Context, stored *model.RefreshToken) error {
+	log.Logger.Warnf("Refresh token reuse detected for user %d, revoking family %s", stored.UserID, stored.FamilyID)
+	if err := ts.revokeFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// revokeFamily ends the refresh token family and its session.
+func (ts *TokenServiceImpl) revokeFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	if err := ts.refreshTokenDao.RevokeFamily(ctx, familyID, now); err != nil {
+		return err
+	}
+	return ts.sessionDao.RevokeByFamilyId(ctx, familyID, now)
+}
+
+// RevokeRefreshToken ends the refresh token family the given token belongs to.
+// Unknown tokens are ignored.
+func (ts *TokenServiceImpl) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	stored, err := ts.refreshTokenDao.GetByTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return nil
+	}
+	return ts.revokeFamily(ctx, stored.FamilyID)
+}
+
+func (ts *TokenServiceImpl) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return ts.refreshTokenDao.DeleteExpired(ctx, now)
+}
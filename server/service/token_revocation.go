@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+)
+
+// TokenRevocationStore is the denylist of tokens that were revoked before
+// their natural expiry. Entries are only needed until the token expires.
+type TokenRevocationStore interface {
+	Revoke(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+var (
+	tokenRevocationStoreInst TokenRevocationStore
+	tokenRevocationOnce      sync.Once
+)
+
+func GetTokenRevocationStore() TokenRevocationStore {
+	tokenRevocationOnce.Do(func() {
+		tokenRevocationStoreInst = &DBTokenRevocationStore{
+			revokedTokenDao: dao.GetRevokedTokenDao(),
+		}
+	})
+	return tokenRevocationStoreInst
+}
+
+// DBTokenRevocationStore keeps the denylist in MySQL so it is shared by all
+// service instances.
+type DBTokenRevocationStore struct {
+	revokedTokenDao dao.RevokedTokenDao
+}
+
+func (s *DBTokenRevocationStore) Revoke(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	return s.revokedTokenDao.Create(ctx, &model.RevokedToken{
+		Jti:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *DBTokenRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.revokedTokenDao.ExistsByJti(ctx, jti)
+}
+
+func (s *DBTokenRevocationStore) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	return s.revokedTokenDao.DeleteExpired(ctx, now)
+}
+
+// MemoryTokenRevocationStore is a process-local denylist, meant for tests and
+// single-instance setups.
+type MemoryTokenRevocationStore struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func NewMemoryTokenRevocationStore() *MemoryTokenRevocationStore {
+	return &MemoryTokenRevocationStore{entries: make(map[string]time.Time)}
+}
+
+func (s *MemoryTokenRevocationStore) Revoke(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryTokenRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.entries[jti]
+	return ok, nil
+}
+
+func (s *MemoryTokenRevocationStore) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for jti, expiresAt := range s.entries {
+		if expiresAt.Before(now) {
+			delete(s.entries, jti)
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMemoryTokenRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTokenRevocationStore()
+	now := time.Now()
+
+	assert.NoError(t, store.Revoke(ctx, "expired", 1, now.Add(-time.Minute)))
+	assert.NoError(t, store.Revoke(ctx, "active", 1, now.Add(time.Hour)))
+
+	revoked, err := store.IsRevoked(ctx, "active")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = store.IsRevoked(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	count, err := store.PruneExpired(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	revoked, _ = store.IsRevoked(ctx, "expired")
+	assert.False(t, revoked)
+	revoked, _ = store.IsRevoked(ctx, "active")
+	assert.True(t, revoked)
+}
+
+func TestDBTokenRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	mockDao := new(mocks.RevokedTokenDao)
+	store := &DBTokenRevocationStore{revokedTokenDao: mockDao}
+	expiresAt := time.Now().Add(time.Hour)
+
+	mockDao.On("Create", ctx, mock.MatchedBy(func(arg *model.RevokedToken) bool {
+		return arg.Jti == "jti-1" && arg.UserID == 1 && arg.ExpiresAt.Equal(expiresAt)
+	})).Return(nil)
+	mockDao.On("ExistsByJti", ctx, "jti-1").Return(true, nil)
+	mockDao.On("DeleteExpired", ctx, mock.Anything).Return(int64(3), nil)
+
+	assert.NoError(t, store.Revoke(ctx, "jti-1", 1, expiresAt))
+	revoked, err := store.IsRevoked(ctx, "jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	count, err := store.PruneExpired(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	mockDao.AssertExpectations(t)
+}
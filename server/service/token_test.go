@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// rolePermissionDaoWithDefaults grants every role its default permissions.
+func rolePermissionDaoWithDefaults() *mocks.RolePermissionDao {
+	rolePermissionDao := new(mocks.RolePermissionDao)
+	for role, permissions := range model.DefaultRolePermissions {
+		rolePermissionDao.On("GetPermissionsByRole", mock.Anything, role).Return(permissions, nil)
+	}
+	return rolePermissionDao
+}
+
+// sessionDaoWithDefaults records every session as session 1 and knows no
+// session families.
+func sessionDaoWithDefaults() *mocks.UserSessionDao {
+	sessionDao := new(mocks.UserSessionDao)
+	sessionDao.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.UserSession).ID = 1
+	}).Return(nil)
+	sessionDao.On("GetByFamilyId", mock.Anything, mock.Anything).Return(nil, nil)
+	sessionDao.On("RevokeByFamilyId", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return sessionDao
+}
+
+func TestIssueTokens(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	refreshTokenDao := new(mocks.RefreshTokenDao)
+	service := &TokenServiceImpl{refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDaoWithDefaults()}
+	user := &model.User{ID: 1, Status: model.UserStatusActive, Email: "test@example.com", Role: model.UserRoleMerchant, CredentialVersion: 2}
+
+	var stored *model.RefreshToken
+	refreshTokenDao.On("Create", ctx, mock.MatchedBy(func(arg *model.RefreshToken) bool {
+		stored = arg
+		return true
+	})).Return(nil)
+
+	tokens, err := service.IssueTokens(ctx, user, utils.AudienceMerchant)
+	assert.NoError(t, err)
+	claims, err := utils.ParseJWTToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claims.ID)
+	assert.Equal(t, 2, claims.CredentialVersion)
+	assert.Equal(t, model.UserRoleMerchant, claims.Role)
+	assert.True(t, claims.HasPermissions(utils.PermProfileRead, utils.PermProfileWrite))
+	assert.False(t, claims.HasPermission(utils.PermAddressWrite))
+	assert.True(t, claims.HasAudience(utils.AudienceMerchant))
+	assert.False(t, claims.HasAudience(utils.AudienceCustomer))
+	assert.Equal(t, utils.AudienceMerchant, stored.Audience)
+	// Only the hash of the refresh token is stored
+	assert.NotEqual(t, tokens.RefreshToken, stored.TokenHash)
+	assert.Equal(t, hashToken(tokens.RefreshToken), stored.TokenHash)
+	assert.NotEmpty(t, stored.FamilyID)
+	assert.True(t, stored.ExpiresAt.After(time.Now().Add(RefreshTokenTTL()-time.Minute)))
+}
+
+func TestRefresh(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	user := &model.User{ID: 1, Status: model.UserStatusActive, Email: "test@example.com", Role: model.UserRoleCustomer, CredentialVersion: 2}
+	validToken := func() *model.RefreshToken {
+		return &model.RefreshToken{
+			ID:                10,
+			UserID:            1,
+			FamilyID:          "family-1",
+			Audience:          utils.AudienceCustomer,
+			CredentialVersion: 2,
+			ExpiresAt:         time.Now().Add(time.Hour),
+		}
+	}
+
+	t.Run("Rotates token", func(t *testing.T) {
+		userDao := new(mocks.UserDao)
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &TokenServiceImpl{userDao: userDao, refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDaoWithDefaults()}
+		refreshTokenDao.On("GetByTokenHash", ctx, hashToken("old")).Return(validToken(), nil)
+		refreshTokenDao.On("MarkUsed", ctx, int64(10), mock.Anything).Return(true, nil)
+		userDao.On("GetUserById", ctx, 1).Return(user, nil)
+		refreshTokenDao.On("Create", ctx, mock.MatchedBy(func(arg *model.RefreshToken) bool {
+			return arg.FamilyID == "family-1" && arg.UserID == 1 && arg.Audience == utils.AudienceCustomer
+		})).Return(nil)
+
+		tokens, err := service.Refresh(ctx, utils.AudienceCustomer, "old")
+		assert.NoError(t, err)
+		assert.NotEqual(t, "old", tokens.RefreshToken)
+		refreshTokenDao.AssertExpectations(t)
+	})
+
+	t.Run("Reused token revokes family", func(t *testing.T) {
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &TokenServiceImpl{refreshTokenDao: refreshTokenDao, sessionDao: sessionDaoWithDefaults()}
+		used := validToken()
+		usedAt := time.Now().Add(-time.Minute)
+		used.UsedAt = &usedAt
+		refreshTokenDao.On("GetByTokenHash", ctx, hashToken("old")).Return(used, nil)
+		refreshTokenDao.On("RevokeFamily", ctx, "family-1", mock.Anything).Return(nil)
+
+		_, err := service.Refresh(ctx, utils.AudienceCustomer, "old")
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		refreshTokenDao.AssertExpectations(t)
+	})
+
+	t.Run("Concurrent use revokes family", func(t *testing.T) {
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &TokenServiceImpl{refreshTokenDao: refreshTokenDao, sessionDao: sessionDaoWithDefaults()}
+		refreshTokenDao.On("GetByTokenHash", ctx, hashToken("old")).Return(validToken(), nil)
+		refreshTokenDao.On("MarkUsed", ctx, int64(10), mock.Anything).Return(false, nil)
+		refreshTokenDao.On("RevokeFamily", ctx, "family-1", mock.Anything).Return(nil)
+
+		_, err := service.Refresh(ctx, utils.AudienceCustomer, "old")
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		refreshTokenDao.AssertExpectations(t)
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &TokenServiceImpl{refreshTokenDao: refreshTokenDao, sessionDao: sessionDaoWithDefaults()}
+		refreshTokenDao.On("GetByTokenHash", ctx, hashToken("unknown")).Return(nil, nil)
+
+		_, err := service.Refresh(ctx, utils.AudienceCustomer, "unknown")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &TokenServiceImpl{refreshTokenDao: refreshTokenDao, sessionDao: sessionDaoWithDefaults()}
+		expired := validToken()
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		refreshTokenDao.On("GetByTokenHash", ctx, hashToken("old")).Return(expired, nil)
+
+		_, err := service.Refresh(ctx, utils.AudienceCustomer, "old")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("Password changed since issue", func(t *testing.T) {
+		userDao := new(mocks.UserDao)
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &TokenServiceImpl{userDao: userDao, refreshTokenDao: refreshTokenDao, sessionDao: sessionDaoWithDefaults()}
+		refreshTokenDao.On("GetByTokenHash", ctx, hashToken("old")).Return(validToken(), nil)
+		refreshTokenDao.On("MarkUsed", ctx, int64(10), mock.Anything).Return(true, nil)
+		userDao.On("GetUserById", ctx, 1).Return(&model.User{ID: 1, Status: model.UserStatusActive, Role: model.UserRoleCustomer, CredentialVersion: 3}, nil)
+
+		_, err := service.Refresh(ctx, utils.AudienceCustomer, "old")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("Token of another client", func(t *testing.T) {
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &TokenServiceImpl{refreshTokenDao: refreshTokenDao, sessionDao: sessionDaoWithDefaults()}
+		refreshTokenDao.On("GetByTokenHash", ctx, hashToken("old")).Return(validToken(), nil)
+
+		_, err := service.Refresh(ctx, utils.AudienceMerchant, "old")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		refreshTokenDao.AssertNotCalled(t, "MarkUsed", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	refreshTokenDao := new(mocks.RefreshTokenDao)
+	service := &TokenServiceImpl{refreshTokenDao: refreshTokenDao, sessionDao: sessionDaoWithDefaults()}
+	refreshTokenDao.On("GetByTokenHash", ctx, hashToken("known")).Return(&model.RefreshToken{FamilyID: "family-1"}, nil)
+	refreshTokenDao.On("GetByTokenHash", ctx, hashToken("unknown")).Return(nil, nil)
+	refreshTokenDao.On("RevokeFamily", ctx, "family-1", mock.Anything).Return(nil)
+
+	assert.NoError(t, service.RevokeRefreshToken(ctx, "known"))
+	assert.NoError(t, service.RevokeRefreshToken(ctx, "unknown"))
+	refreshTokenDao.AssertNumberOfCalls(t, "RevokeFamily", 1)
+}
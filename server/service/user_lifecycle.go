@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/config"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"gorm.io/gorm"
+)
+
+// UserLifecycleService is the only place account statuses change. Every
+// transition is checked against userStatusTransitions, recorded in the status
+// history and published as a UserStatusChangedEvent.
+type UserLifecycleService interface {
+	// Transition moves the user from status from to status to. actorID is
+	// the staff member making the change, nil for the user or the system.
+	Transition(ctx context.Context, userID, from, to int, reason string, actorID *int) error
+	TransitionInTransaction(ctx context.Context, userID, from, to int, reason string, actorID *int, tx *gorm.DB) error
+	History(ctx context.Context, userID int) ([]*model.UserStatusHistory, error)
+}
+
+type UserLifecycleServiceImpl struct {
+	userDao          dao.UserDao
+	statusHistoryDao dao.UserStatusHistoryDao
+	txBeginner       repository.TxBeginner
+	kafkaProducer    mq.KafkaProducer
+}
+
+var (
+	userLifecycleOnce sync.Once
+	userLifecycleInst *UserLifecycleServiceImpl
+)
+
+// userStatusTransitions lists the statuses each status may move to. Deleted
+// accounts stay deleted. Suspended accounts go back to the status they were
+// suspended from, so a suspension never skips activation or a password reset.
+var userStatusTransitions = map[int][]int{
+	model.UserStatusInactive:        {model.UserStatusActive, model.UserStatusSuspended},
+	model.UserStatusActive:          {model.UserStatusLocked, model.UserStatusSuspended, model.UserStatusPendingDeletion},
+	model.UserStatusLocked:          {model.UserStatusActive, model.UserStatusSuspended},
+	model.UserStatusSuspended:       {model.UserStatusActive, model.UserStatusInactive, model.UserStatusLocked},
+	model.UserStatusPendingDeletion: {model.UserStatusActive, model.UserStatusDeleted},
+}
+
+// Reasons recorded with the transitions the service makes by itself or on
+// behalf of staff.
+const (
+	StatusReasonActivated           = "activated"
+	StatusReasonActivatedByAdmin    = "activated_by_admin"
+	StatusReasonSocialLogin         = "social_login"
+	StatusReasonPasswordReset       = "password_reset"
+	StatusReasonDeletionRequested   = "deletion_requested"
+	StatusReasonDeletionCancelled   = "deletion_cancelled"
+	StatusReasonDeletionCompleted   = "deletion_completed"
+	StatusReasonPasswordResetForced = "password_reset_forced"
+	StatusReasonUnsuspended         = "unsuspended"
+)
+
+var (
+	ErrInvalidStatusTransition = errors.New("account status change is not allowed")
+	// ErrStatusChanged means the account left the expected status before
+	// the transition was made
+	ErrStatusChanged = errors.New("account status has changed")
+)
+
+// AccountStateError refuses valid credentials of an account that is not
+// active. It matches utils.ErrAccountUnavailable with errors.Is, so the auth
+// middleware shows its message to the caller.
+type AccountStateError struct {
+	Status  int
+	message string
+}
+
+func (e *AccountStateError) Error() string {
+	return e.message
+}
+
+func (e *AccountStateError) Is(target error) bool {
+	return target == utils.ErrAccountUnavailable
+}
+
+var (
+	ErrAccountNotActivated    = &AccountStateError{Status: model.UserStatusInactive, message: "account is not activated yet"}
+	ErrAccountLocked          = &AccountStateError{Status: model.UserStatusLocked, message: "account is locked, reset your password to unlock it"}
+	ErrAccountSuspended       = &AccountStateError{Status: model.UserStatusSuspended, message: "account is suspended"}
+	ErrAccountPendingDeletion = &AccountStateError{Status: model.UserStatusPendingDeletion, message: "account is scheduled for deletion"}
+	ErrAccountDeleted         = &AccountStateError{Status: model.UserStatusDeleted, message: "account has been deleted"}
+)
+
+var accountStateErrors = map[int]*AccountStateError{
+	model.UserStatusInactive:        ErrAccountNotActivated,
+	model.UserStatusLocked:          ErrAccountLocked,
+	model.UserStatusSuspended:       ErrAccountSuspended,
+	model.UserStatusPendingDeletion: ErrAccountPendingDeletion,
+	model.UserStatusDeleted:         ErrAccountDeleted,
+}
+
+// CheckAccountStatus returns the error a sign-in or token of an account in
+// the given status is refused with, or nil for active accounts. Logins call
+// it once the credentials are verified, so the status is only revealed to
+// the owner.
+func CheckAccountStatus(status int) error {
+	if status == model.UserStatusActive {
+		return nil
+	}
+	if err, ok := accountStateErrors[status]; ok {
+		return err
+	}
+	return &AccountStateError{Status: status, message: "account is not available"}
+}
+
+// CanTransition reports whether an account may move from status from to
+// status to.
+func CanTransition(from, to int) bool {
+	return slices.Contains(userStatusTransitions[from], to)
+}
+
+func GetUserLifecycleService() *UserLifecycleServiceImpl {
+	userLifecycleOnce.Do(func() {
+		if userLifecycleInst == nil {
+			userLifecycleInst = &UserLifecycleServiceImpl{
+				userDao:          dao.GetUserDao(),
+				statusHistoryDao: dao.GetUserStatusHistoryDao(),
+				txBeginner:       repository.DB,
+				kafkaProducer:    mq.GetKafkaProducer(),
+			}
+		}
+	})
+	return userLifecycleInst
+}
+
+func (ls *UserLifecycleServiceImpl) Transition(ctx context.Context, userID, from, to int, reason string, actorID *int) error {
+	return ls.txBeginner.Transaction(func(tx *gorm.DB) error {
+		return ls.TransitionInTransaction(ctx, userID, from, to, reason, actorID, tx)
+	})
+}
+
+// TransitionInTransaction makes the transition as part of tx. Leaving the
+// active status signs the user out everywhere.
+func (ls *UserLifecycleServiceImpl) TransitionInTransaction(ctx context.Context, userID, from, to int, reason string, actorID *int, tx *gorm.DB) error {
+	if !CanTransition(from, to) {
+		log.Logger.Warnf("Refused status change of user %d from %s to %s", userID, model.UserStatusNames[from], model.UserStatusNames[to])
+		return ErrInvalidStatusTransition
+	}
+	changed, err := ls.userDao.UpdateStatusInTransaction(ctx, userID, from, to, from == model.UserStatusActive, tx)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		log.Logger.Warnf("User %d is no longer %s", userID, model.UserStatusNames[from])
+		return ErrStatusChanged
+	}
+	now := time.Now()
+	err = ls.statusHistoryDao.CreateInTransaction(ctx, &model.UserStatusHistory{
+		UserID: userID, FromStatus: from, ToStatus: to, Reason: reason, ActorID: actorID, CreatedAt: now,
+	}, tx)
+	if err != nil {
+		return err
+	}
+	event := &mq.UserStatusChangedEvent{
+		UserID:     userID,
+		FromStatus: model.UserStatusNames[from],
+		ToStatus:   model.UserStatusNames[to],
+		Reason:     reason,
+		ActorID:    actorID,
+		ChangeTime: now.Unix(),
+	}
+	err = ls.kafkaProducer.Produce(ctx, config.Config.KafkaConfig.UserStatusChangedTopic, fmt.Sprintf("%d", userID), event.ToBytes())
+	if err != nil {
+		log.Logger.Errorf("Failed to produce user status changed event: %v", err)
+		return err
+	}
+	log.Logger.Infof("User %d moved from %s to %s: %s", userID, event.FromStatus, event.ToStatus, reason)
+	return nil
+}
+
+func (ls *UserLifecycleServiceImpl) History(ctx context.Context, userID int) ([]*model.UserStatusHistory, error) {
+	return ls.statusHistoryDao.ListByUserId(ctx, userID)
+}
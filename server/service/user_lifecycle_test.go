@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq"
+	mq_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/mq/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	dao_mock "github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newUserLifecycle changes statuses through userDao and publishes the events
+// with kafkaProducer. The history is accepted and dropped.
+func newUserLifecycle(userDao dao.UserDao, kafkaProducer mq.KafkaProducer) *UserLifecycleServiceImpl {
+	historyDao := new(dao_mock.UserStatusHistoryDao)
+	historyDao.On("CreateInTransaction", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return &UserLifecycleServiceImpl{userDao: userDao, statusHistoryDao: historyDao, kafkaProducer: kafkaProducer}
+}
+
+func TestCanTransition(t *testing.T) {
+	allowed := [][2]int{
+		{model.UserStatusInactive, model.UserStatusActive},
+		{model.UserStatusActive, model.UserStatusSuspended},
+		{model.UserStatusLocked, model.UserStatusActive},
+		{model.UserStatusSuspended, model.UserStatusActive},
+		{model.UserStatusSuspended, model.UserStatusLocked},
+		{model.UserStatusPendingDeletion, model.UserStatusDeleted},
+	}
+	for _, pair := range allowed {
+		assert.True(t, CanTransition(pair[0], pair[1]), "%v", pair)
+	}
+	refused := [][2]int{
+		{model.UserStatusInactive, model.UserStatusPendingDeletion},
+		{model.UserStatusActive, model.UserStatusActive},
+		{model.UserStatusActive, model.UserStatusDeleted},
+		{model.UserStatusSuspended, model.UserStatusPendingDeletion},
+		{model.UserStatusDeleted, model.UserStatusActive},
+		{0, model.UserStatusActive},
+	}
+	for _, pair := range refused {
+		assert.False(t, CanTransition(pair[0], pair[1]), "%v", pair)
+	}
+}
+
+func TestUserLifecycleTransition(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	newService := func(t *testing.T) (*UserLifecycleServiceImpl, *dao_mock.UserDao, *dao_mock.UserStatusHistoryDao, *mq_mock.KafkaProducer) {
+		userDao := new(dao_mock.UserDao)
+		historyDao := new(dao_mock.UserStatusHistoryDao)
+		kafkaProducer := new(mq_mock.KafkaProducer)
+		service := &UserLifecycleServiceImpl{
+			userDao:          userDao,
+			statusHistoryDao: historyDao,
+			txBeginner:       &fakeTx{DB: initMemDb(t)},
+			kafkaProducer:    kafkaProducer,
+		}
+		return service, userDao, historyDao, kafkaProducer
+	}
+
+	t.Run("Records and publishes the transition", func(t *testing.T) {
+		service, userDao, historyDao, kafkaProducer := newService(t)
+		actorID := 7
+		// Leaving the active status signs the user out
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusActive, model.UserStatusSuspended, true, mock.Anything).Return(true, nil)
+		historyDao.On("CreateInTransaction", mock.Anything, mock.MatchedBy(func(arg *model.UserStatusHistory) bool {
+			return arg.UserID == 1 && arg.FromStatus == model.UserStatusActive && arg.ToStatus == model.UserStatusSuspended &&
+				arg.Reason == "chargeback fraud" && *arg.ActorID == actorID
+		}), mock.Anything).Return(nil)
+		var event mq.UserStatusChangedEvent
+		kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.MatchedBy(func(msg []byte) bool {
+			return json.Unmarshal(msg, &event) == nil
+		})).Return(nil)
+
+		assert.NoError(t, service.Transition(ctx, 1, model.UserStatusActive, model.UserStatusSuspended, "chargeback fraud", &actorID))
+		assert.Equal(t, "active", event.FromStatus)
+		assert.Equal(t, "suspended", event.ToStatus)
+		assert.Equal(t, &actorID, event.ActorID)
+		assert.WithinDuration(t, time.Now(), time.Unix(event.ChangeTime, 0), time.Minute)
+		userDao.AssertExpectations(t)
+		historyDao.AssertExpectations(t)
+	})
+
+	t.Run("Refused transitions change nothing", func(t *testing.T) {
+		service, userDao, _, kafkaProducer := newService(t)
+
+		err := service.Transition(ctx, 1, model.UserStatusDeleted, model.UserStatusActive, "undelete", nil)
+		assert.ErrorIs(t, err, ErrInvalidStatusTransition)
+		userDao.AssertNotCalled(t, "UpdateStatusInTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		kafkaProducer.AssertNotCalled(t, "Produce", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Status changed in the meantime", func(t *testing.T) {
+		service, userDao, historyDao, _ := newService(t)
+		userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusSuspended, model.UserStatusActive, false, mock.Anything).Return(false, nil)
+
+		err := service.Transition(ctx, 1, model.UserStatusSuspended, model.UserStatusActive, "appeal granted", nil)
+		assert.ErrorIs(t, err, ErrStatusChanged)
+		historyDao.AssertNotCalled(t, "CreateInTransaction", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLoginPerAccountStatus(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	hashedPwd, _ := HashPassword("password1")
+	cases := map[int]error{
+		model.UserStatusInactive:        ErrAccountNotActivated,
+		model.UserStatusLocked:          ErrAccountLocked,
+		model.UserStatusSuspended:       ErrAccountSuspended,
+		model.UserStatusPendingDeletion: ErrAccountPendingDeletion,
+	}
+	for status, expected := range cases {
+		userDao := new(dao_mock.UserDao)
+		userDao.On("GetUserByEmail", mock.Anything, "buyer@example.com").Return(&model.User{
+			ID: 1, Email: "buyer@example.com", Password: hashedPwd, Status: status, Role: model.UserRoleCustomer,
+		}, nil)
+		loginService := &LoginServiceImpl{userDao: userDao, loginGuard: permissiveLoginGuard()}
+
+		_, err := loginService.Login(ctx, utils.AudienceCustomer, "buyer@example.com", "password1", "127.0.0.1")
+		assert.ErrorIs(t, err, expected, model.UserStatusNames[status])
+		assert.ErrorIs(t, err, utils.ErrAccountUnavailable)
+		// The status is only revealed to whoever knows the password
+		_, err = loginService.Login(ctx, utils.AudienceCustomer, "buyer@example.com", "wrong", "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// Deleted accounts have no password left to match
+	userDao := new(dao_mock.UserDao)
+	userDao.On("GetUserByEmail", mock.Anything, "buyer@example.com").Return(&model.User{
+		ID: 1, Email: "buyer@example.com", Status: model.UserStatusDeleted, Role: model.UserRoleCustomer,
+	}, nil)
+	loginService := &LoginServiceImpl{userDao: userDao, loginGuard: permissiveLoginGuard()}
+	_, err := loginService.Login(ctx, utils.AudienceCustomer, "buyer@example.com", "password1", "127.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestCheckClaimsAccountStatus(t *testing.T) {
+	initEnv()
+	userDao := new(dao_mock.UserDao)
+	loginService := &LoginServiceImpl{userDao: userDao}
+	userDao.On("GetUserById", mock.Anything, 1).Return(&model.User{
+		ID: 1, Role: model.UserRoleCustomer, Status: model.UserStatusSuspended, CredentialVersion: 3,
+	}, nil)
+
+	// Suspending bumped the credential version, the status explains why
+	err := loginService.CheckClaims(&utils.Claims{ID: 1, Role: model.UserRoleCustomer, CredentialVersion: 2})
+	assert.ErrorIs(t, err, ErrAccountSuspended)
+	assert.ErrorIs(t, err, utils.ErrAccountUnavailable)
+}
+
+func TestPasswordResetUnlocksAccount(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	email := "test@example.com"
+	resetCodeHash, _ := HashPassword("123456")
+	userDao := new(dao_mock.UserDao)
+	resetDao := new(dao_mock.UserPasswordResetDao)
+	kafkaProducer := new(mq_mock.KafkaProducer)
+	service := &PasswordResetServiceImpl{
+		userDao:          userDao,
+		passwordResetDao: resetDao,
+		txBeginner:       &fakeTx{DB: initMemDb(t)},
+		kafkaProducer:    kafkaProducer,
+		loginGuard:       &LoginGuardImpl{userDao: userDao, loginFailureDao: permissiveLoginGuard().loginFailureDao},
+		passwordPolicy:   permissivePasswordPolicy(),
+		lifecycle:        newUserLifecycle(userDao, kafkaProducer),
+	}
+	locked := &model.User{ID: 1, Email: email, Status: model.UserStatusLocked}
+	userDao.On("GetUserByEmail", mock.Anything, email).Return(locked, nil)
+	userDao.On("GetUserById", mock.Anything, 1).Return(locked, nil)
+	resetDao.On("GetLatestByUserId", mock.Anything, 1).Return(&model.UserPasswordReset{
+		UserID: 1, CodeHash: resetCodeHash, ExpiresAt: time.Now().Add(time.Minute),
+	}, nil)
+	resetDao.On("IncrementAttempts", mock.Anything, mock.Anything, passwordResetMaxAttempts).Return(true, nil)
+	userDao.On("UpdatePasswordInTransaction", mock.Anything, 1, mock.Anything, mock.Anything).Return(nil)
+	resetDao.On("DeleteByUserId", mock.Anything, 1, mock.Anything).Return(nil)
+	userDao.On("UpdateStatusInTransaction", mock.Anything, 1, model.UserStatusLocked, model.UserStatusActive, false, mock.Anything).Return(true, nil)
+	kafkaProducer.On("Produce", mock.Anything, "user_status_changed", "1", mock.Anything).Return(nil)
+	kafkaProducer.On("Produce", mock.Anything, mock.Anything, "1", mock.Anything).Return(nil)
+
+	assert.NoError(t, service.ConfirmReset(ctx, email, "123456", "newPassword1"))
+	userDao.AssertExpectations(t)
+	kafkaProducer.AssertCalled(t, "Produce", mock.Anything, "user_status_changed", "1", mock.Anything)
+}
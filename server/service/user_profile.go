@@ -3,18 +3,27 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"sync"
 
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/log"
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao"
+	"gorm.io/gorm"
 )
 
 type UserProfileService interface {
 	GetUserProfile(ctx context.Context, userID int) (*data.UserProfileVO, error)
 	UpdateUserProfile(ctx context.Context, userID int, profile *data.UserProfileVO) error
+	ChangePassword(ctx context.Context, userID int, audience, oldPassword, newPassword string) (*AuthTokens, error)
 }
 
+var (
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	ErrSamePassword      = errors.New("new password must differ from the current password")
+)
+
 var (
 	userProfileServiceInst *UserProfileServiceImpl
 	userProfileOnce        sync.Once
@@ -23,14 +32,20 @@ var (
 func GetUserProfileService() *UserProfileServiceImpl {
 	userProfileOnce.Do(func() {
 		userProfileServiceInst = &UserProfileServiceImpl{
-			userDao: dao.GetUserDao(),
+			userDao:        dao.GetUserDao(),
+			tokenService:   GetTokenService(),
+			passwordPolicy: GetPasswordPolicy(),
+			txBeginner:     repository.DB,
 		}
 	})
 	return userProfileServiceInst
 }
 
 type UserProfileServiceImpl struct {
-	userDao dao.UserDao
+	userDao        dao.UserDao
+	tokenService   TokenService
+	passwordPolicy PasswordPolicy
+	txBeginner     repository.TxBeginner
 }
 
 func (u *UserProfileServiceImpl) GetUserProfile(ctx context.Context, userID int) (*data.UserProfileVO, error) {
@@ -46,6 +61,7 @@ func (u *UserProfileServiceImpl) GetUserProfile(ctx context.Context, userID int)
 	return &data.UserProfileVO{
 		ID:     user.ID,
 		Email:  user.Email,
+		Role:   user.Role,
 		Name:   user.Name,
 		Avatar: user.AvatarId,
 	}, nil
@@ -61,9 +77,52 @@ func (u *UserProfileServiceImpl) UpdateUserProfile(ctx context.Context, userID i
 		log.Logger.Warnf("User not found with id: %d", userID)
 		return sql.ErrNoRows
 	}
-	user.Name = profile.Name
-	user.AvatarId = profile.Avatar
-	err = u.userDao.UpdateUser(ctx, user)
+	err = u.userDao.UpdateProfile(ctx, userID, profile.Name, profile.Avatar)
 	log.Logger.Infof("User profile updated for user id: %d\terr=%v", userID, err)
 	return err
 }
+
+// ChangePassword replaces the user's password after verifying the current one.
+// Every token issued before the change is invalidated; the returned tokens are
+// fresh ones for the caller's own session.
+func (u *UserProfileServiceImpl) ChangePassword(ctx context.Context, userID int, audience, oldPassword, newPassword string) (*AuthTokens, error) {
+	user, err := u.userDao.GetUserById(ctx, userID)
+	if err != nil {
+		log.Logger.Errorf("Failed to get user by id: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		log.Logger.Warnf("User not found with id: %d", userID)
+		return nil, sql.ErrNoRows
+	}
+	if VerifyPassword(user.Password, oldPassword) != nil {
+		log.Logger.Warnf("Incorrect current password for user id: %d", userID)
+		return nil, ErrIncorrectPassword
+	}
+	if oldPassword == newPassword {
+		return nil, ErrSamePassword
+	}
+	err = u.passwordPolicy.Validate(ctx, newPassword, PasswordSubject{
+		UserID: user.ID, Email: user.Email, Name: user.Name, CurrentHash: user.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		log.Logger.Errorf("Failed to hash password: %v", err)
+		return nil, err
+	}
+	err = u.txBeginner.Transaction(func(tx *gorm.DB) error {
+		if err := u.userDao.UpdatePasswordInTransaction(ctx, userID, hashedPassword, tx); err != nil {
+			return err
+		}
+		return u.passwordPolicy.Remember(ctx, userID, user.Password, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Logger.Infof("Password changed for user id: %d", userID)
+	user.CredentialVersion++
+	return u.tokenService.IssueTokens(ctx, user, audience)
+}
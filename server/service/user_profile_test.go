@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"testing"
 
+	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/common/utils"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/http/data"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/dao/mocks"
 	"github.com/NUS-ISS-Agile-Team/ceramicraft-user-mservice/server/repository/model"
@@ -59,7 +60,7 @@ func TestUpdateUserProfile(t *testing.T) {
 	profile := &data.UserProfileVO{Name: "Updated User", Avatar: "newAvatar123"}
 
 	mockDao.On("GetUserById", context.Background(), userID).Return(&model.User{ID: userID, Email: "test@example.com", Name: "Test User", AvatarId: "avatar123"}, nil)
-	mockDao.On("UpdateUser", context.Background(), mock.Anything).Return(nil)
+	mockDao.On("UpdateProfile", context.Background(), userID, "Updated User", "newAvatar123").Return(nil)
 
 	err := service.UpdateUserProfile(context.Background(), userID, profile)
 	assert.NoError(t, err)
@@ -81,3 +82,66 @@ func TestUpdateUserProfile_UserNotFound(t *testing.T) {
 
 	mockDao.AssertExpectations(t)
 }
+
+func TestChangePassword(t *testing.T) {
+	initEnv()
+	ctx := context.Background()
+	userID := 1
+	hashedPwd, _ := HashPassword("oldPassword1")
+	existUser := &model.User{ID: userID, Email: "test@example.com", Password: hashedPwd, Role: model.UserRoleCustomer, CredentialVersion: 3}
+
+	t.Run("Successful change", func(t *testing.T) {
+		mockDao := new(mocks.UserDao)
+		refreshTokenDao := new(mocks.RefreshTokenDao)
+		service := &UserProfileServiceImpl{
+			userDao:        mockDao,
+			tokenService:   &TokenServiceImpl{userDao: mockDao, refreshTokenDao: refreshTokenDao, rolePermissionDao: rolePermissionDaoWithDefaults(), sessionDao: sessionDaoWithDefaults()},
+			passwordPolicy: permissivePasswordPolicy(),
+			txBeginner:     &fakeTx{DB: initMemDb(t)},
+		}
+		refreshTokenDao.On("Create", ctx, mock.MatchedBy(func(arg *model.RefreshToken) bool {
+			return arg.UserID == userID && arg.CredentialVersion == 4 && arg.Audience == utils.AudienceCustomer
+		})).Return(nil)
+		mockDao.On("GetUserById", ctx, userID).Return(existUser, nil)
+		mockDao.On("UpdatePasswordInTransaction", ctx, userID, mock.MatchedBy(func(hashed string) bool {
+			return VerifyPassword(hashed, "newPassword1") == nil
+		}), mock.Anything).Return(nil)
+
+		tokens, err := service.ChangePassword(ctx, userID, utils.AudienceCustomer, "oldPassword1", "newPassword1")
+		assert.NoError(t, err)
+		claims, err := utils.ParseJWTToken(tokens.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, userID, claims.ID)
+		assert.Equal(t, 4, claims.CredentialVersion)
+		mockDao.AssertExpectations(t)
+		refreshTokenDao.AssertExpectations(t)
+	})
+
+	t.Run("Incorrect current password", func(t *testing.T) {
+		mockDao := new(mocks.UserDao)
+		service := &UserProfileServiceImpl{userDao: mockDao}
+		mockDao.On("GetUserById", ctx, userID).Return(existUser, nil)
+
+		_, err := service.ChangePassword(ctx, userID, utils.AudienceCustomer, "wrongPassword1", "newPassword1")
+		assert.ErrorIs(t, err, ErrIncorrectPassword)
+		mockDao.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Same password", func(t *testing.T) {
+		mockDao := new(mocks.UserDao)
+		service := &UserProfileServiceImpl{userDao: mockDao}
+		mockDao.On("GetUserById", ctx, userID).Return(existUser, nil)
+
+		_, err := service.ChangePassword(ctx, userID, utils.AudienceCustomer, "oldPassword1", "oldPassword1")
+		assert.ErrorIs(t, err, ErrSamePassword)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		mockDao := new(mocks.UserDao)
+		service := &UserProfileServiceImpl{userDao: mockDao}
+		mockDao.On("GetUserById", ctx, userID).Return(nil, nil)
+
+		_, err := service.ChangePassword(ctx, userID, utils.AudienceCustomer, "oldPassword1", "newPassword1")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}